@@ -6,9 +6,12 @@ import (
 	"github.com/therenotomorrow/apicache/internal/config"
 	"github.com/therenotomorrow/apicache/internal/server"
 	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/internal/services/origin"
 	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
 	"github.com/therenotomorrow/apicache/pkg/drivers/memcached"
+	"github.com/therenotomorrow/apicache/pkg/drivers/near"
 	"github.com/therenotomorrow/apicache/pkg/drivers/redis"
+	"github.com/therenotomorrow/apicache/pkg/eviction"
 )
 
 // @Title            apicache
@@ -19,11 +22,20 @@ import (
 // @Tag.name         cache
 // @License.name     MIT
 // @License.url      https://github.com/therenotomorrow/apicache/blob/master/LICENSE
+// @Tag.name         ttl
+// @Tag.name         namespaces
+// @Tag.name         admin
+// @SecurityDefinitions.apikey Bearer
+// @In               header
+// @Name             Authorization
 func main() {
 	var (
 		settings *config.Settings
 		driver   cache.Driver
 		service  *cache.Cache
+		policy   eviction.Policy
+		overflow cache.Overflow
+		loader   cache.Loader
 	)
 
 	settings = config.MustNew()
@@ -37,13 +49,71 @@ func main() {
 		driver = redis.NewWithConfig(redis.Config{Addr: drive.Address})
 	}
 
+	if tier := settings.Near; tier.Keys > 0 {
+		var bus near.Bus
+
+		if tier.Bus == "" && settings.Driver.Name == config.DriverRedis {
+			tier.Bus = settings.Driver.Address
+		}
+
+		if tier.Bus != "" {
+			bus = redis.NewBusWithConfig(redis.BusConfig{Addr: tier.Bus, Channel: tier.Channel})
+		}
+
+		driver = near.MustNewWithConfig(near.Config{MaxKeys: tier.Keys, TTL: tier.TTL, Bus: bus, Clock: nil}, driver)
+	}
+
+	switch settings.Cache.Eviction {
+	case config.EvictionLRU:
+		policy = eviction.NewLRU()
+	case config.EvictionLFU:
+		policy = eviction.NewLFU()
+	case config.EvictionTinyLFU:
+		policy = eviction.NewTinyLFU(settings.Cache.MaxKeys)
+	}
+
+	switch settings.Cache.Overflow {
+	case config.OverflowDropNewest:
+		overflow = cache.OverflowDropNewest
+	case config.OverflowDropOldest:
+		overflow = cache.OverflowDropOldest
+	case config.OverflowBlock:
+		overflow = cache.OverflowBlock
+	}
+
+	if source := settings.Origin; source.URL != "" {
+		loader = origin.MustNew(origin.Config{Client: nil, URL: source.URL, TTL: source.TTL, Timeout: source.Timeout})
+	}
+
 	service = cache.MustNew(cache.Config{
-		MaxConn:     settings.Driver.MaxConn,
-		ConnTimeout: settings.Driver.ConnTimeout,
+		MaxConn:       settings.Driver.MaxConn,
+		MinConn:       settings.Driver.MinConn,
+		TargetLatency: settings.Driver.TargetLatency,
+		ConnTimeout:   settings.Driver.ConnTimeout,
+		WriteMaxConn:  settings.Driver.WriteMaxConn,
+		WriteMinConn:  settings.Driver.WriteMinConn,
+		WriteTimeout:  settings.Driver.WriteTimeout,
+		Borrow:        settings.Driver.Borrow,
+		ShedTarget:    settings.Driver.ShedTarget,
+		ShedInterval:  settings.Driver.ShedInterval,
+		BreakAfter:    settings.Driver.BreakAfter,
+		BreakInterval: settings.Driver.BreakInterval,
+		Retries:       settings.Driver.Retries,
+		RetryBackoff:  settings.Driver.RetryBackoff,
+		RetryCeiling:  settings.Driver.RetryCeiling,
+		ExpiryWorkers: settings.Driver.ExpiryWorkers,
+		Stateless:     settings.Driver.Stateless,
+		MaxKeys:       settings.Cache.MaxKeys,
+		MaxBytes:      settings.Cache.MaxBytes,
+		Policy:        policy,
+		Weights:       settings.Cache.Weights,
+		ClientShare:   settings.Cache.ClientShare,
+		StaleGrace:    settings.Cache.StaleGrace,
+		Events:        settings.Cache.Events,
+		Overflow:      overflow,
+		Loader:        loader,
 	}, driver)
 
-	defer func() { _ = service.Close() }()
-
 	app := server.New(settings, service)
 
 	app.Serve(context.Background())
@@ -0,0 +1,87 @@
+package api
+
+import (
+	"crypto/subtle"
+	"strings"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+var priorities = map[string]domain.Priority{
+	"low":    domain.PriorityLow,
+	"normal": domain.PriorityNormal,
+	"high":   domain.PriorityHigh,
+}
+
+// Client identifies the client by the header (e.g. API key) or by the remote IP when header is missed.
+// The IP is the peer address, X-Forwarded-For and X-Real-IP are set by the client, so they identify nobody.
+func Client(header string) echo.MiddlewareFunc {
+	direct := echo.ExtractIPDirect()
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(etx echo.Context) error {
+			req := etx.Request()
+
+			client := req.Header.Get(header)
+			if client == "" {
+				client = direct(req)
+			}
+
+			etx.SetRequest(req.WithContext(domain.WithClient(req.Context(), client)))
+
+			return next(etx)
+		}
+	}
+}
+
+// Priority takes priority of the request from the header (e.g. X-Priority), unknown value means normal one.
+func Priority(header string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(etx echo.Context) error {
+			req := etx.Request()
+
+			priority, ok := priorities[strings.ToLower(req.Header.Get(header))]
+			if !ok {
+				priority = domain.PriorityNormal
+			}
+
+			etx.SetRequest(req.WithContext(domain.WithPriority(req.Context(), priority)))
+
+			return next(etx)
+		}
+	}
+}
+
+// Admin lets in the requests with "Authorization: Bearer <token>", empty token disables the admin API.
+func Admin(token string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(etx echo.Context) error {
+			bearer, ok := strings.CutPrefix(etx.Request().Header.Get(echo.HeaderAuthorization), "Bearer ")
+
+			if token == "" || !ok || subtle.ConstantTimeCompare([]byte(bearer), []byte(token)) != 1 {
+				return UnauthorizedError(domain.ErrUnauthorized)
+			}
+
+			return next(etx)
+		}
+	}
+}
+
+// Namespace puts the namespace from the path into the context, the unknown namespace is not found.
+func Namespace(checker domain.NamespaceChecker) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(etx echo.Context) error {
+			namespace := etx.Param("namespace")
+			if !checker.Exists(namespace) {
+				return NotFoundError(domain.ErrNoNamespace)
+			}
+
+			req := etx.Request()
+
+			etx.SetRequest(req.WithContext(domain.WithNamespace(req.Context(), namespace)))
+
+			return next(etx)
+		}
+	}
+}
@@ -0,0 +1,183 @@
+package api_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+var errUnauthorized = api.UnauthorizedError(domain.ErrUnauthorized)
+
+type checker struct{}
+
+func (c checker) Exists(namespace string) bool {
+	return namespace == "team"
+}
+
+func TestUnitClient(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		key       string
+		forwarded string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[string]
+	}{
+		{name: "api key", args: args{key: "secret", forwarded: ""}, want: toolkit.Want("secret", nil)},
+		{name: "remote ip", args: args{key: "", forwarded: ""}, want: toolkit.Want("192.0.2.1", nil)},
+		{name: "forwarded ip", args: args{key: "", forwarded: "203.0.113.7"}, want: toolkit.Want("192.0.2.1", nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Api-Key", test.args.key)
+			req.Header.Set(echo.HeaderXForwardedFor, test.args.forwarded)
+			req.Header.Set(echo.HeaderXRealIP, test.args.forwarded)
+
+			rec := httptest.NewRecorder()
+			etx := echo.New().NewContext(req, rec)
+
+			var got string
+
+			err := api.Client("X-Api-Key")(func(etx echo.Context) error {
+				got = domain.ClientFrom(etx.Request().Context())
+
+				return nil
+			})(etx)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitPriority(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		priority string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[domain.Priority]
+	}{
+		{name: "low", args: args{priority: "low"}, want: toolkit.Want(domain.PriorityLow, nil)},
+		{name: "high", args: args{priority: "HIGH"}, want: toolkit.Want(domain.PriorityHigh, nil)},
+		{name: "missed", args: args{priority: ""}, want: toolkit.Want(domain.PriorityNormal, nil)},
+		{name: "unknown", args: args{priority: "urgent"}, want: toolkit.Want(domain.PriorityNormal, nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("X-Priority", test.args.priority)
+
+			rec := httptest.NewRecorder()
+			etx := echo.New().NewContext(req, rec)
+
+			var got domain.Priority
+
+			err := api.Priority("X-Priority")(func(etx echo.Context) error {
+				got = domain.PriorityFrom(etx.Request().Context())
+
+				return nil
+			})(etx)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitAdmin(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		token  string
+		header string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[bool]
+	}{
+		{name: "authorized", args: args{token: "secret", header: "Bearer secret"}, want: toolkit.Want(true, nil)},
+		{name: "wrong token", args: args{token: "secret", header: "Bearer invalid"}, want: toolkit.Want(false, errUnauthorized)},
+		{name: "wrong scheme", args: args{token: "secret", header: "Basic secret"}, want: toolkit.Want(false, errUnauthorized)},
+		{name: "missed header", args: args{token: "secret", header: ""}, want: toolkit.Want(false, errUnauthorized)},
+		{name: "disabled", args: args{token: "", header: "Bearer "}, want: toolkit.Want(false, errUnauthorized)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set(echo.HeaderAuthorization, test.args.header)
+
+			rec := httptest.NewRecorder()
+			etx := echo.New().NewContext(req, rec)
+
+			var got bool
+
+			err := api.Admin(test.args.token)(func(_ echo.Context) error {
+				got = true
+
+				return nil
+			})(etx)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitNamespace(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want toolkit.W[string]
+	}{
+		{name: "known", args: "team", want: toolkit.Want("team", nil)},
+		{name: "unknown", args: "other", want: toolkit.Want("", api.NotFoundError(domain.ErrNoNamespace))},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			etx := echo.New().NewContext(req, rec)
+
+			etx.SetParamNames("namespace")
+			etx.SetParamValues(test.args)
+
+			var got string
+
+			err := api.Namespace(checker{})(func(etx echo.Context) error {
+				got = domain.NamespaceFrom(etx.Request().Context())
+
+				return nil
+			})(etx)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
@@ -1,5 +1,116 @@
 package api
 
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+var (
+	ErrInvalidDuration = errors.New("invalid duration")
+	ErrModeIfMatch     = errors.New("mode excludes If-Match")
+)
+
 type Params struct {
 	Key string `param:"key" validate:"required"`
 }
+
+// WriteParams select the condition of the write: "nx" creates the key only, "xx" updates it only.
+// The mode excludes the If-Match header, so they never contradict each other.
+type WriteParams struct {
+	Mode string `query:"mode" validate:"omitempty,oneof=nx xx"`
+}
+
+type WatchParams struct {
+	Prefix string `query:"prefix"`
+}
+
+type WebhookParams struct {
+	ID string `param:"id" validate:"required"`
+}
+
+// Duration is a number of seconds or a duration string, e.g. "1500ms" or "2h30m".
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var seconds float64
+
+	if json.Unmarshal(data, &seconds) == nil {
+		*d = Duration(math.Round(seconds * float64(time.Second)))
+
+		return nil
+	}
+
+	var text string
+
+	err := json.Unmarshal(data, &text)
+	if err != nil {
+		return ErrInvalidDuration
+	}
+
+	duration, err := time.ParseDuration(text)
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrInvalidDuration, err)
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+// Tag is the strong entity tag of the version.
+func Tag(version uint64) string {
+	return `"` + strconv.FormatUint(version, 10) + `"`
+}
+
+// NoneMatch tells whether the If-None-Match header matches the version of the existing value.
+// The comparison is weak, so W/"1" matches the version 1, "*" matches any version.
+func NoneMatch(etx echo.Context, version uint64) bool {
+	header := etx.Request().Header.Get("If-None-Match")
+	if header == "" {
+		return false
+	}
+
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || (version > 0 && strings.TrimPrefix(tag, "W/") == Tag(version)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IfMatch puts the If-Match header to the context: "*" requires the key to exist (see domain.WithCondition),
+// the single strong tag is the version of the compare-and-swap (see domain.WithVersion). The comparison is
+// strong, so the weak, malformed or several tags never match and fail with domain.ErrVersionMismatch.
+func IfMatch(ctx context.Context, etx echo.Context) (context.Context, error) {
+	header := strings.TrimSpace(etx.Request().Header.Get("If-Match"))
+
+	switch header {
+	case "":
+		return ctx, nil
+	case "*":
+		return domain.WithCondition(ctx, domain.ConditionPresent), nil
+	}
+
+	inner, ok := strings.CutPrefix(header, `"`)
+	if ok {
+		inner, ok = strings.CutSuffix(inner, `"`)
+	}
+
+	version, err := strconv.ParseUint(inner, 10, 64)
+	if !ok || err != nil || version == 0 {
+		return ctx, domain.ErrVersionMismatch
+	}
+
+	return domain.WithVersion(ctx, version), nil
+}
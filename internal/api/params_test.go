@@ -1 +1,125 @@
 package api_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+var errMissingUnit = fmt.Errorf("%w: %w", api.ErrInvalidDuration, errors.New(`time: missing unit in duration "10"`))
+
+func TestUnitDuration(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want toolkit.W[api.Duration]
+	}{
+		{name: "seconds", args: `10`, want: toolkit.Want(api.Duration(10*time.Second), nil)},
+		{name: "fraction", args: `0.25`, want: toolkit.Want(api.Duration(250*time.Millisecond), nil)},
+		{name: "milliseconds", args: `"1500ms"`, want: toolkit.Want(api.Duration(1500*time.Millisecond), nil)},
+		{name: "hours", args: `"2h30m"`, want: toolkit.Want(api.Duration(150*time.Minute), nil)},
+		{name: "no unit", args: `"10"`, want: toolkit.Want(api.Duration(0), errMissingUnit)},
+		{name: "not a duration", args: `true`, want: toolkit.Want(api.Duration(0), api.ErrInvalidDuration)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			var got api.Duration
+
+			err := json.Unmarshal([]byte(test.args), &got)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func withHeader(name string, value string) echo.Context {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(name, value)
+
+	return echo.New().NewContext(req, httptest.NewRecorder())
+}
+
+func TestUnitNoneMatch(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		header  string
+		version uint64
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want bool
+	}{
+		{name: "no header", args: args{header: "", version: 1}, want: false},
+		{name: "same", args: args{header: `"1"`, version: 1}, want: true},
+		{name: "another", args: args{header: `"2"`, version: 1}, want: false},
+		{name: "weak", args: args{header: `W/"1"`, version: 1}, want: true},
+		{name: "list", args: args{header: `"2", "1"`, version: 1}, want: true},
+		{name: "any", args: args{header: "*", version: 0}, want: true},
+		{name: "unknown version", args: args{header: `"0"`, version: 0}, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got := api.NoneMatch(withHeader("If-None-Match", test.args.header), test.args.version)
+
+			toolkit.Assert(t, toolkit.Got(nil, got), toolkit.Want(test.want, nil))
+		})
+	}
+}
+
+func TestUnitIfMatch(t *testing.T) {
+	t.Parallel()
+
+	type want struct {
+		condition domain.Condition
+		version   uint64
+		err       error
+	}
+
+	mismatch := want{condition: domain.ConditionNone, version: 0, err: domain.ErrVersionMismatch}
+
+	tests := []struct {
+		name string
+		args string
+		want want
+	}{
+		{name: "no header", args: "", want: want{condition: domain.ConditionNone, version: 0, err: nil}},
+		{name: "any", args: "*", want: want{condition: domain.ConditionPresent, version: 0, err: nil}},
+		{name: "tag", args: `"42"`, want: want{condition: domain.ConditionNone, version: 42, err: nil}},
+		{name: "weak", args: `W/"42"`, want: mismatch},
+		{name: "list", args: `"1", "2"`, want: mismatch},
+		{name: "unquoted", args: "42", want: mismatch},
+		{name: "zero", args: `"0"`, want: mismatch},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx, err := api.IfMatch(context.Background(), withHeader("If-Match", test.args))
+
+			toolkit.Assert(t, toolkit.Got(err, domain.ConditionFrom(ctx)), toolkit.Want(test.want.condition, test.want.err))
+			toolkit.Assert(t, toolkit.Got(err, domain.VersionFrom(ctx)), toolkit.Want(test.want.version, test.want.err))
+		})
+	}
+}
@@ -1,19 +1,39 @@
 package api
 
 import (
+	"errors"
+	"math"
 	"net/http"
+	"strconv"
 
 	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/domain"
 )
 
 func BadRequestError(err error) *echo.HTTPError {
 	return &echo.HTTPError{Code: http.StatusBadRequest, Message: err, Internal: nil}
 }
 
+func UnauthorizedError(err error) *echo.HTTPError {
+	return &echo.HTTPError{Code: http.StatusUnauthorized, Message: err, Internal: nil}
+}
+
 func NotFoundError(err error) *echo.HTTPError {
 	return &echo.HTTPError{Code: http.StatusNotFound, Message: err, Internal: nil}
 }
 
+func ConflictError(err error) *echo.HTTPError {
+	return &echo.HTTPError{Code: http.StatusConflict, Message: err, Internal: nil}
+}
+
+func PreconditionFailedError(err error) *echo.HTTPError {
+	return &echo.HTTPError{Code: http.StatusPreconditionFailed, Message: err, Internal: nil}
+}
+
+func GoneError(err error) *echo.HTTPError {
+	return &echo.HTTPError{Code: http.StatusGone, Message: err, Internal: nil}
+}
+
 func UnprocessableEntityError(err error) *echo.HTTPError {
 	return &echo.HTTPError{Code: http.StatusUnprocessableEntity, Message: err, Internal: nil}
 }
@@ -22,6 +42,37 @@ func TooManyRequestsError(err error) *echo.HTTPError {
 	return &echo.HTTPError{Code: http.StatusTooManyRequests, Message: err, Internal: nil}
 }
 
+func ServiceUnavailableError(err error) *echo.HTTPError {
+	return &echo.HTTPError{Code: http.StatusServiceUnavailable, Message: err, Internal: nil}
+}
+
+// RetryAfter sets the Retry-After header in whole seconds when the error knows it.
+func RetryAfter(etx echo.Context, err error) {
+	var retry *domain.RetryError
+	if !errors.As(err, &retry) {
+		return
+	}
+
+	seconds := max(1, int(math.Ceil(retry.After.Seconds())))
+
+	etx.Response().Header().Set("Retry-After", strconv.Itoa(seconds))
+}
+
+// Stale tells the client that the value is served stale, by both standard and custom headers.
+func Stale(etx echo.Context) {
+	etx.Response().Header().Set("Warning", `110 - "Response is Stale"`)
+	etx.Response().Header().Set("X-Cache-Stale", "true")
+}
+
+// ETag tells the client the version of the value, the unknown (zero) version has no tag.
+func ETag(etx echo.Context, version uint64) {
+	if version == 0 {
+		return
+	}
+
+	etx.Response().Header().Set("ETag", Tag(version))
+}
+
 func InternalServerError(err error, message ...string) *echo.HTTPError {
 	herr := &echo.HTTPError{Code: http.StatusInternalServerError, Message: "InternalServerError", Internal: err}
 	if len(message) > 0 {
@@ -2,13 +2,16 @@ package api_test
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
 	"github.com/therenotomorrow/apicache/test/toolkit"
 )
 
@@ -68,6 +71,21 @@ func TestUnitBadRequestError(t *testing.T) {
 	}
 }
 
+func TestUnitUnauthorizedError(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range testCases() {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := handleError(api.UnauthorizedError(test.args.err), test.args.debug)
+
+			toolkit.Assert(t, toolkit.Got(nil, code), toolkit.Want(http.StatusUnauthorized, nil))
+			toolkit.Assert(t, toolkit.Got(nil, body), test.want)
+		})
+	}
+}
+
 func TestUnitNotFoundError(t *testing.T) {
 	t.Parallel()
 
@@ -83,6 +101,51 @@ func TestUnitNotFoundError(t *testing.T) {
 	}
 }
 
+func TestUnitConflictError(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range testCases() {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := handleError(api.ConflictError(test.args.err), test.args.debug)
+
+			toolkit.Assert(t, toolkit.Got(nil, code), toolkit.Want(http.StatusConflict, nil))
+			toolkit.Assert(t, toolkit.Got(nil, body), test.want)
+		})
+	}
+}
+
+func TestUnitPreconditionFailedError(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range testCases() {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := handleError(api.PreconditionFailedError(test.args.err), test.args.debug)
+
+			toolkit.Assert(t, toolkit.Got(nil, code), toolkit.Want(http.StatusPreconditionFailed, nil))
+			toolkit.Assert(t, toolkit.Got(nil, body), test.want)
+		})
+	}
+}
+
+func TestUnitGoneError(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range testCases() {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := handleError(api.GoneError(test.args.err), test.args.debug)
+
+			toolkit.Assert(t, toolkit.Got(nil, code), toolkit.Want(http.StatusGone, nil))
+			toolkit.Assert(t, toolkit.Got(nil, body), test.want)
+		})
+	}
+}
+
 func TestUnitUnprocessableEntityError(t *testing.T) {
 	t.Parallel()
 
@@ -113,6 +176,97 @@ func TestUnitTooManyRequestsError(t *testing.T) {
 	}
 }
 
+func TestUnitServiceUnavailableError(t *testing.T) {
+	t.Parallel()
+
+	for _, test := range testCases() {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			code, body := handleError(api.ServiceUnavailableError(test.args.err), test.args.debug)
+
+			toolkit.Assert(t, toolkit.Got(nil, code), toolkit.Want(http.StatusServiceUnavailable, nil))
+			toolkit.Assert(t, toolkit.Got(nil, body), test.want)
+		})
+	}
+}
+
+func TestUnitRetryAfter(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		err error
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want string
+	}{
+		{name: "without retry", args: args{err: errDummy}, want: ""},
+		{name: "round up", args: args{err: &domain.RetryError{Err: errDummy, After: 1500 * time.Millisecond}}, want: "2"},
+		{name: "at least second", args: args{err: &domain.RetryError{Err: errDummy, After: 0}}, want: "1"},
+		{
+			name: "wrapped",
+			args: args{err: fmt.Errorf("wrap: %w", &domain.RetryError{Err: errDummy, After: 3 * time.Second})},
+			want: "3",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			etx := echo.New().NewContext(req, rec)
+
+			api.RetryAfter(etx, test.args.err)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("Retry-After")), toolkit.Want(test.want, nil))
+		})
+	}
+}
+
+func TestUnitStale(t *testing.T) {
+	t.Parallel()
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	rec := httptest.NewRecorder()
+	etx := echo.New().NewContext(req, rec)
+
+	api.Stale(etx)
+
+	toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("Warning")), toolkit.Want(`110 - "Response is Stale"`, nil))
+	toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("X-Cache-Stale")), toolkit.Want("true", nil))
+}
+
+func TestUnitETag(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args uint64
+		want string
+	}{
+		{name: "known", args: 42, want: `"42"`},
+		{name: "unknown", args: 0, want: ""},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			rec := httptest.NewRecorder()
+			etx := echo.New().NewContext(req, rec)
+
+			api.ETag(etx, test.args)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("ETag")), toolkit.Want(test.want, nil))
+		})
+	}
+}
+
 func TestUnitInternalServerError(t *testing.T) {
 	t.Parallel()
 
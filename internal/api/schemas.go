@@ -4,8 +4,24 @@ type BadRequest struct {
 	Message string `enums:"key is expired" json:"message"`
 }
 
+type Unauthorized struct {
+	Message string `enums:"unauthorized" json:"message"`
+}
+
 type NotFound struct {
-	Message string `enums:"key not exist" json:"message"`
+	Message string `enums:"key not exist,subscription not exist,namespace not exist" json:"message"`
+}
+
+type Conflict struct {
+	Message string `enums:"key already exist" json:"message"`
+}
+
+type PreconditionFailed struct {
+	Message string `enums:"key not exist,version mismatch" json:"message"`
+}
+
+type Gone struct {
+	Message string `enums:"offset gone" json:"message"`
 }
 
 type UnprocessableEntity struct {
@@ -13,7 +29,11 @@ type UnprocessableEntity struct {
 }
 
 type TooManyRequests struct {
-	Message string `enums:"connection timeout,context timeout" json:"message"`
+	Message string `enums:"connection timeout,context timeout,read pool exhausted: connection timeout,read pool exhausted: context timeout,write pool exhausted: connection timeout,write pool exhausted: context timeout" json:"message"`
+}
+
+type ServiceUnavailable struct {
+	Message string `enums:"request shed,circuit open,closed instance" json:"message"`
 }
 
 type InternalServer struct {
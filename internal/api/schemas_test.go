@@ -24,6 +24,22 @@ func TestUnitBadRequest(t *testing.T) {
 	)
 }
 
+func TestUnitUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	tags := reflect.TypeOf(new(api.Unauthorized)).Elem().Field(0).Tag
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("enums")),
+		toolkit.Want("unauthorized", nil),
+	)
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("json")),
+		toolkit.Want("message", nil),
+	)
+}
+
 func TestUnitNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -31,7 +47,55 @@ func TestUnitNotFound(t *testing.T) {
 
 	toolkit.Assert(t,
 		toolkit.Got(nil, tags.Get("enums")),
-		toolkit.Want("key not exist", nil),
+		toolkit.Want("key not exist,subscription not exist,namespace not exist", nil),
+	)
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("json")),
+		toolkit.Want("message", nil),
+	)
+}
+
+func TestUnitConflict(t *testing.T) {
+	t.Parallel()
+
+	tags := reflect.TypeOf(new(api.Conflict)).Elem().Field(0).Tag
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("enums")),
+		toolkit.Want("key already exist", nil),
+	)
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("json")),
+		toolkit.Want("message", nil),
+	)
+}
+
+func TestUnitPreconditionFailed(t *testing.T) {
+	t.Parallel()
+
+	tags := reflect.TypeOf(new(api.PreconditionFailed)).Elem().Field(0).Tag
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("enums")),
+		toolkit.Want("key not exist,version mismatch", nil),
+	)
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("json")),
+		toolkit.Want("message", nil),
+	)
+}
+
+func TestUnitGone(t *testing.T) {
+	t.Parallel()
+
+	tags := reflect.TypeOf(new(api.Gone)).Elem().Field(0).Tag
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("enums")),
+		toolkit.Want("offset gone", nil),
 	)
 
 	toolkit.Assert(t,
@@ -58,7 +122,25 @@ func TestUnitTooManyRequests(t *testing.T) {
 
 	toolkit.Assert(t,
 		toolkit.Got(nil, tags.Get("enums")),
-		toolkit.Want("connection timeout,context timeout", nil),
+		toolkit.Want("connection timeout,context timeout,"+
+			"read pool exhausted: connection timeout,read pool exhausted: context timeout,"+
+			"write pool exhausted: connection timeout,write pool exhausted: context timeout", nil),
+	)
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("json")),
+		toolkit.Want("message", nil),
+	)
+}
+
+func TestUnitServiceUnavailable(t *testing.T) {
+	t.Parallel()
+
+	tags := reflect.TypeOf(new(api.ServiceUnavailable)).Elem().Field(0).Tag
+
+	toolkit.Assert(t,
+		toolkit.Got(nil, tags.Get("enums")),
+		toolkit.Want("request shed,circuit open,closed instance", nil),
 	)
 
 	toolkit.Assert(t,
@@ -14,10 +14,13 @@ import (
 // @Summary    "Delete key/value pair"
 // @Tags       cache
 // @Param      key path string true "Key"
+// @Param      If-Match header string false "Entity tag of the version to delete, * requires the key to exist"
 // @Success    204
+// @Failure    412 {object} api.PreconditionFailed
 // @Failure    422 {object} api.UnprocessableEntity
 // @Failure    429 {object} api.TooManyRequests
 // @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
 // @Router     /api/v1/{key}/ [delete].
 func Delete(cache domain.CacheDeleter) echo.HandlerFunc {
 	params := blender.New[api.Params]()
@@ -29,16 +32,35 @@ func Delete(cache domain.CacheDeleter) echo.HandlerFunc {
 			return api.UnprocessableEntityError(err)
 		}
 
-		err = useCase.Execute(etx.Request().Context(), params.Key)
+		ctx, err := api.IfMatch(etx.Request().Context(), etx)
+		if err != nil {
+			return api.PreconditionFailedError(err)
+		}
+
+		err = useCase.Execute(ctx, params.Key)
 		if err == nil {
 			return etx.NoContent(http.StatusNoContent)
 		}
 
 		switch {
+		case errors.Is(err, domain.ErrInvalidKey):
+			return api.UnprocessableEntityError(err)
+		case errors.Is(err, domain.ErrKeyNotExist):
+			return api.PreconditionFailedError(err)
+		case errors.Is(err, domain.ErrVersionMismatch):
+			return api.PreconditionFailedError(err)
+		case errors.Is(err, domain.ErrUnsupported):
+			return api.UnprocessableEntityError(err)
 		case errors.Is(err, domain.ErrConnTimeout):
 			return api.TooManyRequestsError(err)
 		case errors.Is(err, domain.ErrContextTimeout):
 			return api.TooManyRequestsError(err)
+		case errors.Is(err, domain.ErrShed):
+			return api.ServiceUnavailableError(err)
+		case errors.Is(err, domain.ErrCircuitOpen):
+			api.RetryAfter(etx, err)
+
+			return api.ServiceUnavailableError(err)
 		}
 
 		etx.Logger().Error(err)
@@ -7,6 +7,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	apiv1delete "github.com/therenotomorrow/apicache/internal/api/v1/delete"
@@ -20,6 +21,10 @@ const (
 	Smoke3 = "smoke3"
 	Smoke4 = "smoke4"
 	Smoke5 = "smoke5"
+	Smoke6 = "smoke6"
+	Smoke7 = "smoke7"
+	Smoke8 = "smoke8"
+	Smoke9 = "smoke9"
 )
 
 var errDummy = errors.New("dummy error")
@@ -32,10 +37,13 @@ type (
 	}
 	args struct {
 		params *params
+		// match is the If-Match header.
+		match string
 	}
 	want struct {
-		code int
-		body string
+		code  int
+		body  string
+		retry string
 	}
 	testCase struct {
 		name string
@@ -44,7 +52,16 @@ type (
 	}
 )
 
-func (c cacheDeleter) Del(_ context.Context, key string) error {
+func (c cacheDeleter) Del(ctx context.Context, key string) error {
+	// smoke9 lives in the cache that knows no versions
+	if domain.VersionFrom(ctx) > 0 && key == Smoke9 {
+		return domain.ErrUnsupported
+	}
+
+	if version := domain.VersionFrom(ctx); version > 0 && version != 7 {
+		return domain.ErrVersionMismatch
+	}
+
 	switch key {
 	case Smoke2:
 		return domain.ErrConnTimeout
@@ -52,6 +69,14 @@ func (c cacheDeleter) Del(_ context.Context, key string) error {
 		return domain.ErrContextTimeout
 	case Smoke4:
 		return errDummy
+	case Smoke6:
+		return domain.ErrShed
+	case Smoke7:
+		return &domain.RetryError{Err: domain.ErrCircuitOpen, After: 1500 * time.Millisecond}
+	case Smoke8:
+		if domain.ConditionFrom(ctx) == domain.ConditionPresent {
+			return domain.ErrKeyNotExist
+		}
 	}
 
 	return nil
@@ -61,7 +86,39 @@ func successTC() testCase {
 	return testCase{
 		name: Smoke1,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke1}}},
-		want: want{code: http.StatusNoContent, body: ""},
+		want: want{code: http.StatusNoContent, body: "", retry: ""},
+	}
+}
+
+func versionMatchTC() testCase {
+	return testCase{
+		name: "version match",
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke1}}, match: `"7"`},
+		want: want{code: http.StatusNoContent, body: "", retry: ""},
+	}
+}
+
+func versionMismatchTC(match string) testCase {
+	return testCase{
+		name: "version mismatch " + match,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke1}}, match: match},
+		want: want{code: http.StatusPreconditionFailed, body: `{"message":"version mismatch"}`, retry: ""},
+	}
+}
+
+func unsupportedTC() testCase {
+	return testCase{
+		name: Smoke9,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke9}}, match: `"7"`},
+		want: want{code: http.StatusUnprocessableEntity, body: `{"message":"unsupported by the driver"}`, retry: ""},
+	}
+}
+
+func keyNotExistTC() testCase {
+	return testCase{
+		name: Smoke8,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke8}}, match: "*"},
+		want: want{code: http.StatusPreconditionFailed, body: `{"message":"key not exist"}`, retry: ""},
 	}
 }
 
@@ -69,7 +126,7 @@ func connectionTimeoutTC() testCase {
 	return testCase{
 		name: Smoke2,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke2}}},
-		want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`},
+		want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`, retry: ""},
 	}
 }
 
@@ -77,7 +134,23 @@ func contextTimeoutTC() testCase {
 	return testCase{
 		name: Smoke3,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke3}}},
-		want: want{code: http.StatusTooManyRequests, body: `{"message":"context timeout"}`},
+		want: want{code: http.StatusTooManyRequests, body: `{"message":"context timeout"}`, retry: ""},
+	}
+}
+
+func shedTC() testCase {
+	return testCase{
+		name: Smoke6,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke6}}},
+		want: want{code: http.StatusServiceUnavailable, body: `{"message":"request shed"}`, retry: ""},
+	}
+}
+
+func circuitOpenTC() testCase {
+	return testCase{
+		name: Smoke7,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke7}}},
+		want: want{code: http.StatusServiceUnavailable, body: `{"message":"circuit open"}`, retry: "2"},
 	}
 }
 
@@ -85,7 +158,7 @@ func failureTC() testCase {
 	return testCase{
 		name: Smoke4,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke4}}},
-		want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`},
+		want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`, retry: ""},
 	}
 }
 
@@ -97,6 +170,7 @@ func invalidParamsTC() testCase {
 			code: http.StatusUnprocessableEntity,
 			body: "{\"message\":\"validate error: Key: 'Params.Key' Error:" +
 				"Field validation for 'Key' failed on the 'required' tag\"}",
+			retry: "",
 		},
 	}
 }
@@ -106,8 +180,15 @@ func TestUnitDelete(t *testing.T) {
 
 	tests := []testCase{
 		successTC(),
+		versionMatchTC(),
+		versionMismatchTC(`"6"`),
+		versionMismatchTC(`W/"7"`),
+		unsupportedTC(),
+		keyNotExistTC(),
 		connectionTimeoutTC(),
 		contextTimeoutTC(),
+		shedTC(),
+		circuitOpenTC(),
 		failureTC(),
 		invalidParamsTC(),
 	}
@@ -117,6 +198,8 @@ func TestUnitDelete(t *testing.T) {
 			t.Parallel()
 
 			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			req.Header.Set("If-Match", test.args.match)
+
 			rec := httptest.NewRecorder()
 			mux := echo.New()
 
@@ -128,6 +211,7 @@ func TestUnitDelete(t *testing.T) {
 
 			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
 			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("Retry-After")), toolkit.Want(test.want.retry, nil))
 		})
 	}
 }
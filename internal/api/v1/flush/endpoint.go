@@ -0,0 +1,50 @@
+package apiv1flush
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+// Flush ----
+// @Summary    "Delete all the keys of the namespace at once"
+// @Tags       namespaces
+// @Param      namespace path string true "Namespace"
+// @Success    204
+// @Failure    404 {object} api.NotFound
+// @Failure    429 {object} api.TooManyRequests
+// @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
+// @Router     /api/v1/ns/{namespace}/ [delete].
+func Flush(cache domain.CacheFlusher) echo.HandlerFunc {
+	useCase := domain.NewFlushUseCase(cache)
+
+	return func(etx echo.Context) error {
+		err := useCase.Execute(etx.Request().Context())
+		if err == nil {
+			return etx.NoContent(http.StatusNoContent)
+		}
+
+		switch {
+		case errors.Is(err, domain.ErrNoNamespace):
+			return api.NotFoundError(err)
+		case errors.Is(err, domain.ErrConnTimeout):
+			return api.TooManyRequestsError(err)
+		case errors.Is(err, domain.ErrContextTimeout):
+			return api.TooManyRequestsError(err)
+		case errors.Is(err, domain.ErrShed):
+			return api.ServiceUnavailableError(err)
+		case errors.Is(err, domain.ErrCircuitOpen):
+			api.RetryAfter(etx, err)
+
+			return api.ServiceUnavailableError(err)
+		}
+
+		etx.Logger().Error(err)
+
+		return api.InternalServerError(err)
+	}
+}
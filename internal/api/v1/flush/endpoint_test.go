@@ -0,0 +1,76 @@
+package apiv1flush_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/labstack/echo/v4"
+	apiv1flush "github.com/therenotomorrow/apicache/internal/api/v1/flush"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const (
+	Smoke1 = "smoke1"
+	Smoke2 = "smoke2"
+	Smoke3 = "smoke3"
+	Smoke4 = "smoke4"
+)
+
+var errDummy = errors.New("dummy error")
+
+type (
+	cacheFlusher struct{}
+	want         struct {
+		code int
+		body string
+	}
+)
+
+func (c cacheFlusher) Flush(ctx context.Context) error {
+	switch domain.NamespaceFrom(ctx) {
+	case Smoke2:
+		return domain.ErrConnTimeout
+	case Smoke3:
+		return errDummy
+	}
+
+	return nil
+}
+
+func TestUnitFlush(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want want
+	}{
+		{name: Smoke1, args: Smoke1, want: want{code: http.StatusNoContent, body: ""}},
+		{name: Smoke2, args: Smoke2, want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`}},
+		{name: Smoke3, args: Smoke3, want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`}},
+		{name: Smoke4, args: "", want: want{code: http.StatusNotFound, body: `{"message":"namespace not exist"}`}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodDelete, "/", nil)
+			req = req.WithContext(domain.WithNamespace(req.Context(), test.args))
+			rec := httptest.NewRecorder()
+			mux := echo.New()
+
+			etx := mux.NewContext(req, rec)
+
+			mux.HTTPErrorHandler(apiv1flush.Flush(cacheFlusher{})(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+		})
+	}
+}
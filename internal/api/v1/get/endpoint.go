@@ -19,17 +19,23 @@ type Response struct {
 // @Summary    "Retrieve key/value pair"
 // @Tags       cache
 // @Param      key path string true "Key"
+// @Param      If-None-Match header string false "Entity tags of the known versions, * matches any"
 // @Produce    json
 // @Success    200 {object} Response
+// @Header     200 {string} ETag "Version of the value, the stale and the just loaded values have none"
+// @Header     200 {string} X-Cache-Stale "true when the value is stale"
+// @Success    304
 // @Failure    400 {object} api.BadRequest
 // @Failure    404 {object} api.NotFound
 // @Failure    422 {object} api.UnprocessableEntity
 // @Failure    429 {object} api.TooManyRequests
 // @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
 // @Router     /api/v1/{key}/ [get].
-func Get(cache domain.CacheGetter) echo.HandlerFunc {
+func Get(cache domain.CacheVersioner, loader domain.CacheLoader) echo.HandlerFunc {
 	params := blender.New[api.Params]()
-	useCase := domain.NewGetUseCase(cache)
+	useCase := domain.NewGetVersionUseCase(cache)
+	loadCase := domain.NewGetOrLoadUseCase(loader)
 
 	return func(etx echo.Context) error {
 		params, err := params.Path(etx)
@@ -37,12 +43,33 @@ func Get(cache domain.CacheGetter) echo.HandlerFunc {
 			return api.UnprocessableEntityError(err)
 		}
 
-		val, err := useCase.Execute(etx.Request().Context(), params.Key)
+		val, version, err := useCase.Execute(etx.Request().Context(), params.Key)
+		// the missed key is filled by the loader, nil loader means no loading
+		if loader != nil && (errors.Is(err, domain.ErrKeyNotExist) || errors.Is(err, domain.ErrKeyExpired)) {
+			// the loaded value gets its version on the next read
+			val, err = loadCase.Execute(etx.Request().Context(), params.Key)
+			version = 0
+		}
+
+		if errors.Is(err, domain.ErrStale) {
+			api.Stale(etx)
+
+			err = nil
+		}
+
 		if err == nil {
+			api.ETag(etx, version)
+
+			if api.NoneMatch(etx, version) {
+				return etx.NoContent(http.StatusNotModified)
+			}
+
 			return etx.JSON(http.StatusOK, &Response{Key: params.Key, Val: val})
 		}
 
 		switch {
+		case errors.Is(err, domain.ErrInvalidKey):
+			return api.UnprocessableEntityError(err)
 		case errors.Is(err, domain.ErrKeyExpired):
 			return api.BadRequestError(err)
 		case errors.Is(err, domain.ErrKeyNotExist):
@@ -51,6 +78,12 @@ func Get(cache domain.CacheGetter) echo.HandlerFunc {
 			return api.TooManyRequestsError(err)
 		case errors.Is(err, domain.ErrContextTimeout):
 			return api.TooManyRequestsError(err)
+		case errors.Is(err, domain.ErrShed):
+			return api.ServiceUnavailableError(err)
+		case errors.Is(err, domain.ErrCircuitOpen):
+			api.RetryAfter(etx, err)
+
+			return api.ServiceUnavailableError(err)
 		}
 
 		etx.Logger().Error(err)
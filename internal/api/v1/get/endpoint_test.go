@@ -3,10 +3,12 @@ package apiv1get_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	apiv1get "github.com/therenotomorrow/apicache/internal/api/v1/get"
@@ -15,13 +17,17 @@ import (
 )
 
 const (
-	Smoke1 = "smoke1"
-	Smoke2 = "smoke2"
-	Smoke3 = "smoke3"
-	Smoke4 = "smoke4"
-	Smoke5 = "smoke5"
-	Smoke6 = "smoke6"
-	Smoke7 = "smoke7"
+	Smoke1  = "smoke1"
+	Smoke2  = "smoke2"
+	Smoke3  = "smoke3"
+	Smoke4  = "smoke4"
+	Smoke5  = "smoke5"
+	Smoke6  = "smoke6"
+	Smoke7  = "smoke7"
+	Smoke8  = "smoke8"
+	Smoke9  = "smoke9"
+	Smoke10 = "smoke10"
+	Smoke11 = "smoke11"
 )
 
 var errDummy = errors.New("dummy error")
@@ -34,10 +40,15 @@ type (
 	}
 	args struct {
 		params *params
+		// match is the If-None-Match header.
+		match string
 	}
 	want struct {
-		code int
-		body string
+		code  int
+		body  string
+		retry string
+		stale string
+		etag  string
 	}
 	testCase struct {
 		name string
@@ -46,28 +57,76 @@ type (
 	}
 )
 
-func (c cacheGetter) Get(_ context.Context, key string) ([]byte, error) {
+func (c cacheGetter) GetVersion(_ context.Context, key string) ([]byte, uint64, error) {
 	switch key {
 	case Smoke2:
-		return nil, domain.ErrKeyExpired
+		return nil, 0, domain.ErrKeyExpired
 	case Smoke3:
-		return nil, domain.ErrKeyNotExist
+		return nil, 0, domain.ErrKeyNotExist
 	case Smoke4:
-		return nil, domain.ErrConnTimeout
+		return nil, 0, domain.ErrConnTimeout
 	case Smoke5:
-		return nil, domain.ErrContextTimeout
+		return nil, 0, domain.ErrContextTimeout
+	case Smoke6:
+		return nil, 0, errDummy
+	case Smoke9:
+		return nil, 0, domain.ErrShed
+	case Smoke10:
+		return nil, 0, &domain.RetryError{Err: domain.ErrCircuitOpen, After: 1500 * time.Millisecond}
+	case Smoke11:
+		return []byte(`{"hello":"world","age":42}`), 0, domain.ErrStale
+	case Smoke8:
+		return nil, 0, fmt.Errorf("%w: %w", domain.ErrReadPoolExhausted, domain.ErrConnTimeout)
+	}
+
+	return []byte(`{"hello":"world","age":42}`), 7, nil
+}
+
+// GetOrLoad is the origin that has only the missed key.
+func (c cacheGetter) GetOrLoad(_ context.Context, key string) ([]byte, error) {
+	switch key {
+	case Smoke3:
+		return []byte(`{"hello":"origin"}`), nil
 	case Smoke6:
 		return nil, errDummy
 	}
 
-	return []byte(`{"hello":"world","age":42}`), nil
+	return nil, domain.ErrKeyNotExist
 }
 
 func successTC() testCase {
 	return testCase{
 		name: Smoke1,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke1}}},
-		want: want{code: http.StatusOK, body: `{"key":"smoke1","val":{"age":42,"hello":"world"}}`},
+		want: want{
+			code:  http.StatusOK,
+			body:  `{"key":"smoke1","val":{"age":42,"hello":"world"}}`,
+			retry: "",
+			stale: "",
+			etag:  `"7"`,
+		},
+	}
+}
+
+func notModifiedTC(match string) testCase {
+	return testCase{
+		name: "not modified " + match,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke1}}, match: match},
+		want: want{code: http.StatusNotModified, body: "", retry: "", stale: "", etag: `"7"`},
+	}
+}
+
+func modifiedTC() testCase {
+	return testCase{
+		name: "modified",
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke1}}, match: `"6"`},
+		want: want{
+			code:  http.StatusOK,
+			body:  `{"key":"smoke1","val":{"age":42,"hello":"world"}}`,
+			retry: "",
+			stale: "",
+			etag:  `"7"`,
+		},
 	}
 }
 
@@ -75,7 +134,7 @@ func expiredKeyTC() testCase {
 	return testCase{
 		name: Smoke2,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke2}}},
-		want: want{code: http.StatusBadRequest, body: `{"message":"key is expired"}`},
+		want: want{code: http.StatusBadRequest, body: `{"message":"key is expired"}`, retry: "", stale: "", etag: ""},
 	}
 }
 
@@ -83,7 +142,7 @@ func keyNotExistTC() testCase {
 	return testCase{
 		name: Smoke3,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke3}}},
-		want: want{code: http.StatusNotFound, body: `{"message":"key not exist"}`},
+		want: want{code: http.StatusNotFound, body: `{"message":"key not exist"}`, retry: "", stale: "", etag: ""},
 	}
 }
 
@@ -91,7 +150,13 @@ func connectionTimeoutTC() testCase {
 	return testCase{
 		name: Smoke4,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke4}}},
-		want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`},
+		want: want{
+			code:  http.StatusTooManyRequests,
+			body:  `{"message":"connection timeout"}`,
+			retry: "",
+			stale: "",
+			etag:  "",
+		},
 	}
 }
 
@@ -99,7 +164,51 @@ func contextTimeoutTC() testCase {
 	return testCase{
 		name: Smoke5,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke5}}},
-		want: want{code: http.StatusTooManyRequests, body: `{"message":"context timeout"}`},
+		want: want{code: http.StatusTooManyRequests, body: `{"message":"context timeout"}`, retry: "", stale: "", etag: ""},
+	}
+}
+
+func poolExhaustedTC() testCase {
+	return testCase{
+		name: Smoke8,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke8}}},
+		want: want{
+			code:  http.StatusTooManyRequests,
+			body:  `{"message":"read pool exhausted: connection timeout"}`,
+			retry: "",
+			stale: "",
+			etag:  "",
+		},
+	}
+}
+
+func shedTC() testCase {
+	return testCase{
+		name: Smoke9,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke9}}},
+		want: want{code: http.StatusServiceUnavailable, body: `{"message":"request shed"}`, retry: "", stale: "", etag: ""},
+	}
+}
+
+func circuitOpenTC() testCase {
+	return testCase{
+		name: Smoke10,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke10}}},
+		want: want{code: http.StatusServiceUnavailable, body: `{"message":"circuit open"}`, retry: "2", stale: "", etag: ""},
+	}
+}
+
+func staleTC() testCase {
+	return testCase{
+		name: Smoke11,
+		args: args{params: &params{names: []string{"key"}, values: []string{Smoke11}}},
+		want: want{
+			code:  http.StatusOK,
+			body:  `{"key":"smoke11","val":{"age":42,"hello":"world"}}`,
+			retry: "",
+			stale: "true",
+			etag:  "",
+		},
 	}
 }
 
@@ -107,7 +216,13 @@ func failureTC() testCase {
 	return testCase{
 		name: Smoke6,
 		args: args{params: &params{names: []string{"key"}, values: []string{Smoke6}}},
-		want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`},
+		want: want{
+			code:  http.StatusInternalServerError,
+			body:  `{"message":"InternalServerError"}`,
+			retry: "",
+			stale: "",
+			etag:  "",
+		},
 	}
 }
 
@@ -119,6 +234,9 @@ func invalidParamsTC() testCase {
 			code: http.StatusUnprocessableEntity,
 			body: "{\"message\":\"validate error: Key: 'Params.Key' Error:" +
 				"Field validation for 'Key' failed on the 'required' tag\"}",
+			retry: "",
+			stale: "",
+			etag:  "",
 		},
 	}
 }
@@ -128,14 +246,66 @@ func TestUnitGet(t *testing.T) {
 
 	tests := []testCase{
 		successTC(),
+		notModifiedTC(`"7"`),
+		notModifiedTC("*"),
+		modifiedTC(),
+		staleTC(),
 		expiredKeyTC(),
 		keyNotExistTC(),
 		connectionTimeoutTC(),
 		contextTimeoutTC(),
+		poolExhaustedTC(),
+		shedTC(),
+		circuitOpenTC(),
 		failureTC(),
 		invalidParamsTC(),
 	}
 
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.Header.Set("If-None-Match", test.args.match)
+
+			rec := httptest.NewRecorder()
+			mux := echo.New()
+
+			etx := mux.NewContext(req, rec)
+			etx.SetParamNames(test.args.params.names...)
+			etx.SetParamValues(test.args.params.values...)
+
+			mux.HTTPErrorHandler(apiv1get.Get(cacheGetter{}, nil)(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("Retry-After")), toolkit.Want(test.want.retry, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("X-Cache-Stale")), toolkit.Want(test.want.stale, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("ETag")), toolkit.Want(test.want.etag, nil))
+		})
+	}
+}
+
+func TestUnitGetLoad(t *testing.T) {
+	t.Parallel()
+
+	tests := []testCase{
+		successTC(),
+		staleTC(),
+		{
+			name: "loaded",
+			args: args{params: &params{names: []string{"key"}, values: []string{Smoke3}}},
+			want: want{code: http.StatusOK, body: `{"key":"smoke3","val":{"hello":"origin"}}`, retry: "", stale: "", etag: ""},
+		},
+		{
+			name: "expired not loaded",
+			args: args{params: &params{names: []string{"key"}, values: []string{Smoke2}}},
+			want: want{code: http.StatusNotFound, body: `{"message":"key not exist"}`, retry: "", stale: "", etag: ""},
+		},
+		connectionTimeoutTC(),
+		failureTC(),
+	}
+
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
@@ -148,10 +318,12 @@ func TestUnitGet(t *testing.T) {
 			etx.SetParamNames(test.args.params.names...)
 			etx.SetParamValues(test.args.params.values...)
 
-			mux.HTTPErrorHandler(apiv1get.Get(cacheGetter{})(etx), etx)
+			mux.HTTPErrorHandler(apiv1get.Get(cacheGetter{}, cacheGetter{})(etx), etx)
 
 			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
 			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("X-Cache-Stale")), toolkit.Want(test.want.stale, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("ETag")), toolkit.Want(test.want.etag, nil))
 		})
 	}
 }
@@ -3,6 +3,7 @@ package apiv1post
 import (
 	"errors"
 	"net/http"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/therenotomorrow/apicache/internal/api"
@@ -13,7 +14,12 @@ import (
 type (
 	Payload struct {
 		Val domain.ValType `json:"val" validate:"required"`
-		TTL int            `json:"ttl" validate:"omitempty,min=0"`
+		// TTL is a number of seconds or a duration string, e.g. "1500ms" or "2h30m".
+		TTL api.Duration `example:"1500ms" json:"ttl" swaggertype:"string" validate:"omitempty,min=0"`
+		// ExpireAt is an RFC3339 deadline in the future, it excludes TTL.
+		ExpireAt time.Time `json:"expireAt"`
+		// Sliding extends the deadline by the TTL on every successful read, Stateless driver refuses it.
+		Sliding bool `json:"sliding"`
 	}
 	Response struct {
 		Key string         `json:"key"`
@@ -21,22 +27,34 @@ type (
 	}
 )
 
+var conditions = map[string]domain.Condition{
+	"":   domain.ConditionNone,
+	"nx": domain.ConditionAbsent,
+	"xx": domain.ConditionPresent,
+}
+
 // Post ----
 // @Summary    "Insert key/value pair"
 // @Tags       cache
 // @Param      key path string true "Key"
+// @Param      mode query string false "Write only if the key is absent (nx) or present (xx)" Enums(nx, xx)
+// @Param      If-Match header string false "Entity tag of the version to replace, * requires the key to exist, excludes mode"
 // @Accept     json
 // @Param      payload body Payload true "Payload"
 // @Produce    json
 // @Success    201 {object} Response
+// @Failure    409 {object} api.Conflict
+// @Failure    412 {object} api.PreconditionFailed
 // @Failure    422 {object} api.UnprocessableEntity
 // @Failure    429 {object} api.TooManyRequests
 // @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
 // @Router     /api/v1/{key}/ [post].
-func Post(cache domain.CacheSetter) echo.HandlerFunc {
+func Post(cache domain.CacheSetter, clock domain.Clock, policy domain.TTLPolicy) echo.HandlerFunc {
 	params := blender.New[api.Params]()
+	query := blender.New[api.WriteParams]()
 	payload := blender.New[Payload]()
-	useCase := domain.NewSetUseCase(cache)
+	useCase := domain.NewSetUseCase(cache, clock, policy)
 
 	return func(etx echo.Context) error {
 		params, err := params.Path(etx)
@@ -44,21 +62,59 @@ func Post(cache domain.CacheSetter) echo.HandlerFunc {
 			return api.UnprocessableEntityError(err)
 		}
 
+		query, err := query.Query(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
 		payload, err := payload.JSON(etx)
 		if err != nil {
 			return api.UnprocessableEntityError(err)
 		}
 
-		err = useCase.Execute(etx.Request().Context(), params.Key, payload.Val, payload.TTL)
+		if query.Mode != "" && etx.Request().Header.Get("If-Match") != "" {
+			return api.UnprocessableEntityError(api.ErrModeIfMatch)
+		}
+
+		ctx, err := api.IfMatch(domain.WithCondition(etx.Request().Context(), conditions[query.Mode]), etx)
+		if err != nil {
+			return api.PreconditionFailedError(err)
+		}
+
+		expiry := domain.Expiry{TTL: time.Duration(payload.TTL), ExpireAt: payload.ExpireAt, Sliding: payload.Sliding}
+
+		err = useCase.Execute(ctx, params.Key, payload.Val, expiry)
 		if err == nil {
 			return etx.JSON(http.StatusCreated, &Response{Key: params.Key, Val: payload.Val})
 		}
 
 		switch {
+		case errors.Is(err, domain.ErrInvalidKey):
+			return api.UnprocessableEntityError(err)
+		case errors.Is(err, domain.ErrKeyExists):
+			return api.ConflictError(err)
+		case errors.Is(err, domain.ErrKeyNotExist):
+			return api.PreconditionFailedError(err)
+		case errors.Is(err, domain.ErrVersionMismatch):
+			return api.PreconditionFailedError(err)
+		case errors.Is(err, domain.ErrUnsupported):
+			return api.UnprocessableEntityError(err)
+		case errors.Is(err, domain.ErrInvalidExpiry):
+			return api.UnprocessableEntityError(err)
+		case errors.Is(err, domain.ErrPastDeadline):
+			return api.UnprocessableEntityError(err)
+		case errors.Is(err, domain.ErrInfiniteKey):
+			return api.UnprocessableEntityError(err)
 		case errors.Is(err, domain.ErrConnTimeout):
 			return api.TooManyRequestsError(err)
 		case errors.Is(err, domain.ErrContextTimeout):
 			return api.TooManyRequestsError(err)
+		case errors.Is(err, domain.ErrShed):
+			return api.ServiceUnavailableError(err)
+		case errors.Is(err, domain.ErrCircuitOpen):
+			api.RetryAfter(etx, err)
+
+			return api.ServiceUnavailableError(err)
 		}
 
 		etx.Logger().Error(err)
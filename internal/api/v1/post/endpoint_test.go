@@ -3,6 +3,7 @@ package apiv1post_test
 import (
 	"context"
 	"errors"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -12,24 +13,33 @@ import (
 	"github.com/labstack/echo/v4"
 	apiv1post "github.com/therenotomorrow/apicache/internal/api/v1/post"
 	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/clock"
 	"github.com/therenotomorrow/apicache/test/toolkit"
 )
 
 const (
-	Smoke1 = "smoke1"
-	Smoke2 = "smoke2"
-	Smoke3 = "smoke3"
-	Smoke4 = "smoke4"
-	Smoke5 = "smoke5"
-	Smoke6 = "smoke6"
-	Smoke7 = "smoke7"
-	Smoke8 = "smoke8"
+	Smoke1  = "smoke1"
+	Smoke2  = "smoke2"
+	Smoke3  = "smoke3"
+	Smoke4  = "smoke4"
+	Smoke5  = "smoke5"
+	Smoke6  = "smoke6"
+	Smoke7  = "smoke7"
+	Smoke8  = "smoke8"
+	Smoke9  = "smoke9"
+	Smoke10 = "smoke10"
+	Smoke11 = "smoke11"
+	Smoke12 = "smoke12"
+	Smoke13 = "smoke13"
+	Smoke14 = "smoke14"
+	Smoke15 = "smoke15"
 )
 
 var errDummy = errors.New("dummy error")
 
 type (
 	cacheSetter struct{}
+	ttlPolicy   struct{}
 	params      struct {
 		names  []string
 		values []string
@@ -39,8 +49,9 @@ type (
 		payload string
 	}
 	want struct {
-		code int
-		body string
+		code  int
+		body  string
+		retry string
 	}
 	testCase struct {
 		name string
@@ -49,14 +60,44 @@ type (
 	}
 )
 
-func (c cacheSetter) Set(_ context.Context, key string, _ []byte, _ time.Time) error {
+func (c cacheSetter) Set(ctx context.Context, key string, _ []byte, _ time.Time) error {
+	condition := domain.ConditionFrom(ctx)
+
+	// smoke15 lives in the cache that can't serve the conditional writes
+	if (condition != domain.ConditionNone || domain.VersionFrom(ctx) > 0) && key == Smoke15 {
+		return domain.ErrUnsupported
+	}
+
+	// smoke14 is the only existing key for the conditional writes, its version is 7
+	if condition == domain.ConditionAbsent && key == Smoke14 {
+		return domain.ErrKeyExists
+	}
+
+	if condition == domain.ConditionPresent && key != Smoke14 {
+		return domain.ErrKeyNotExist
+	}
+
+	if version := domain.VersionFrom(ctx); version > 0 && (key != Smoke14 || version != 7) {
+		return domain.ErrVersionMismatch
+	}
+
 	switch key {
+	case Smoke12:
+		if !domain.SlidingFrom(ctx) {
+			return errDummy
+		}
 	case Smoke2:
 		return domain.ErrConnTimeout
 	case Smoke3:
 		return domain.ErrContextTimeout
 	case Smoke4:
 		return errDummy
+	case Smoke10:
+		return domain.ErrShed
+	case Smoke11:
+		return &domain.RetryError{Err: domain.ErrCircuitOpen, After: 1500 * time.Millisecond}
+	case Smoke9:
+		return fmt.Errorf("%w: %w", domain.ErrWritePoolExhausted, domain.ErrContextTimeout)
 	}
 
 	return nil
@@ -69,7 +110,97 @@ func successTC() testCase {
 			params:  &params{names: []string{"key"}, values: []string{Smoke1}},
 			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
 		},
-		want: want{code: http.StatusCreated, body: `{"key":"smoke1","val":{"age":42,"hello":"world"}}`},
+		want: want{code: http.StatusCreated, body: `{"key":"smoke1","val":{"age":42,"hello":"world"}}`, retry: ""},
+	}
+}
+
+func slidingTC() testCase {
+	return testCase{
+		name: Smoke12,
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke12}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":10,"sliding":true}`,
+		},
+		want: want{code: http.StatusCreated, body: `{"key":"smoke12","val":{"age":42,"hello":"world"}}`, retry: ""},
+	}
+}
+
+func durationTC() testCase {
+	return testCase{
+		name: "duration",
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke1}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":"1500ms"}`,
+		},
+		want: want{code: http.StatusCreated, body: `{"key":"smoke1","val":{"age":42,"hello":"world"}}`, retry: ""},
+	}
+}
+
+func expireAtTC() testCase {
+	return testCase{
+		name: "expire at",
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke1}},
+			payload: `{"val":{"age":42,"hello":"world"},"expireAt":"2999-01-01T00:00:00Z"}`,
+		},
+		want: want{code: http.StatusCreated, body: `{"key":"smoke1","val":{"age":42,"hello":"world"}}`, retry: ""},
+	}
+}
+
+func (p ttlPolicy) Deadline(key string, deadline time.Time, _ time.Time) (time.Time, error) {
+	if key == Smoke13 && deadline.IsZero() {
+		return time.Time{}, domain.ErrInfiniteKey
+	}
+
+	return deadline, nil
+}
+
+func infiniteKeyTC() testCase {
+	return testCase{
+		name: "infinite key",
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke13}},
+			payload: `{"val":{"age":42,"hello":"world"}}`,
+		},
+		want: want{code: http.StatusUnprocessableEntity, body: `{"message":"infinite key forbidden"}`, retry: ""},
+	}
+}
+
+func pastDeadlineTC() testCase {
+	return testCase{
+		name: "past deadline",
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke1}},
+			payload: `{"val":{"age":42,"hello":"world"},"expireAt":"2000-01-01T00:00:00Z"}`,
+		},
+		want: want{code: http.StatusUnprocessableEntity, body: `{"message":"deadline in the past"}`, retry: ""},
+	}
+}
+
+func invalidExpiryTC() testCase {
+	return testCase{
+		name: "ttl and expire at",
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke1}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":10,"expireAt":"2999-01-01T00:00:00Z"}`,
+		},
+		want: want{code: http.StatusUnprocessableEntity, body: `{"message":"invalid expiry"}`, retry: ""},
+	}
+}
+
+func invalidDurationTC() testCase {
+	return testCase{
+		name: "invalid duration",
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke1}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":"10"}`,
+		},
+		want: want{
+			code: http.StatusUnprocessableEntity,
+			body: `{"message":"json error: code=400, message=invalid duration: time: missing unit in duration \"10\", ` +
+				`internal=invalid duration: time: missing unit in duration \"10\""}`,
+			retry: "",
+		},
 	}
 }
 
@@ -80,7 +211,7 @@ func connectionTimeoutTC() testCase {
 			params:  &params{names: []string{"key"}, values: []string{Smoke2}},
 			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
 		},
-		want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`},
+		want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`, retry: ""},
 	}
 }
 
@@ -91,7 +222,40 @@ func contextTimeoutTC() testCase {
 			params:  &params{names: []string{"key"}, values: []string{Smoke3}},
 			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
 		},
-		want: want{code: http.StatusTooManyRequests, body: `{"message":"context timeout"}`},
+		want: want{code: http.StatusTooManyRequests, body: `{"message":"context timeout"}`, retry: ""},
+	}
+}
+
+func poolExhaustedTC() testCase {
+	return testCase{
+		name: Smoke9,
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke9}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
+		},
+		want: want{code: http.StatusTooManyRequests, body: `{"message":"write pool exhausted: context timeout"}`, retry: ""},
+	}
+}
+
+func shedTC() testCase {
+	return testCase{
+		name: Smoke10,
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke10}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
+		},
+		want: want{code: http.StatusServiceUnavailable, body: `{"message":"request shed"}`, retry: ""},
+	}
+}
+
+func circuitOpenTC() testCase {
+	return testCase{
+		name: Smoke11,
+		args: args{
+			params:  &params{names: []string{"key"}, values: []string{Smoke11}},
+			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
+		},
+		want: want{code: http.StatusServiceUnavailable, body: `{"message":"circuit open"}`, retry: "2"},
 	}
 }
 
@@ -102,7 +266,7 @@ func failureTC() testCase {
 			params:  &params{names: []string{"key"}, values: []string{Smoke4}},
 			payload: `{"val":{"age":42,"hello":"world"},"ttl":10}`,
 		},
-		want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`},
+		want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`, retry: ""},
 	}
 }
 
@@ -117,6 +281,7 @@ func invalidParamsTC() testCase {
 			code: http.StatusUnprocessableEntity,
 			body: "{\"message\":\"validate error: Key: 'Params.Key' Error:" +
 				"Field validation for 'Key' failed on the 'required' tag\"}",
+			retry: "",
 		},
 	}
 }
@@ -128,7 +293,7 @@ func nonRequiredPayloadTC() testCase {
 			params:  &params{names: []string{"key"}, values: []string{Smoke6}},
 			payload: `{"val":{"age":42,"hello":"world"}}`,
 		},
-		want: want{code: http.StatusCreated, body: `{"key":"smoke6","val":{"age":42,"hello":"world"}}`},
+		want: want{code: http.StatusCreated, body: `{"key":"smoke6","val":{"age":42,"hello":"world"}}`, retry: ""},
 	}
 }
 
@@ -143,6 +308,7 @@ func ttlMinValidationTC() testCase {
 			code: http.StatusUnprocessableEntity,
 			body: "{\"message\":\"validate error: Key: 'Payload.TTL' Error:" +
 				"Field validation for 'TTL' failed on the 'min' tag\"}",
+			retry: "",
 		},
 	}
 }
@@ -158,6 +324,7 @@ func requiredPayloadTC() testCase {
 			code: http.StatusUnprocessableEntity,
 			body: "{\"message\":\"validate error: Key: 'Payload.Val' Error:" +
 				"Field validation for 'Val' failed on the 'required' tag\"}",
+			retry: "",
 		},
 	}
 }
@@ -167,8 +334,18 @@ func TestUnitPost(t *testing.T) {
 
 	tests := []testCase{
 		successTC(),
+		slidingTC(),
+		durationTC(),
+		expireAtTC(),
+		pastDeadlineTC(),
+		infiniteKeyTC(),
+		invalidExpiryTC(),
+		invalidDurationTC(),
 		connectionTimeoutTC(),
 		contextTimeoutTC(),
+		poolExhaustedTC(),
+		shedTC(),
+		circuitOpenTC(),
 		failureTC(),
 		invalidParamsTC(),
 		nonRequiredPayloadTC(),
@@ -190,7 +367,140 @@ func TestUnitPost(t *testing.T) {
 			etx.SetParamNames(test.args.params.names...)
 			etx.SetParamValues(test.args.params.values...)
 
-			mux.HTTPErrorHandler(apiv1post.Post(cacheSetter{})(etx), etx)
+			mux.HTTPErrorHandler(apiv1post.Post(cacheSetter{}, clock.New(), ttlPolicy{})(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("Retry-After")), toolkit.Want(test.want.retry, nil))
+		})
+	}
+}
+
+func TestUnitPostCondition(t *testing.T) {
+	t.Parallel()
+
+	created := `{"key":"smoke14","val":{"age":42}}`
+	tests := []struct {
+		name   string
+		key    string
+		target string
+		want   want
+	}{
+		{
+			name:   "create existing",
+			key:    Smoke14,
+			target: "/?mode=nx",
+			want:   want{code: http.StatusConflict, body: `{"message":"key already exist"}`, retry: ""},
+		},
+		{name: "update", key: Smoke14, target: "/?mode=xx", want: want{code: http.StatusCreated, body: created, retry: ""}},
+		{name: "upsert", key: Smoke14, target: "/", want: want{code: http.StatusCreated, body: created, retry: ""}},
+		{
+			name:   "create absent",
+			key:    Smoke1,
+			target: "/?mode=nx",
+			want:   want{code: http.StatusCreated, body: `{"key":"smoke1","val":{"age":42}}`, retry: ""},
+		},
+		{
+			name:   "update absent",
+			key:    Smoke1,
+			target: "/?mode=xx",
+			want:   want{code: http.StatusPreconditionFailed, body: `{"message":"key not exist"}`, retry: ""},
+		},
+		{
+			name:   "unsupported",
+			key:    Smoke15,
+			target: "/?mode=nx",
+			want:   want{code: http.StatusUnprocessableEntity, body: `{"message":"unsupported by the driver"}`, retry: ""},
+		},
+		{
+			name:   "invalid mode",
+			key:    Smoke1,
+			target: "/?mode=any",
+			want: want{
+				code: http.StatusUnprocessableEntity,
+				body: "{\"message\":\"validate error: Key: 'WriteParams.Mode' Error:" +
+					"Field validation for 'Mode' failed on the 'oneof' tag\"}",
+				retry: "",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(`{"val":{"age":42}}`))
+			rec := httptest.NewRecorder()
+			mux := echo.New()
+
+			req.Header.Set("Content-Type", "application/json")
+
+			etx := mux.NewContext(req, rec)
+			etx.SetParamNames("key")
+			etx.SetParamValues(test.key)
+
+			mux.HTTPErrorHandler(apiv1post.Post(cacheSetter{}, clock.New(), ttlPolicy{})(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+		})
+	}
+}
+
+func TestUnitPostVersion(t *testing.T) {
+	t.Parallel()
+
+	created := want{code: http.StatusCreated, body: `{"key":"smoke14","val":{"age":42}}`, retry: ""}
+	mismatch := want{code: http.StatusPreconditionFailed, body: `{"message":"version mismatch"}`, retry: ""}
+	conflict := want{code: http.StatusUnprocessableEntity, body: `{"message":"mode excludes If-Match"}`, retry: ""}
+	tests := []struct {
+		name   string
+		key    string
+		target string
+		match  string
+		want   want
+	}{
+		{name: "swap", key: Smoke14, target: "/", match: `"7"`, want: created},
+		{name: "any", key: Smoke14, target: "/", match: "*", want: created},
+		{name: "stale version", key: Smoke14, target: "/", match: `"6"`, want: mismatch},
+		{name: "weak tag", key: Smoke14, target: "/", match: `W/"7"`, want: mismatch},
+		{name: "malformed tag", key: Smoke14, target: "/", match: "7", want: mismatch},
+		{name: "absent version", key: Smoke1, target: "/", match: `"7"`, want: mismatch},
+		{
+			name:   "absent any",
+			key:    Smoke1,
+			target: "/",
+			match:  "*",
+			want:   want{code: http.StatusPreconditionFailed, body: `{"message":"key not exist"}`, retry: ""},
+		},
+		{
+			name:   "unsupported",
+			key:    Smoke15,
+			target: "/",
+			match:  `"7"`,
+			want:   want{code: http.StatusUnprocessableEntity, body: `{"message":"unsupported by the driver"}`, retry: ""},
+		},
+		{name: "create any", key: Smoke14, target: "/?mode=nx", match: "*", want: conflict},
+		{name: "create version", key: Smoke1, target: "/?mode=nx", match: `"7"`, want: conflict},
+		{name: "update any", key: Smoke14, target: "/?mode=xx", match: "*", want: conflict},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodPost, test.target, strings.NewReader(`{"val":{"age":42}}`))
+			rec := httptest.NewRecorder()
+			mux := echo.New()
+
+			req.Header.Set("Content-Type", "application/json")
+			req.Header.Set("If-Match", test.match)
+
+			etx := mux.NewContext(req, rec)
+			etx.SetParamNames("key")
+			etx.SetParamValues(test.key)
+
+			mux.HTTPErrorHandler(apiv1post.Post(cacheSetter{}, clock.New(), ttlPolicy{})(etx), etx)
 
 			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
 			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
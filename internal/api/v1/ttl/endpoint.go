@@ -0,0 +1,173 @@
+package apiv1ttl
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/blender"
+)
+
+type (
+	Payload struct {
+		// TTL is a number of seconds or a duration string, e.g. "1500ms" or "2h30m".
+		TTL api.Duration `example:"1500ms" json:"ttl" swaggertype:"string" validate:"omitempty,min=0"`
+		// ExpireAt is an RFC3339 deadline in the future, it excludes TTL.
+		ExpireAt time.Time `json:"expireAt"`
+	}
+	// Response has null TTL and Deadline for the infinite key.
+	Response struct {
+		Key string `json:"key"`
+		// TTL is a number of seconds left until the Deadline.
+		TTL      *float64   `json:"ttl"`
+		Deadline *time.Time `json:"deadline"`
+	}
+)
+
+// Get ----
+// @Summary    "Retrieve the TTL of the key"
+// @Tags       ttl
+// @Param      key path string true "Key"
+// @Produce    json
+// @Success    200 {object} Response
+// @Failure    400 {object} api.BadRequest
+// @Failure    404 {object} api.NotFound
+// @Failure    422 {object} api.UnprocessableEntity
+// @Failure    429 {object} api.TooManyRequests
+// @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
+// @Router     /api/v1/{key}/ttl [get].
+func Get(cache domain.CacheInspector, clock domain.Clock) echo.HandlerFunc {
+	params := blender.New[api.Params]()
+	useCase := domain.NewTTLUseCase(cache, clock)
+
+	return func(etx echo.Context) error {
+		params, err := params.Path(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		life, err := useCase.Execute(etx.Request().Context(), params.Key)
+		if err != nil {
+			return failure(etx, err)
+		}
+
+		resp := &Response{Key: params.Key, TTL: nil, Deadline: nil}
+
+		if !life.Deadline.IsZero() {
+			ttl := life.TTL.Seconds()
+
+			resp.TTL = &ttl
+			resp.Deadline = &life.Deadline
+		}
+
+		return etx.JSON(http.StatusOK, resp)
+	}
+}
+
+// Patch ----
+// @Summary    "Extend or shorten the TTL of the key, the value stays untouched"
+// @Tags       ttl
+// @Param      key path string true "Key"
+// @Accept     json
+// @Param      payload body Payload true "Payload"
+// @Success    204
+// @Failure    400 {object} api.BadRequest
+// @Failure    404 {object} api.NotFound
+// @Failure    422 {object} api.UnprocessableEntity
+// @Failure    429 {object} api.TooManyRequests
+// @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
+// @Router     /api/v1/{key}/ttl [patch].
+func Patch(cache domain.CacheExpirer, clock domain.Clock, policy domain.TTLPolicy) echo.HandlerFunc {
+	params := blender.New[api.Params]()
+	payload := blender.New[Payload]()
+	useCase := domain.NewExpireUseCase(cache, clock, policy)
+
+	return func(etx echo.Context) error {
+		params, err := params.Path(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		payload, err := payload.JSON(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		expiry := domain.Expiry{TTL: time.Duration(payload.TTL), ExpireAt: payload.ExpireAt, Sliding: false}
+
+		err = useCase.Execute(etx.Request().Context(), params.Key, expiry)
+		if err != nil {
+			return failure(etx, err)
+		}
+
+		return etx.NoContent(http.StatusNoContent)
+	}
+}
+
+// Delete ----
+// @Summary    "Make the key infinite"
+// @Tags       ttl
+// @Param      key path string true "Key"
+// @Success    204
+// @Failure    400 {object} api.BadRequest
+// @Failure    404 {object} api.NotFound
+// @Failure    422 {object} api.UnprocessableEntity
+// @Failure    429 {object} api.TooManyRequests
+// @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
+// @Router     /api/v1/{key}/ttl [delete].
+func Delete(cache domain.CacheExpirer, clock domain.Clock, policy domain.TTLPolicy) echo.HandlerFunc {
+	params := blender.New[api.Params]()
+	useCase := domain.NewPersistUseCase(cache, clock, policy)
+
+	return func(etx echo.Context) error {
+		params, err := params.Path(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		err = useCase.Execute(etx.Request().Context(), params.Key)
+		if err != nil {
+			return failure(etx, err)
+		}
+
+		return etx.NoContent(http.StatusNoContent)
+	}
+}
+
+// failure is the same for all the endpoints, the key must exist to have the TTL.
+func failure(etx echo.Context, err error) error {
+	switch {
+	case errors.Is(err, domain.ErrInvalidKey):
+		return api.UnprocessableEntityError(err)
+	case errors.Is(err, domain.ErrKeyExpired):
+		return api.BadRequestError(err)
+	case errors.Is(err, domain.ErrKeyNotExist):
+		return api.NotFoundError(err)
+	case errors.Is(err, domain.ErrInvalidExpiry):
+		return api.UnprocessableEntityError(err)
+	case errors.Is(err, domain.ErrPastDeadline):
+		return api.UnprocessableEntityError(err)
+	case errors.Is(err, domain.ErrInfiniteKey):
+		return api.UnprocessableEntityError(err)
+	case errors.Is(err, domain.ErrConnTimeout):
+		return api.TooManyRequestsError(err)
+	case errors.Is(err, domain.ErrContextTimeout):
+		return api.TooManyRequestsError(err)
+	case errors.Is(err, domain.ErrShed):
+		return api.ServiceUnavailableError(err)
+	case errors.Is(err, domain.ErrCircuitOpen):
+		api.RetryAfter(etx, err)
+
+		return api.ServiceUnavailableError(err)
+	}
+
+	etx.Logger().Error(err)
+
+	return api.InternalServerError(err)
+}
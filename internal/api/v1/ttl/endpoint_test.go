@@ -0,0 +1,246 @@
+package apiv1ttl_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apiv1ttl "github.com/therenotomorrow/apicache/internal/api/v1/ttl"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/mocks"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const (
+	Smoke1 = "smoke1"
+	Smoke2 = "smoke2"
+	Smoke3 = "smoke3"
+	Smoke4 = "smoke4"
+	Smoke5 = "smoke5"
+	Smoke6 = "smoke6"
+	Smoke7 = "smoke7"
+	Smoke8 = "smoke8"
+)
+
+var (
+	errDummy = errors.New("dummy error")
+	now      = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type (
+	cacheExpirer struct{}
+	ttlPolicy    struct{}
+	params       struct {
+		names  []string
+		values []string
+	}
+	args struct {
+		params  *params
+		payload string
+	}
+	want struct {
+		code  int
+		body  string
+		retry string
+	}
+	testCase struct {
+		name string
+		args args
+		want want
+	}
+)
+
+func fail(key string) error {
+	switch key {
+	case Smoke3:
+		return domain.ErrKeyExpired
+	case Smoke4:
+		return domain.ErrKeyNotExist
+	case Smoke5:
+		return domain.ErrConnTimeout
+	case Smoke6:
+		return &domain.RetryError{Err: domain.ErrCircuitOpen, After: 1500 * time.Millisecond}
+	case Smoke7:
+		return errDummy
+	}
+
+	return nil
+}
+
+func (c cacheExpirer) Deadline(_ context.Context, key string) (time.Time, error) {
+	switch key {
+	case Smoke1:
+		return now.Add(90 * time.Second), nil
+	case Smoke2:
+		return time.Time{}, nil
+	}
+
+	return time.Time{}, fail(key)
+}
+
+func (c cacheExpirer) Expire(_ context.Context, key string, deadline time.Time) error {
+	if key == Smoke1 && !deadline.Equal(now.Add(1500*time.Millisecond)) {
+		return errDummy
+	}
+
+	return fail(key)
+}
+
+func (c cacheExpirer) Persist(_ context.Context, key string) error {
+	return fail(key)
+}
+
+func (p ttlPolicy) Deadline(key string, deadline time.Time, _ time.Time) (time.Time, error) {
+	if key == Smoke8 && deadline.IsZero() {
+		return time.Time{}, domain.ErrInfiniteKey
+	}
+
+	return deadline, nil
+}
+
+func key(name string) *params {
+	return &params{names: []string{"key"}, values: []string{name}}
+}
+
+// failures are the same for all the endpoints.
+func failures(payload string) []testCase {
+	return []testCase{
+		{
+			name: "expired",
+			args: args{params: key(Smoke3), payload: payload},
+			want: want{code: http.StatusBadRequest, body: `{"message":"key is expired"}`, retry: ""},
+		},
+		{
+			name: "not exist",
+			args: args{params: key(Smoke4), payload: payload},
+			want: want{code: http.StatusNotFound, body: `{"message":"key not exist"}`, retry: ""},
+		},
+		{
+			name: "connection timeout",
+			args: args{params: key(Smoke5), payload: payload},
+			want: want{code: http.StatusTooManyRequests, body: `{"message":"connection timeout"}`, retry: ""},
+		},
+		{
+			name: "circuit open",
+			args: args{params: key(Smoke6), payload: payload},
+			want: want{code: http.StatusServiceUnavailable, body: `{"message":"circuit open"}`, retry: "2"},
+		},
+		{
+			name: "failure",
+			args: args{params: key(Smoke7), payload: payload},
+			want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`, retry: ""},
+		},
+		{
+			name: "invalid params",
+			args: args{params: &params{names: []string{"key"}, values: nil}, payload: payload},
+			want: want{
+				code: http.StatusUnprocessableEntity,
+				body: "{\"message\":\"validate error: Key: 'Params.Key' Error:" +
+					"Field validation for 'Key' failed on the 'required' tag\"}",
+				retry: "",
+			},
+		},
+	}
+}
+
+func run(t *testing.T, method string, handler echo.HandlerFunc, tests []testCase) {
+	t.Helper()
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(method, "/", strings.NewReader(test.args.payload))
+			rec := httptest.NewRecorder()
+			mux := echo.New()
+
+			req.Header.Set("Content-Type", "application/json")
+
+			etx := mux.NewContext(req, rec)
+			etx.SetParamNames(test.args.params.names...)
+			etx.SetParamValues(test.args.params.values...)
+
+			mux.HTTPErrorHandler(handler(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+			toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("Retry-After")), toolkit.Want(test.want.retry, nil))
+		})
+	}
+}
+
+func TestUnitGet(t *testing.T) {
+	t.Parallel()
+
+	tests := append([]testCase{
+		{
+			name: "finite",
+			args: args{params: key(Smoke1), payload: ""},
+			want: want{
+				code:  http.StatusOK,
+				body:  `{"key":"smoke1","ttl":90,"deadline":"2024-01-01T00:01:30Z"}`,
+				retry: "",
+			},
+		},
+		{
+			name: "infinite",
+			args: args{params: key(Smoke2), payload: ""},
+			want: want{code: http.StatusOK, body: `{"key":"smoke2","ttl":null,"deadline":null}`, retry: ""},
+		},
+	}, failures("")...)
+
+	run(t, http.MethodGet, apiv1ttl.Get(cacheExpirer{}, mocks.NewClockMock(now)), tests)
+}
+
+func TestUnitPatch(t *testing.T) {
+	t.Parallel()
+
+	tests := append([]testCase{
+		{
+			name: "duration",
+			args: args{params: key(Smoke1), payload: `{"ttl":"1500ms"}`},
+			want: want{code: http.StatusNoContent, body: "", retry: ""},
+		},
+		{
+			name: "expire at",
+			args: args{params: key(Smoke1), payload: `{"expireAt":"2024-01-01T00:00:01.5Z"}`},
+			want: want{code: http.StatusNoContent, body: "", retry: ""},
+		},
+		{
+			name: "no expiry",
+			args: args{params: key(Smoke1), payload: `{}`},
+			want: want{code: http.StatusUnprocessableEntity, body: `{"message":"invalid expiry"}`, retry: ""},
+		},
+		{
+			name: "past deadline",
+			args: args{params: key(Smoke1), payload: `{"expireAt":"2000-01-01T00:00:00Z"}`},
+			want: want{code: http.StatusUnprocessableEntity, body: `{"message":"deadline in the past"}`, retry: ""},
+		},
+	}, failures(`{"ttl":60}`)...)
+
+	run(t, http.MethodPatch, apiv1ttl.Patch(cacheExpirer{}, mocks.NewClockMock(now), ttlPolicy{}), tests)
+}
+
+func TestUnitDelete(t *testing.T) {
+	t.Parallel()
+
+	tests := append([]testCase{
+		{
+			name: "persist",
+			args: args{params: key(Smoke1), payload: ""},
+			want: want{code: http.StatusNoContent, body: "", retry: ""},
+		},
+		{
+			name: "infinite key",
+			args: args{params: key(Smoke8), payload: ""},
+			want: want{code: http.StatusUnprocessableEntity, body: `{"message":"infinite key forbidden"}`, retry: ""},
+		},
+	}, failures("")...)
+
+	run(t, http.MethodDelete, apiv1ttl.Delete(cacheExpirer{}, mocks.NewClockMock(now), ttlPolicy{}), tests)
+}
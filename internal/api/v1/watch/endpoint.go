@@ -0,0 +1,107 @@
+package apiv1watch
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/blender"
+)
+
+// Event is the data of the server-sent event, its id is the offset and its name is set, delete or expire.
+// The reset event without data ends the stream of the client that missed the changes, so it starts over.
+type Event struct {
+	Key  string    `json:"key"`
+	Time time.Time `json:"time"`
+}
+
+// Watch ----
+// @Summary    "Stream the changes of the keys as server-sent events"
+// @Tags       cache
+// @Param      prefix        query  string false "Prefix of the keys"
+// @Param      Last-Event-ID header string false "Resume after this event"
+// @Produce    text/event-stream
+// @Success    200 {object} Event
+// @Failure    410 {object} api.Gone
+// @Failure    422 {object} api.UnprocessableEntity
+// @Failure    500 {object} api.InternalServer
+// @Failure    503 {object} api.ServiceUnavailable
+// @Router     /api/v1/watch [get].
+func Watch(feed domain.ChangeWatcher) echo.HandlerFunc {
+	params := blender.New[api.WatchParams]()
+	useCase := domain.NewWatchUseCase(feed)
+
+	return func(etx echo.Context) error {
+		params, err := params.Query(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		changes, err := useCase.Execute(params.Prefix, etx.Request().Header.Get("Last-Event-ID"))
+		if err == nil {
+			defer func() { _ = changes.Close() }()
+
+			return stream(etx, changes)
+		}
+
+		switch {
+		case errors.Is(err, domain.ErrInvalidOffset):
+			return api.UnprocessableEntityError(err)
+		case errors.Is(err, domain.ErrOffsetGone):
+			return api.GoneError(err)
+		case errors.Is(err, domain.ErrClosed):
+			return api.ServiceUnavailableError(err)
+		}
+
+		etx.Logger().Error(err)
+
+		return api.InternalServerError(err)
+	}
+}
+
+func stream(etx echo.Context, changes domain.Changes) error {
+	res := etx.Response()
+
+	res.Header().Set(echo.HeaderContentType, "text/event-stream")
+	res.Header().Set(echo.HeaderCacheControl, "no-cache")
+	res.Header().Set(echo.HeaderConnection, "keep-alive")
+	res.Header().Set("X-Accel-Buffering", "no")
+	res.WriteHeader(http.StatusOK)
+	res.Flush()
+
+	for {
+		batch, err := changes.Next(etx.Request().Context())
+		if errors.Is(err, domain.ErrOffsetGone) {
+			// the changes are lost for the client, so there is nothing to resume from
+			_, _ = fmt.Fprint(res, "event: reset\ndata: {}\n\n")
+			res.Flush()
+
+			return nil
+		}
+
+		if err != nil {
+			// the client is gone or the feed is closed:
+			// the stream is over and the client resumes with Last-Event-ID
+			return nil
+		}
+
+		for _, change := range batch {
+			data, err := json.Marshal(&Event{Key: change.Key, Time: change.Time})
+			if err != nil {
+				return fmt.Errorf("json error: %w", err)
+			}
+
+			_, err = fmt.Fprintf(res, "id: %d\nevent: %s\ndata: %s\n\n", change.ID, change.Kind, data)
+			if err != nil {
+				return nil //nolint:nilerr // the client is gone
+			}
+		}
+
+		res.Flush()
+	}
+}
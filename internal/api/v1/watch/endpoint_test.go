@@ -0,0 +1,219 @@
+package apiv1watch_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apiv1watch "github.com/therenotomorrow/apicache/internal/api/v1/watch"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const (
+	Smoke1 = "smoke1"
+	Smoke2 = "smoke2"
+	Smoke3 = "smoke3"
+	Smoke4 = "smoke4"
+	Smoke5 = "smoke5"
+	Smoke6 = "smoke6"
+	Smoke7 = "smoke7"
+)
+
+var (
+	errDummy = errors.New("dummy error")
+	now      = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type (
+	changeWatcher struct{}
+	changes       struct {
+		batches [][]domain.Change
+		// end is the error after the batches.
+		end error
+	}
+	args struct {
+		query string
+		last  string
+	}
+	want struct {
+		code        int
+		body        string
+		contentType string
+	}
+	testCase struct {
+		name string
+		args args
+		want want
+	}
+)
+
+func (w changeWatcher) Watch(prefix string, after uint64) (domain.Changes, error) {
+	switch prefix {
+	case Smoke4:
+		return nil, domain.ErrOffsetGone
+	case Smoke5:
+		return nil, domain.ErrClosed
+	case Smoke6:
+		return nil, errDummy
+	}
+
+	batch := []domain.Change{
+		{ID: after + 1, Kind: "set", Key: prefix + ":1", Time: now},
+		{ID: after + 2, Kind: "delete", Key: prefix + ":2", Time: now},
+	}
+
+	if prefix == Smoke7 {
+		return &changes{batches: [][]domain.Change{batch}, end: domain.ErrOffsetGone}, nil
+	}
+
+	return &changes{
+		batches: [][]domain.Change{batch, {{ID: after + 3, Kind: "expire", Key: prefix, Time: now}}},
+		end:     domain.ErrClosed,
+	}, nil
+}
+
+func (c *changes) Next(_ context.Context) ([]domain.Change, error) {
+	if len(c.batches) == 0 {
+		return nil, c.end
+	}
+
+	batch := c.batches[0]
+	c.batches = c.batches[1:]
+
+	return batch, nil
+}
+
+func (c *changes) Close() error {
+	return nil
+}
+
+func successTC() testCase {
+	return testCase{
+		name: Smoke1,
+		args: args{query: "prefix=" + Smoke1, last: ""},
+		want: want{
+			code: http.StatusOK,
+			body: "id: 1\nevent: set\ndata: {\"key\":\"smoke1:1\",\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"id: 2\nevent: delete\ndata: {\"key\":\"smoke1:2\",\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"id: 3\nevent: expire\ndata: {\"key\":\"smoke1\",\"time\":\"2024-01-01T00:00:00Z\"}",
+			contentType: "text/event-stream",
+		},
+	}
+}
+
+func resumeTC() testCase {
+	return testCase{
+		name: Smoke2,
+		args: args{query: "prefix=" + Smoke2, last: "41"},
+		want: want{
+			code: http.StatusOK,
+			body: "id: 42\nevent: set\ndata: {\"key\":\"smoke2:1\",\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"id: 43\nevent: delete\ndata: {\"key\":\"smoke2:2\",\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"id: 44\nevent: expire\ndata: {\"key\":\"smoke2\",\"time\":\"2024-01-01T00:00:00Z\"}",
+			contentType: "text/event-stream",
+		},
+	}
+}
+
+func invalidOffsetTC() testCase {
+	return testCase{
+		name: Smoke3,
+		args: args{query: "prefix=" + Smoke3, last: "invalid"},
+		want: want{
+			code:        http.StatusUnprocessableEntity,
+			body:        `{"message":"invalid offset"}`,
+			contentType: echo.MIMEApplicationJSON,
+		},
+	}
+}
+
+func offsetGoneTC() testCase {
+	return testCase{
+		name: Smoke4,
+		args: args{query: "prefix=" + Smoke4, last: "1"},
+		want: want{code: http.StatusGone, body: `{"message":"offset gone"}`, contentType: echo.MIMEApplicationJSON},
+	}
+}
+
+func closedTC() testCase {
+	return testCase{
+		name: Smoke5,
+		args: args{query: "prefix=" + Smoke5, last: ""},
+		want: want{
+			code:        http.StatusServiceUnavailable,
+			body:        `{"message":"closed instance"}`,
+			contentType: echo.MIMEApplicationJSON,
+		},
+	}
+}
+
+func resetTC() testCase {
+	return testCase{
+		name: Smoke7,
+		args: args{query: "prefix=" + Smoke7, last: ""},
+		want: want{
+			code: http.StatusOK,
+			body: "id: 1\nevent: set\ndata: {\"key\":\"smoke7:1\",\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"id: 2\nevent: delete\ndata: {\"key\":\"smoke7:2\",\"time\":\"2024-01-01T00:00:00Z\"}\n\n" +
+				"event: reset\ndata: {}",
+			contentType: "text/event-stream",
+		},
+	}
+}
+
+func failureTC() testCase {
+	return testCase{
+		name: Smoke6,
+		args: args{query: "prefix=" + Smoke6, last: ""},
+		want: want{
+			code:        http.StatusInternalServerError,
+			body:        `{"message":"InternalServerError"}`,
+			contentType: echo.MIMEApplicationJSON,
+		},
+	}
+}
+
+func TestUnitWatch(t *testing.T) {
+	t.Parallel()
+
+	tests := []testCase{
+		successTC(),
+		resumeTC(),
+		invalidOffsetTC(),
+		offsetGoneTC(),
+		closedTC(),
+		resetTC(),
+		failureTC(),
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			req := httptest.NewRequest(http.MethodGet, "/?"+test.args.query, nil)
+			rec := httptest.NewRecorder()
+			mux := echo.New()
+
+			if test.args.last != "" {
+				req.Header.Set("Last-Event-ID", test.args.last)
+			}
+
+			etx := mux.NewContext(req, rec)
+
+			mux.HTTPErrorHandler(apiv1watch.Watch(changeWatcher{})(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+			toolkit.Assert(t,
+				toolkit.Got(nil, rec.Header().Get(echo.HeaderContentType)),
+				toolkit.Want(test.want.contentType, nil),
+			)
+		})
+	}
+}
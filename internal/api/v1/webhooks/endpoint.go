@@ -0,0 +1,168 @@
+package apiv1webhooks
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	"github.com/therenotomorrow/apicache/internal/api"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/blender"
+)
+
+type (
+	Payload struct {
+		URL    string   `json:"url"    validate:"required"`
+		Prefix string   `json:"prefix"`
+		Events []string `enums:"delete,expire" json:"events" validate:"required"`
+		// Secret signs the payloads, it is generated when missed.
+		Secret string `json:"secret"`
+	}
+	// Subscription is shown with the secret only once, when it is created.
+	Subscription struct {
+		ID     string   `json:"id"`
+		URL    string   `json:"url"`
+		Prefix string   `json:"prefix"`
+		Events []string `json:"events"`
+		Secret string   `json:"secret,omitempty"`
+	}
+	DeadLetter struct {
+		ID           string    `json:"id"`
+		Subscription string    `json:"subscription"`
+		URL          string    `json:"url"`
+		Event        string    `json:"event"`
+		Key          string    `json:"key"`
+		Time         time.Time `json:"time"`
+		Attempts     int       `json:"attempts"`
+		Error        string    `json:"error"`
+	}
+)
+
+// Create ----
+// @Summary    "Subscribe the webhook to the events of the keys"
+// @Tags       admin
+// @Security   Bearer
+// @Accept     json
+// @Param      payload body Payload true "Payload"
+// @Produce    json
+// @Success    201 {object} Subscription
+// @Failure    401 {object} api.Unauthorized
+// @Failure    422 {object} api.UnprocessableEntity
+// @Router     /api/v1/admin/webhooks [post].
+func Create(hooks domain.Subscriber) echo.HandlerFunc {
+	payload := blender.New[Payload]()
+	useCase := domain.NewSubscribeUseCase(hooks)
+
+	return func(etx echo.Context) error {
+		payload, err := payload.JSON(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		sub, err := useCase.Execute(domain.Subscription{
+			ID:     "",
+			URL:    payload.URL,
+			Prefix: payload.Prefix,
+			Events: payload.Events,
+			Secret: payload.Secret,
+		})
+		if err == nil {
+			return etx.JSON(http.StatusCreated, subscription(sub))
+		}
+
+		switch {
+		case errors.Is(err, domain.ErrInvalidURL), errors.Is(err, domain.ErrInvalidEvent):
+			return api.UnprocessableEntityError(err)
+		}
+
+		etx.Logger().Error(err)
+
+		return api.InternalServerError(err)
+	}
+}
+
+// List ----
+// @Summary    "List the webhooks"
+// @Tags       admin
+// @Security   Bearer
+// @Produce    json
+// @Success    200 {array}  Subscription
+// @Failure    401 {object} api.Unauthorized
+// @Router     /api/v1/admin/webhooks [get].
+func List(hooks domain.SubscriptionLister) echo.HandlerFunc {
+	useCase := domain.NewSubscriptionsUseCase(hooks)
+
+	return func(etx echo.Context) error {
+		subs := useCase.Execute()
+
+		resp := make([]Subscription, 0, len(subs))
+		for _, sub := range subs {
+			resp = append(resp, subscription(sub))
+		}
+
+		return etx.JSON(http.StatusOK, resp)
+	}
+}
+
+// Delete ----
+// @Summary    "Unsubscribe the webhook"
+// @Tags       admin
+// @Security   Bearer
+// @Param      id path string true "ID"
+// @Success    204
+// @Failure    401 {object} api.Unauthorized
+// @Failure    404 {object} api.NotFound
+// @Failure    422 {object} api.UnprocessableEntity
+// @Router     /api/v1/admin/webhooks/{id} [delete].
+func Delete(hooks domain.Unsubscriber) echo.HandlerFunc {
+	params := blender.New[api.WebhookParams]()
+	useCase := domain.NewUnsubscribeUseCase(hooks)
+
+	return func(etx echo.Context) error {
+		params, err := params.Path(etx)
+		if err != nil {
+			return api.UnprocessableEntityError(err)
+		}
+
+		err = useCase.Execute(params.ID)
+		if err == nil {
+			return etx.NoContent(http.StatusNoContent)
+		}
+
+		if errors.Is(err, domain.ErrNotSubscribed) {
+			return api.NotFoundError(err)
+		}
+
+		etx.Logger().Error(err)
+
+		return api.InternalServerError(err)
+	}
+}
+
+// DeadLetters ----
+// @Summary    "List the webhook deliveries that failed all the attempts"
+// @Tags       admin
+// @Security   Bearer
+// @Produce    json
+// @Success    200 {array}  DeadLetter
+// @Failure    401 {object} api.Unauthorized
+// @Router     /api/v1/admin/webhooks/dead [get].
+func DeadLetters(hooks domain.DeadLetterLister) echo.HandlerFunc {
+	useCase := domain.NewDeadLettersUseCase(hooks)
+
+	return func(etx echo.Context) error {
+		letters := useCase.Execute()
+
+		resp := make([]DeadLetter, 0, len(letters))
+		for _, letter := range letters {
+			resp = append(resp, DeadLetter(letter))
+		}
+
+		return etx.JSON(http.StatusOK, resp)
+	}
+}
+
+func subscription(sub domain.Subscription) Subscription {
+	return Subscription{ID: sub.ID, URL: sub.URL, Prefix: sub.Prefix, Events: sub.Events, Secret: sub.Secret}
+}
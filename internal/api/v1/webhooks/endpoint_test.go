@@ -0,0 +1,233 @@
+package apiv1webhooks_test
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/labstack/echo/v4"
+	apiv1webhooks "github.com/therenotomorrow/apicache/internal/api/v1/webhooks"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const (
+	Smoke1 = "smoke1"
+	Smoke2 = "smoke2"
+	Smoke3 = "smoke3"
+	Smoke4 = "smoke4"
+	Smoke5 = "smoke5"
+	Smoke6 = "smoke6"
+)
+
+var (
+	errDummy = errors.New("dummy error")
+	now      = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+type (
+	hooks  struct{}
+	params struct {
+		names  []string
+		values []string
+	}
+	args struct {
+		params  *params
+		payload string
+	}
+	want struct {
+		code int
+		body string
+	}
+	testCase struct {
+		name string
+		args args
+		want want
+	}
+)
+
+func (h hooks) Subscribe(sub domain.Subscription) (domain.Subscription, error) {
+	if sub.Prefix == Smoke4 {
+		return domain.Subscription{}, errDummy
+	}
+
+	sub.ID = "id"
+	sub.Secret = "secret"
+
+	return sub, nil
+}
+
+func (h hooks) Unsubscribe(id string) error {
+	switch id {
+	case Smoke2:
+		return domain.ErrNotSubscribed
+	case Smoke3:
+		return errDummy
+	}
+
+	return nil
+}
+
+func (h hooks) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{
+		{ID: "id", URL: "http://test.loc", Prefix: "session:", Events: []string{"expire"}, Secret: "secret"},
+	}
+}
+
+func (h hooks) DeadLetters() []domain.DeadLetter {
+	return []domain.DeadLetter{{
+		ID:           "id",
+		Subscription: "sub",
+		URL:          "http://test.loc",
+		Event:        domain.EventExpire,
+		Key:          "session:1",
+		Time:         now,
+		Attempts:     4,
+		Error:        "unexpected status 503",
+	}}
+}
+
+func request(method string, test testCase) (*echo.Echo, echo.Context, *httptest.ResponseRecorder) {
+	req := httptest.NewRequest(method, "/", strings.NewReader(test.args.payload))
+	rec := httptest.NewRecorder()
+	mux := echo.New()
+
+	req.Header.Set("Content-Type", "application/json")
+
+	etx := mux.NewContext(req, rec)
+
+	if test.args.params != nil {
+		etx.SetParamNames(test.args.params.names...)
+		etx.SetParamValues(test.args.params.values...)
+	}
+
+	return mux, etx, rec
+}
+
+func TestUnitCreate(t *testing.T) {
+	t.Parallel()
+
+	tests := []testCase{
+		{
+			name: Smoke1,
+			args: args{params: nil, payload: `{"url":"http://test.loc","prefix":"smoke1","events":["expire"]}`},
+			want: want{
+				code: http.StatusCreated,
+				body: `{"id":"id","url":"http://test.loc","prefix":"smoke1","events":["expire"],"secret":"secret"}`,
+			},
+		},
+		{
+			name: Smoke2,
+			args: args{params: nil, payload: `{"url":"test.loc","events":["expire"]}`},
+			want: want{code: http.StatusUnprocessableEntity, body: `{"message":"invalid url"}`},
+		},
+		{
+			name: Smoke3,
+			args: args{params: nil, payload: `{"url":"http://test.loc","events":["set"]}`},
+			want: want{code: http.StatusUnprocessableEntity, body: `{"message":"invalid event"}`},
+		},
+		{
+			name: Smoke4,
+			args: args{params: nil, payload: `{"url":"http://test.loc","prefix":"smoke4","events":["delete"]}`},
+			want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`},
+		},
+		{
+			name: Smoke5,
+			args: args{params: nil, payload: `{"events":["delete"]}`},
+			want: want{
+				code: http.StatusUnprocessableEntity,
+				body: "{\"message\":\"validate error: Key: 'Payload.URL' Error:" +
+					"Field validation for 'URL' failed on the 'required' tag\"}",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			mux, etx, rec := request(http.MethodPost, test)
+
+			mux.HTTPErrorHandler(apiv1webhooks.Create(hooks{})(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+		})
+	}
+}
+
+func TestUnitList(t *testing.T) {
+	t.Parallel()
+
+	mux, etx, rec := request(http.MethodGet, testCase{name: Smoke1, args: args{params: nil, payload: ""}, want: want{}})
+
+	mux.HTTPErrorHandler(apiv1webhooks.List(hooks{})(etx), etx)
+
+	toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(http.StatusOK, nil))
+	toolkit.Assert(t,
+		toolkit.Got(nil, strings.TrimSpace(rec.Body.String())),
+		toolkit.Want(`[{"id":"id","url":"http://test.loc","prefix":"session:","events":["expire"]}]`, nil),
+	)
+}
+
+func TestUnitDelete(t *testing.T) {
+	t.Parallel()
+
+	tests := []testCase{
+		{
+			name: Smoke1,
+			args: args{params: &params{names: []string{"id"}, values: []string{Smoke1}}, payload: ""},
+			want: want{code: http.StatusNoContent, body: ""},
+		},
+		{
+			name: Smoke2,
+			args: args{params: &params{names: []string{"id"}, values: []string{Smoke2}}, payload: ""},
+			want: want{code: http.StatusNotFound, body: `{"message":"subscription not exist"}`},
+		},
+		{
+			name: Smoke3,
+			args: args{params: &params{names: []string{"id"}, values: []string{Smoke3}}, payload: ""},
+			want: want{code: http.StatusInternalServerError, body: `{"message":"InternalServerError"}`},
+		},
+		{
+			name: Smoke6,
+			args: args{params: &params{names: []string{"id"}, values: nil}, payload: ""},
+			want: want{
+				code: http.StatusUnprocessableEntity,
+				body: "{\"message\":\"validate error: Key: 'WebhookParams.ID' Error:" +
+					"Field validation for 'ID' failed on the 'required' tag\"}",
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			mux, etx, rec := request(http.MethodDelete, test)
+
+			mux.HTTPErrorHandler(apiv1webhooks.Delete(hooks{})(etx), etx)
+
+			toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(test.want.code, nil))
+			toolkit.Assert(t, toolkit.Got(nil, strings.TrimSpace(rec.Body.String())), toolkit.Want(test.want.body, nil))
+		})
+	}
+}
+
+func TestUnitDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	mux, etx, rec := request(http.MethodGet, testCase{name: Smoke1, args: args{params: nil, payload: ""}, want: want{}})
+
+	mux.HTTPErrorHandler(apiv1webhooks.DeadLetters(hooks{})(etx), etx)
+
+	toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(http.StatusOK, nil))
+	toolkit.Assert(t,
+		toolkit.Got(nil, strings.TrimSpace(rec.Body.String())),
+		toolkit.Want(`[{"id":"id","subscription":"sub","url":"http://test.loc","event":"expire","key":"session:1",`+
+			`"time":"2024-01-01T00:00:00Z","attempts":4,"error":"unexpected status 503"}]`, nil),
+	)
+}
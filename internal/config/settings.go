@@ -2,8 +2,10 @@ package config
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"os"
 	"time"
 
 	"github.com/joho/godotenv"
@@ -18,20 +20,150 @@ const (
 	DriverRedis     Driver = "redis"
 )
 
-var ErrInvalidDriver = errors.New("invalid driver")
+type Eviction string
+
+const (
+	EvictionLRU     Eviction = "lru"
+	EvictionLFU     Eviction = "lfu"
+	EvictionTinyLFU Eviction = "tinylfu"
+)
+
+type Overflow string
+
+const (
+	OverflowDropNewest Overflow = "drop-newest"
+	OverflowDropOldest Overflow = "drop-oldest"
+	OverflowBlock      Overflow = "block"
+)
+
+var (
+	ErrInvalidDriver     = errors.New("invalid driver")
+	ErrInvalidEviction   = errors.New("invalid eviction")
+	ErrInvalidOverflow   = errors.New("invalid overflow")
+	ErrInvalidWebhooks   = errors.New("invalid webhooks")
+	ErrInvalidNamespaces = errors.New("invalid namespaces")
+	ErrInvalidTTLPolicy  = errors.New("invalid ttl policy")
+)
+
+// Webhook is the subscription known in advance, the file of them is the JSON array.
+type Webhook struct {
+	ID     string   `json:"id"`
+	URL    string   `json:"url"`
+	Prefix string   `json:"prefix"`
+	Events []string `json:"events"`
+	Secret string   `json:"secret"`
+}
+
+// Duration is written as "1m30s" in the files.
+type Duration time.Duration
+
+func (d Duration) MarshalText() ([]byte, error) {
+	return []byte(time.Duration(d).String()), nil
+}
+
+func (d *Duration) UnmarshalText(text []byte) error {
+	duration, err := time.ParseDuration(string(text))
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	*d = Duration(duration)
+
+	return nil
+}
+
+// Namespace is the group of keys with its own settings, the file of them is the JSON array.
+type Namespace struct {
+	Name       string   `json:"name"`
+	DefaultTTL Duration `json:"defaultTTL"`
+	MaxTTL     Duration `json:"maxTTL"`
+	MaxKeys    int      `json:"maxKeys"`
+	Share      float64  `json:"share"`
+}
+
+// TTLRule shapes the deadlines of the keys matched by the pattern, the file of them is the JSON array.
+type TTLRule struct {
+	Pattern    string   `json:"pattern"`
+	DefaultTTL Duration `json:"defaultTTL"`
+	MaxTTL     Duration `json:"maxTTL"`
+	Finite     bool     `json:"finite"`
+	Jitter     float64  `json:"jitter"`
+}
 
 type Settings struct {
 	Debug  bool `env:"APICACHE_DEBUG,required" json:"debug"`
 	Server struct {
 		Address         string        `json:"address"`
 		ShutdownTimeout time.Duration `json:"shutdownTimeout"`
+		ClientHeader    string        `env:"APICACHE_SERVER_CLIENT_HEADER,default=X-API-Key" json:"clientHeader"`
+		WatchRing       int           `env:"APICACHE_SERVER_WATCH_RING"                      json:"watchRing"`
+		// AdminToken is the bearer token of the admin API, the API is disabled without it.
+		AdminToken string `env:"APICACHE_SERVER_ADMIN_TOKEN" json:"adminToken"`
 	} `json:"server"`
 	Driver struct {
-		Name        Driver        `env:"APICACHE_DRIVER_NAME,required"         json:"name"`
-		Address     string        `env:"APICACHE_DRIVER_ADDRESS,required"      json:"address"`
-		MaxConn     int           `env:"APICACHE_DRIVER_MAX_CONN,required"     json:"maxConn"`
-		ConnTimeout time.Duration `env:"APICACHE_DRIVER_CONN_TIMEOUT,required" json:"connTimeout"`
+		Name          Driver        `env:"APICACHE_DRIVER_NAME,required"         json:"name"`
+		Address       string        `env:"APICACHE_DRIVER_ADDRESS,required"      json:"address"`
+		MaxConn       int           `env:"APICACHE_DRIVER_MAX_CONN,required"     json:"maxConn"`
+		MinConn       int           `env:"APICACHE_DRIVER_MIN_CONN"              json:"minConn"`
+		TargetLatency time.Duration `env:"APICACHE_DRIVER_TARGET_LATENCY"        json:"targetLatency"`
+		ConnTimeout   time.Duration `env:"APICACHE_DRIVER_CONN_TIMEOUT,required" json:"connTimeout"`
+		WriteMaxConn  int           `env:"APICACHE_DRIVER_WRITE_MAX_CONN"        json:"writeMaxConn"`
+		WriteMinConn  int           `env:"APICACHE_DRIVER_WRITE_MIN_CONN"        json:"writeMinConn"`
+		WriteTimeout  time.Duration `env:"APICACHE_DRIVER_WRITE_TIMEOUT"         json:"writeTimeout"`
+		Borrow        bool          `env:"APICACHE_DRIVER_BORROW"                json:"borrow"`
+		ShedTarget    time.Duration `env:"APICACHE_DRIVER_SHED_TARGET"           json:"shedTarget"`
+		ShedInterval  time.Duration `env:"APICACHE_DRIVER_SHED_INTERVAL"         json:"shedInterval"`
+		BreakAfter    int           `env:"APICACHE_DRIVER_BREAK_AFTER"           json:"breakAfter"`
+		BreakInterval time.Duration `env:"APICACHE_DRIVER_BREAK_INTERVAL"        json:"breakInterval"`
+		Retries       int           `env:"APICACHE_DRIVER_RETRIES"               json:"retries"`
+		RetryBackoff  time.Duration `env:"APICACHE_DRIVER_RETRY_BACKOFF"         json:"retryBackoff"`
+		RetryCeiling  time.Duration `env:"APICACHE_DRIVER_RETRY_CEILING"         json:"retryCeiling"`
+		ExpiryWorkers int           `env:"APICACHE_DRIVER_EXPIRY_WORKERS"        json:"expiryWorkers"`
+		Stateless     bool          `env:"APICACHE_DRIVER_STATELESS"             json:"stateless"`
 	} `json:"driver"`
+	// Near is the local tier of the hot keys in front of the driver, zero Keys means no tier.
+	// Bus is the redis address of the invalidation bus, the driver address is used for redis.
+	Near struct {
+		Keys    int           `env:"APICACHE_NEAR_KEYS"                                json:"keys"`
+		TTL     time.Duration `env:"APICACHE_NEAR_TTL"                                 json:"ttl"`
+		Bus     string        `env:"APICACHE_NEAR_BUS"                                 json:"bus"`
+		Channel string        `env:"APICACHE_NEAR_CHANNEL,default=apicache:invalidate" json:"channel"`
+	} `json:"near"`
+	Cache struct {
+		MaxKeys  int      `env:"APICACHE_CACHE_MAX_KEYS"              json:"maxKeys"`
+		MaxBytes int      `env:"APICACHE_CACHE_MAX_BYTES"             json:"maxBytes"`
+		Eviction Eviction `env:"APICACHE_CACHE_EVICTION,default=lru" json:"eviction"`
+		// Weights are pairs of the client and its weight, e.g. "key1:3,key2:1".
+		Weights     map[string]int `env:"APICACHE_CACHE_CLIENT_WEIGHTS" json:"weights"`
+		ClientShare float64        `env:"APICACHE_CACHE_CLIENT_SHARE"   json:"clientShare"`
+		StaleGrace  time.Duration  `env:"APICACHE_CACHE_STALE_GRACE"    json:"staleGrace"`
+		// Events is the buffer of the lifecycle events, Overflow tells what to do when it is full.
+		Events   int      `env:"APICACHE_CACHE_EVENTS"                      json:"events"`
+		Overflow Overflow `env:"APICACHE_CACHE_OVERFLOW,default=drop-newest" json:"overflow"`
+	} `json:"cache"`
+	// Origin fills the missed keys of the cache from URL/{key}, empty URL means no loading.
+	// TTL is used when the origin doesn't tell max-age, zero means infinite keys.
+	Origin struct {
+		URL     string        `env:"APICACHE_ORIGIN_URL"     json:"url"`
+		TTL     time.Duration `env:"APICACHE_ORIGIN_TTL"     json:"ttl"`
+		Timeout time.Duration `env:"APICACHE_ORIGIN_TIMEOUT" json:"timeout"`
+	} `json:"origin"`
+	Webhooks struct {
+		File          string        `env:"APICACHE_WEBHOOKS_FILE"              json:"file"`
+		Workers       int           `env:"APICACHE_WEBHOOKS_WORKERS"           json:"workers"`
+		Retries       int           `env:"APICACHE_WEBHOOKS_RETRIES,default=3" json:"retries"`
+		Backoff       time.Duration `env:"APICACHE_WEBHOOKS_BACKOFF"           json:"backoff"`
+		Timeout       time.Duration `env:"APICACHE_WEBHOOKS_TIMEOUT"           json:"timeout"`
+		Subscriptions []Webhook     `json:"subscriptions"`
+	} `json:"webhooks"`
+	Namespaces struct {
+		File string      `env:"APICACHE_NAMESPACES_FILE" json:"file"`
+		List []Namespace `json:"list"`
+	} `json:"namespaces"`
+	TTL struct {
+		File  string    `env:"APICACHE_TTL_POLICY_FILE" json:"file"`
+		Rules []TTLRule `json:"rules"`
+	} `json:"ttl"`
 }
 
 func New(filenames ...string) (*Settings, error) {
@@ -53,6 +185,54 @@ func New(filenames ...string) (*Settings, error) {
 		return nil, ErrInvalidDriver
 	}
 
+	switch settings.Cache.Eviction {
+	case EvictionLRU, EvictionLFU, EvictionTinyLFU:
+	default:
+		return nil, ErrInvalidEviction
+	}
+
+	switch settings.Cache.Overflow {
+	case OverflowDropNewest, OverflowDropOldest, OverflowBlock:
+	default:
+		return nil, ErrInvalidOverflow
+	}
+
+	if file := settings.Webhooks.File; file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidWebhooks, err)
+		}
+
+		err = json.Unmarshal(raw, &settings.Webhooks.Subscriptions)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidWebhooks, err)
+		}
+	}
+
+	if file := settings.Namespaces.File; file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidNamespaces, err)
+		}
+
+		err = json.Unmarshal(raw, &settings.Namespaces.List)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidNamespaces, err)
+		}
+	}
+
+	if file := settings.TTL.File; file != "" {
+		raw, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTTLPolicy, err)
+		}
+
+		err = json.Unmarshal(raw, &settings.TTL.Rules)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrInvalidTTLPolicy, err)
+		}
+	}
+
 	return settings, nil
 }
 
@@ -3,8 +3,11 @@ package config_test
 import (
 	"encoding/json"
 	"errors"
+	"path"
 	"testing"
+	"time"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 	"github.com/therenotomorrow/apicache/internal/config"
 	"github.com/therenotomorrow/apicache/test/toolkit"
@@ -16,8 +19,19 @@ var errParseEnv = errors.New("parse env error: " +
 func TestUnitNew(t *testing.T) {
 	t.Parallel()
 
-	wantJSON := "{\"debug\":true,\"server\":{\"address\":\"0.0.0.0:8080\",\"shutdownTimeout\":1000000000}," +
-		"\"driver\":{\"name\":\"machine\",\"address\":\"http://test.loc\",\"maxConn\":10,\"connTimeout\":1000000000}}"
+	wantJSON := "{\"debug\":true,\"server\":{\"address\":\"0.0.0.0:8080\",\"shutdownTimeout\":1000000000," +
+		"\"clientHeader\":\"X-API-Key\",\"watchRing\":0,\"adminToken\":\"\"}," +
+		"\"driver\":{\"name\":\"machine\",\"address\":\"http://test.loc\",\"maxConn\":10,\"minConn\":0,\"targetLatency\":0," +
+		"\"connTimeout\":1000000000,\"writeMaxConn\":0,\"writeMinConn\":0,\"writeTimeout\":0,\"borrow\":false," +
+		"\"shedTarget\":0,\"shedInterval\":0,\"breakAfter\":0,\"breakInterval\":0," +
+		"\"retries\":0,\"retryBackoff\":0,\"retryCeiling\":0," +
+		"\"expiryWorkers\":0,\"stateless\":false}," +
+		"\"near\":{\"keys\":0,\"ttl\":0,\"bus\":\"\",\"channel\":\"apicache:invalidate\"},\"cache\":{\"maxKeys\":0,\"maxBytes\":0,\"eviction\":\"lru\"," +
+		"\"weights\":null,\"clientShare\":0,\"staleGrace\":0," +
+		"\"events\":0,\"overflow\":\"drop-newest\"}," +
+		"\"origin\":{\"url\":\"\",\"ttl\":0,\"timeout\":0}," +
+		"\"webhooks\":{\"file\":\"\",\"workers\":0,\"retries\":3,\"backoff\":0,\"timeout\":0,\"subscriptions\":null}," +
+		"\"namespaces\":{\"file\":\"\",\"list\":null},\"ttl\":{\"file\":\"\",\"rules\":null}}"
 
 	got, err := config.New(toolkit.EnvFile())
 
@@ -80,4 +94,99 @@ func TestUnitSettingsEnvDependent(t *testing.T) {
 
 		toolkit.Assert(t, toolkit.Got(nil, obj.Driver.Name), toolkit.Want[config.Driver](config.Driver(driverName), nil))
 	}
+
+	t.Setenv("APICACHE_CACHE_EVICTION", "invalid")
+
+	obj, err = config.New(toolkit.EnvFile())
+
+	toolkit.Assert(t, toolkit.Got(err, obj), toolkit.Want[*config.Settings](nil, config.ErrInvalidEviction))
+
+	for _, evictionName := range []string{"lru", "lfu", "tinylfu"} {
+		t.Setenv("APICACHE_CACHE_EVICTION", evictionName)
+
+		obj, _ = config.New(toolkit.EnvFile())
+
+		toolkit.Assert(t, toolkit.Got(nil, obj.Cache.Eviction), toolkit.Want(config.Eviction(evictionName), nil))
+	}
+
+	t.Setenv("APICACHE_CACHE_OVERFLOW", "invalid")
+
+	obj, err = config.New(toolkit.EnvFile())
+
+	toolkit.Assert(t, toolkit.Got(err, obj), toolkit.Want[*config.Settings](nil, config.ErrInvalidOverflow))
+
+	for _, overflowName := range []string{"drop-newest", "drop-oldest", "block"} {
+		t.Setenv("APICACHE_CACHE_OVERFLOW", overflowName)
+
+		obj, _ = config.New(toolkit.EnvFile())
+
+		toolkit.Assert(t, toolkit.Got(nil, obj.Cache.Overflow), toolkit.Want(config.Overflow(overflowName), nil))
+	}
+
+	t.Setenv("APICACHE_WEBHOOKS_FILE", path.Join(toolkit.RootDir(), "configs", "missed.json"))
+
+	obj, err = config.New(toolkit.EnvFile())
+
+	require.ErrorIs(t, err, config.ErrInvalidWebhooks)
+	assert.Nil(t, obj)
+
+	t.Setenv("APICACHE_WEBHOOKS_FILE", toolkit.EnvFile())
+
+	obj, err = config.New(toolkit.EnvFile())
+
+	require.ErrorIs(t, err, config.ErrInvalidWebhooks)
+	assert.Nil(t, obj)
+
+	t.Setenv("APICACHE_WEBHOOKS_FILE", path.Join(toolkit.RootDir(), "configs", "webhooks.example.json"))
+
+	obj, _ = config.New(toolkit.EnvFile())
+
+	toolkit.Assert(t, toolkit.Got(nil, obj.Webhooks.Subscriptions), toolkit.Want([]config.Webhook{{
+		ID:     "sessions",
+		URL:    "http://127.0.0.1:9000/hooks/sessions",
+		Prefix: "session:",
+		Events: []string{"expire", "delete"},
+		Secret: "change-me",
+	}}, nil))
+
+	t.Setenv("APICACHE_WEBHOOKS_FILE", "")
+	t.Setenv("APICACHE_NAMESPACES_FILE", toolkit.EnvFile())
+
+	obj, err = config.New(toolkit.EnvFile())
+
+	require.ErrorIs(t, err, config.ErrInvalidNamespaces)
+	assert.Nil(t, obj)
+
+	t.Setenv("APICACHE_NAMESPACES_FILE", path.Join(toolkit.RootDir(), "configs", "namespaces.example.json"))
+
+	obj, _ = config.New(toolkit.EnvFile())
+
+	toolkit.Assert(t, toolkit.Got(nil, obj.Namespaces.List), toolkit.Want([]config.Namespace{
+		{Name: "sessions", DefaultTTL: config.Duration(30 * time.Minute), MaxTTL: config.Duration(24 * time.Hour), MaxKeys: 100000, Share: 0.5},
+		{Name: "reports", DefaultTTL: 0, MaxTTL: 0, MaxKeys: 0, Share: 0.25},
+	}, nil))
+
+	t.Setenv("APICACHE_NAMESPACES_FILE", "")
+	t.Setenv("APICACHE_TTL_POLICY_FILE", toolkit.EnvFile())
+
+	obj, err = config.New(toolkit.EnvFile())
+
+	require.ErrorIs(t, err, config.ErrInvalidTTLPolicy)
+	assert.Nil(t, obj)
+
+	t.Setenv("APICACHE_TTL_POLICY_FILE", path.Join(toolkit.RootDir(), "configs", "ttl.example.json"))
+
+	obj, _ = config.New(toolkit.EnvFile())
+
+	toolkit.Assert(t, toolkit.Got(nil, obj.TTL.Rules), toolkit.Want([]config.TTLRule{
+		{Pattern: "session:", DefaultTTL: config.Duration(time.Hour), MaxTTL: config.Duration(24 * time.Hour), Finite: false, Jitter: 0.1},
+		{Pattern: "lock:*", DefaultTTL: 0, MaxTTL: 0, Finite: true, Jitter: 0},
+	}, nil))
+
+	t.Setenv("APICACHE_TTL_POLICY_FILE", "")
+	t.Setenv("APICACHE_CACHE_CLIENT_WEIGHTS", "key1:3,key2:1")
+
+	obj, _ = config.New(toolkit.EnvFile())
+
+	toolkit.Assert(t, toolkit.Got(nil, obj.Cache.Weights), toolkit.Want(map[string]int{"key1": 3, "key2": 1}, nil))
 }
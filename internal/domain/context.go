@@ -0,0 +1,106 @@
+package domain
+
+import "context"
+
+// Priority of the request, the higher one is admitted first, the lowest one could be shed under overload.
+type Priority int
+
+const (
+	PriorityLow Priority = iota
+	PriorityNormal
+	PriorityHigh
+)
+
+// Condition of the write, the key is written only when it holds.
+type Condition int
+
+const (
+	ConditionNone Condition = iota
+	// ConditionAbsent writes the key only if it doesn't exist yet, like NX of redis.
+	ConditionAbsent
+	// ConditionPresent writes the key only if it exists already, like XX of redis.
+	ConditionPresent
+)
+
+type (
+	clientKey    struct{}
+	priorityKey  struct{}
+	namespaceKey struct{}
+	slidingKey   struct{}
+	conditionKey struct{}
+	versionKey   struct{}
+)
+
+// WithClient marks the context with identity of the client, so the cache shares its capacity fairly.
+func WithClient(ctx context.Context, client string) context.Context {
+	return context.WithValue(ctx, clientKey{}, client)
+}
+
+// ClientFrom returns identity of the client, empty string means anonymous.
+func ClientFrom(ctx context.Context) string {
+	client, _ := ctx.Value(clientKey{}).(string)
+
+	return client
+}
+
+func WithPriority(ctx context.Context, priority Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, priority)
+}
+
+// PriorityFrom returns priority of the request, PriorityNormal by default.
+func PriorityFrom(ctx context.Context) Priority {
+	priority, ok := ctx.Value(priorityKey{}).(Priority)
+	if !ok {
+		return PriorityNormal
+	}
+
+	return priority
+}
+
+// WithNamespace marks the context with the namespace the keys of the request belong to.
+func WithNamespace(ctx context.Context, namespace string) context.Context {
+	return context.WithValue(ctx, namespaceKey{}, namespace)
+}
+
+// NamespaceFrom returns the namespace of the request, empty string means the flat keyspace.
+func NamespaceFrom(ctx context.Context) string {
+	namespace, _ := ctx.Value(namespaceKey{}).(string)
+
+	return namespace
+}
+
+// WithSliding asks the cache to extend the deadline of the written key by its TTL on every read.
+func WithSliding(ctx context.Context, sliding bool) context.Context {
+	return context.WithValue(ctx, slidingKey{}, sliding)
+}
+
+// SlidingFrom tells whether the written key has sliding expiration, absolute deadline by default.
+func SlidingFrom(ctx context.Context) bool {
+	sliding, _ := ctx.Value(slidingKey{}).(bool)
+
+	return sliding
+}
+
+// WithCondition asks the cache to check the existence of the key and to write it atomically.
+func WithCondition(ctx context.Context, condition Condition) context.Context {
+	return context.WithValue(ctx, conditionKey{}, condition)
+}
+
+// ConditionFrom returns the condition of the write, ConditionNone by default.
+func ConditionFrom(ctx context.Context) Condition {
+	condition, _ := ctx.Value(conditionKey{}).(Condition)
+
+	return condition
+}
+
+// WithVersion asks the cache to write the key only if it has the version, it's the compare-and-swap.
+func WithVersion(ctx context.Context, version uint64) context.Context {
+	return context.WithValue(ctx, versionKey{}, version)
+}
+
+// VersionFrom returns the expected version of the key, zero means any.
+func VersionFrom(ctx context.Context) uint64 {
+	version, _ := ctx.Value(versionKey{}).(uint64)
+
+	return version
+}
@@ -0,0 +1,81 @@
+package domain_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+func TestUnitClient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ClientFrom(ctx)), toolkit.Want("", nil))
+
+	ctx = domain.WithClient(ctx, "client")
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ClientFrom(ctx)), toolkit.Want("client", nil))
+}
+
+func TestUnitPriority(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.PriorityFrom(ctx)), toolkit.Want(domain.PriorityNormal, nil))
+
+	ctx = domain.WithPriority(ctx, domain.PriorityLow)
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.PriorityFrom(ctx)), toolkit.Want(domain.PriorityLow, nil))
+}
+
+func TestUnitNamespace(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.NamespaceFrom(ctx)), toolkit.Want("", nil))
+
+	ctx = domain.WithNamespace(ctx, "team")
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.NamespaceFrom(ctx)), toolkit.Want("team", nil))
+}
+
+func TestUnitSliding(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.SlidingFrom(ctx)), toolkit.Want(false, nil))
+
+	ctx = domain.WithSliding(ctx, true)
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.SlidingFrom(ctx)), toolkit.Want(true, nil))
+}
+
+func TestUnitCondition(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ConditionFrom(ctx)), toolkit.Want(domain.ConditionNone, nil))
+
+	ctx = domain.WithCondition(ctx, domain.ConditionAbsent)
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ConditionFrom(ctx)), toolkit.Want(domain.ConditionAbsent, nil))
+}
+
+func TestUnitVersion(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.VersionFrom(ctx)), toolkit.Want(uint64(0), nil))
+
+	ctx = domain.WithVersion(ctx, 42)
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.VersionFrom(ctx)), toolkit.Want(uint64(42), nil))
+}
@@ -1,14 +1,57 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"time"
+)
 
 var (
 	ErrKeyNotExist    = errors.New("key not exist")
 	ErrKeyExpired     = errors.New("key is expired")
+	ErrKeyExists      = errors.New("key already exist")
 	ErrConnTimeout    = errors.New("connection timeout")
 	ErrContextTimeout = errors.New("context timeout")
 	ErrClosed         = errors.New("closed instance")
 	ErrEmptyKey       = errors.New("empty key")
+	ErrInvalidKey     = errors.New("invalid key")
 	ErrEmptyVal       = errors.New("empty value")
 	ErrDataCorrupted  = errors.New("data corrupted")
+	ErrShed           = errors.New("request shed")
+	ErrCircuitOpen    = errors.New("circuit open")
+	ErrStale          = errors.New("stale value")
+	ErrInvalidOffset  = errors.New("invalid offset")
+	ErrOffsetGone     = errors.New("offset gone")
+	ErrInvalidURL     = errors.New("invalid url")
+	ErrInvalidEvent   = errors.New("invalid event")
+	ErrNotSubscribed  = errors.New("subscription not exist")
+	ErrUnauthorized   = errors.New("unauthorized")
+	ErrNoNamespace    = errors.New("namespace not exist")
+	ErrInvalidExpiry  = errors.New("invalid expiry")
+	ErrPastDeadline   = errors.New("deadline in the past")
+	ErrInfiniteKey    = errors.New("infinite key forbidden")
+
+	// ErrReadPoolExhausted and ErrWritePoolExhausted accompany the timeouts when
+	// reads and writes are admitted through the separate pools.
+	ErrReadPoolExhausted  = errors.New("read pool exhausted")
+	ErrWritePoolExhausted = errors.New("write pool exhausted")
+
+	// ErrVersionMismatch fails the compare-and-swap, the key was written by someone else after it was read.
+	ErrVersionMismatch = errors.New("version mismatch")
+	// ErrUnsupported is the request the cache can't serve safely with its driver, e.g. the conditional
+	// write to the driver that has no atomic one.
+	ErrUnsupported = errors.New("unsupported by the driver")
 )
+
+// RetryError tells the caller when it makes sense to repeat the call.
+type RetryError struct {
+	Err   error
+	After time.Duration
+}
+
+func (e *RetryError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *RetryError) Unwrap() error {
+	return e.Err
+}
@@ -1,7 +1,9 @@
 package domain_test
 
 import (
+	"errors"
 	"testing"
+	"time"
 
 	"github.com/therenotomorrow/apicache/internal/domain"
 	"github.com/therenotomorrow/apicache/test/toolkit"
@@ -19,6 +21,18 @@ func TestUnitErrKeyExpired(t *testing.T) {
 	toolkit.Assert(t, toolkit.Got(nil, domain.ErrKeyExpired.Error()), toolkit.Want("key is expired", nil))
 }
 
+func TestUnitErrKeyExists(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrKeyExists.Error()), toolkit.Want("key already exist", nil))
+}
+
+func TestUnitErrVersionMismatch(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrVersionMismatch.Error()), toolkit.Want("version mismatch", nil))
+}
+
 func TestUnitErrConnTimeout(t *testing.T) {
 	t.Parallel()
 
@@ -43,8 +57,113 @@ func TestUnitErrEmptyKey(t *testing.T) {
 	toolkit.Assert(t, toolkit.Got(nil, domain.ErrEmptyKey.Error()), toolkit.Want("empty key", nil))
 }
 
+func TestUnitErrInvalidKey(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrInvalidKey.Error()), toolkit.Want("invalid key", nil))
+}
+
 func TestUnitErrEmptyVal(t *testing.T) {
 	t.Parallel()
 
 	toolkit.Assert(t, toolkit.Got(nil, domain.ErrEmptyVal.Error()), toolkit.Want("empty value", nil))
 }
+
+func TestUnitErrShed(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrShed.Error()), toolkit.Want("request shed", nil))
+}
+
+func TestUnitErrCircuitOpen(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrCircuitOpen.Error()), toolkit.Want("circuit open", nil))
+}
+
+func TestUnitErrStale(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrStale.Error()), toolkit.Want("stale value", nil))
+}
+
+func TestUnitErrInvalidOffset(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrInvalidOffset.Error()), toolkit.Want("invalid offset", nil))
+}
+
+func TestUnitErrOffsetGone(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrOffsetGone.Error()), toolkit.Want("offset gone", nil))
+}
+
+func TestUnitErrInvalidURL(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrInvalidURL.Error()), toolkit.Want("invalid url", nil))
+}
+
+func TestUnitErrInvalidEvent(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrInvalidEvent.Error()), toolkit.Want("invalid event", nil))
+}
+
+func TestUnitErrNotSubscribed(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrNotSubscribed.Error()), toolkit.Want("subscription not exist", nil))
+}
+
+func TestUnitErrUnauthorized(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrUnauthorized.Error()), toolkit.Want("unauthorized", nil))
+}
+
+func TestUnitErrNoNamespace(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrNoNamespace.Error()), toolkit.Want("namespace not exist", nil))
+}
+
+func TestUnitErrInvalidExpiry(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrInvalidExpiry.Error()), toolkit.Want("invalid expiry", nil))
+}
+
+func TestUnitErrPastDeadline(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrPastDeadline.Error()), toolkit.Want("deadline in the past", nil))
+}
+
+func TestUnitErrInfiniteKey(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrInfiniteKey.Error()), toolkit.Want("infinite key forbidden", nil))
+}
+
+func TestUnitRetryError(t *testing.T) {
+	t.Parallel()
+
+	err := &domain.RetryError{Err: domain.ErrCircuitOpen, After: time.Second}
+
+	toolkit.Assert(t, toolkit.Got(nil, err.Error()), toolkit.Want("circuit open", nil))
+	toolkit.Assert(t, toolkit.Got(nil, errors.Is(err, domain.ErrCircuitOpen)), toolkit.Want(true, nil))
+}
+
+func TestUnitErrReadPoolExhausted(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrReadPoolExhausted.Error()), toolkit.Want("read pool exhausted", nil))
+}
+
+func TestUnitErrWritePoolExhausted(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, domain.ErrWritePoolExhausted.Error()), toolkit.Want("write pool exhausted", nil))
+}
@@ -9,10 +9,62 @@ type (
 	CacheGetter interface {
 		Get(ctx context.Context, key string) ([]byte, error)
 	}
+	// CacheVersioner reads the key along with its version, zero version means unknown one.
+	CacheVersioner interface {
+		GetVersion(ctx context.Context, key string) ([]byte, uint64, error)
+	}
+	CacheLoader interface {
+		GetOrLoad(ctx context.Context, key string) ([]byte, error)
+	}
 	CacheSetter interface {
 		Set(ctx context.Context, key string, val []byte, deadline time.Time) error
 	}
 	CacheDeleter interface {
 		Del(ctx context.Context, key string) error
 	}
+	// CacheInspector tells when the key expires, zero time means infinite key.
+	CacheInspector interface {
+		Deadline(ctx context.Context, key string) (time.Time, error)
+	}
+	// CacheExpirer moves the deadline of the key without touching its value.
+	CacheExpirer interface {
+		Expire(ctx context.Context, key string, deadline time.Time) error
+		Persist(ctx context.Context, key string) error
+	}
+	// TTLPolicy shapes the deadline of the written key by its name, zero deadline means infinite key.
+	TTLPolicy interface {
+		Deadline(key string, deadline time.Time, now time.Time) (time.Time, error)
+	}
+	// CacheFlusher deletes all the keys of the namespace from the context at once.
+	CacheFlusher interface {
+		Flush(ctx context.Context) error
+	}
+	NamespaceChecker interface {
+		Exists(namespace string) bool
+	}
+	// ChangeWatcher follows the changes of the keys with the prefix after the given ID,
+	// zero ID means the changes that happen from now on.
+	ChangeWatcher interface {
+		Watch(prefix string, after uint64) (Changes, error)
+	}
+	Changes interface {
+		// Next waits for the changes that are not seen yet.
+		Next(ctx context.Context) ([]Change, error)
+		Close() error
+	}
+	Subscriber interface {
+		Subscribe(sub Subscription) (Subscription, error)
+	}
+	Unsubscriber interface {
+		Unsubscribe(id string) error
+	}
+	SubscriptionLister interface {
+		Subscriptions() []Subscription
+	}
+	DeadLetterLister interface {
+		DeadLetters() []DeadLetter
+	}
+	Clock interface {
+		Now() time.Time
+	}
 )
@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/clock"
 )
 
 func TestUnitCacheGetter(t *testing.T) {
@@ -12,6 +13,18 @@ func TestUnitCacheGetter(t *testing.T) {
 	var _ domain.CacheGetter = getter{}
 }
 
+func TestUnitCacheVersioner(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.CacheVersioner = versioner{}
+}
+
+func TestUnitCacheLoader(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.CacheLoader = loader{}
+}
+
 func TestUnitCacheSetter(t *testing.T) {
 	t.Parallel()
 
@@ -23,3 +36,75 @@ func TestUnitCacheDeleter(t *testing.T) {
 
 	var _ domain.CacheDeleter = deleter{}
 }
+
+func TestUnitCacheInspector(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.CacheInspector = expirer{}
+}
+
+func TestUnitCacheExpirer(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.CacheExpirer = expirer{}
+}
+
+func TestUnitTTLPolicy(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.TTLPolicy = policy{}
+}
+
+func TestUnitCacheFlusher(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.CacheFlusher = flusher{}
+}
+
+func TestUnitNamespaceChecker(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.NamespaceChecker = flusher{}
+}
+
+func TestUnitChangeWatcher(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.ChangeWatcher = watcher{}
+}
+
+func TestUnitChanges(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.Changes = changes{}
+}
+
+func TestUnitSubscriber(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.Subscriber = hooks{}
+}
+
+func TestUnitUnsubscriber(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.Unsubscriber = hooks{}
+}
+
+func TestUnitSubscriptionLister(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.SubscriptionLister = hooks{}
+}
+
+func TestUnitDeadLetterLister(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.DeadLetterLister = hooks{}
+}
+
+func TestUnitClock(t *testing.T) {
+	t.Parallel()
+
+	var _ domain.Clock = clock.New()
+}
@@ -1,3 +1,58 @@
 package domain
 
+import "time"
+
+// Separator joins the parts of the internal keys, e.g. the namespace and the key, so the keys of the clients
+// can't have it (see ErrInvalidKey) and never address the internal ones.
+const Separator = "\x1f"
+
 type ValType map[string]any
+
+// Expiry of the written key, TTL and ExpireAt exclude each other, both zero mean infinite key.
+type Expiry struct {
+	TTL      time.Duration
+	ExpireAt time.Time
+	// Sliding extends the deadline by the TTL on every read.
+	Sliding bool
+}
+
+// Lifetime of the key, TTL is what is left until the Deadline, zero Deadline means infinite key.
+type Lifetime struct {
+	TTL      time.Duration
+	Deadline time.Time
+}
+
+const (
+	EventDelete = "delete"
+	EventExpire = "expire"
+)
+
+// Change is what happened to the key, IDs grow by one, so the watcher could resume after the last seen.
+type Change struct {
+	ID   uint64
+	Kind string
+	Key  string
+	Time time.Time
+}
+
+// Subscription is the webhook called on the events of the keys with the prefix,
+// the payloads are signed by the secret.
+type Subscription struct {
+	ID     string
+	URL    string
+	Prefix string
+	Events []string
+	Secret string
+}
+
+// DeadLetter is the delivery of the webhook that failed all the attempts.
+type DeadLetter struct {
+	ID           string
+	Subscription string
+	URL          string
+	Event        string
+	Key          string
+	Time         time.Time
+	Attempts     int
+	Error        string
+}
@@ -2,6 +2,7 @@ package domain_test
 
 import (
 	"testing"
+	"time"
 
 	"github.com/therenotomorrow/apicache/internal/domain"
 )
@@ -11,3 +12,30 @@ func TestUnitValType(t *testing.T) {
 
 	var _ domain.ValType = map[string]any{"hello": "world", "age": 42}
 }
+
+func TestUnitChange(t *testing.T) {
+	t.Parallel()
+
+	var _ = domain.Change{ID: 1, Kind: "set", Key: "key", Time: time.Now()}
+}
+
+func TestUnitSubscription(t *testing.T) {
+	t.Parallel()
+
+	var _ = domain.Subscription{ID: "id", URL: "http://test.loc", Prefix: "", Events: nil, Secret: ""}
+}
+
+func TestUnitDeadLetter(t *testing.T) {
+	t.Parallel()
+
+	var _ = domain.DeadLetter{
+		ID:           "id",
+		Subscription: "id",
+		URL:          "http://test.loc",
+		Event:        domain.EventExpire,
+		Key:          "key",
+		Time:         time.Now(),
+		Attempts:     1,
+		Error:        "",
+	}
+}
@@ -3,7 +3,12 @@ package domain
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -13,12 +18,52 @@ type (
 	GetUseCase struct {
 		cache CacheGetter
 	}
+	GetVersionUseCase struct {
+		cache CacheVersioner
+	}
+	GetOrLoadUseCase struct {
+		cache CacheLoader
+	}
 	SetUseCase struct {
-		cache CacheSetter
+		cache  CacheSetter
+		clock  Clock
+		policy TTLPolicy
 	}
 	DelUseCase struct {
 		cache CacheDeleter
 	}
+	TTLUseCase struct {
+		cache CacheInspector
+		clock Clock
+	}
+	ExpireUseCase struct {
+		cache  CacheExpirer
+		clock  Clock
+		policy TTLPolicy
+	}
+	PersistUseCase struct {
+		cache  CacheExpirer
+		clock  Clock
+		policy TTLPolicy
+	}
+	FlushUseCase struct {
+		cache CacheFlusher
+	}
+	WatchUseCase struct {
+		feed ChangeWatcher
+	}
+	SubscribeUseCase struct {
+		hooks Subscriber
+	}
+	UnsubscribeUseCase struct {
+		hooks Unsubscriber
+	}
+	SubscriptionsUseCase struct {
+		hooks SubscriptionLister
+	}
+	DeadLettersUseCase struct {
+		hooks DeadLetterLister
+	}
 )
 
 func NewGetUseCase(cache CacheGetter) *GetUseCase {
@@ -26,32 +71,70 @@ func NewGetUseCase(cache CacheGetter) *GetUseCase {
 }
 
 func (use *GetUseCase) Execute(ctx context.Context, key string) (ValType, error) {
-	if key == "" {
-		return nil, ErrEmptyKey
+	err := validate(key)
+	if err != nil {
+		return nil, err
 	}
 
 	raw, err := use.cache.Get(ctx, key)
-	if err != nil {
+	if err != nil && !errors.Is(err, ErrStale) {
 		return nil, fmt.Errorf("%w", err)
 	}
 
-	var val ValType
+	return stale(raw, err)
+}
 
-	err = json.Unmarshal(raw, &val)
+func NewGetVersionUseCase(cache CacheVersioner) *GetVersionUseCase {
+	return &GetVersionUseCase{cache: cache}
+}
+
+// Execute reads the key along with its version, the stale value has no version.
+func (use *GetVersionUseCase) Execute(ctx context.Context, key string) (ValType, uint64, error) {
+	err := validate(key)
 	if err != nil {
-		return nil, ErrDataCorrupted
+		return nil, 0, err
 	}
 
-	return val, nil
+	raw, version, err := use.cache.GetVersion(ctx, key)
+	if err != nil && !errors.Is(err, ErrStale) {
+		return nil, 0, fmt.Errorf("%w", err)
+	}
+
+	val, err := stale(raw, err)
+	if errors.Is(err, ErrDataCorrupted) {
+		return nil, 0, err
+	}
+
+	return val, version, err
 }
 
-func NewSetUseCase(cache CacheSetter) *SetUseCase {
-	return &SetUseCase{cache: cache}
+func NewGetOrLoadUseCase(cache CacheLoader) *GetOrLoadUseCase {
+	return &GetOrLoadUseCase{cache: cache}
 }
 
-func (use *SetUseCase) Execute(ctx context.Context, key string, val ValType, ttl int) error {
-	if key == "" {
-		return ErrEmptyKey
+func (use *GetOrLoadUseCase) Execute(ctx context.Context, key string) (ValType, error) {
+	err := validate(key)
+	if err != nil {
+		return nil, err
+	}
+
+	raw, err := use.cache.GetOrLoad(ctx, key)
+	if err != nil && !errors.Is(err, ErrStale) {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return stale(raw, err)
+}
+
+func NewSetUseCase(cache CacheSetter, clock Clock, policy TTLPolicy) *SetUseCase {
+	return &SetUseCase{cache: cache, clock: clock, policy: policy}
+}
+
+// Execute writes the key, sliding key gets its TTL back on every read instead of the absolute deadline.
+func (use *SetUseCase) Execute(ctx context.Context, key string, val ValType, expiry Expiry) error {
+	err := validate(key)
+	if err != nil {
+		return err
 	}
 
 	if val == nil {
@@ -63,10 +146,21 @@ func (use *SetUseCase) Execute(ctx context.Context, key string, val ValType, ttl
 		return ErrDataCorrupted
 	}
 
-	var deadline time.Time
+	now := use.clock.Now()
+
+	deadline, err := expiry.deadline(now)
+	if err != nil {
+		return err
+	}
 
-	if ttl > defaultTTL {
-		deadline = time.Now().UTC().Add(time.Duration(ttl) * time.Second)
+	deadline, err = use.policy.Deadline(key, deadline, now)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// the key without deadline has nothing to extend
+	if expiry.Sliding && !deadline.IsZero() {
+		ctx = WithSliding(ctx, true)
 	}
 
 	err = use.cache.Set(ctx, key, raw, deadline)
@@ -77,19 +171,267 @@ func (use *SetUseCase) Execute(ctx context.Context, key string, val ValType, ttl
 	return nil
 }
 
+func (expiry Expiry) deadline(now time.Time) (time.Time, error) {
+	switch {
+	case expiry.ExpireAt.IsZero() && expiry.TTL > defaultTTL:
+		return now.Add(expiry.TTL), nil
+	case expiry.ExpireAt.IsZero():
+		return time.Time{}, nil
+	case expiry.TTL > defaultTTL:
+		return time.Time{}, ErrInvalidExpiry
+	case !expiry.ExpireAt.After(now):
+		return time.Time{}, ErrPastDeadline
+	}
+
+	return expiry.ExpireAt, nil
+}
+
+func NewTTLUseCase(cache CacheInspector, clock Clock) *TTLUseCase {
+	return &TTLUseCase{cache: cache, clock: clock}
+}
+
+func (use *TTLUseCase) Execute(ctx context.Context, key string) (Lifetime, error) {
+	err := validate(key)
+	if err != nil {
+		return Lifetime{}, err
+	}
+
+	deadline, err := use.cache.Deadline(ctx, key)
+	if err != nil {
+		return Lifetime{}, fmt.Errorf("%w", err)
+	}
+
+	if deadline.IsZero() {
+		return Lifetime{TTL: 0, Deadline: deadline}, nil
+	}
+
+	return Lifetime{TTL: max(0, deadline.Sub(use.clock.Now())), Deadline: deadline}, nil
+}
+
+func NewExpireUseCase(cache CacheExpirer, clock Clock, policy TTLPolicy) *ExpireUseCase {
+	return &ExpireUseCase{cache: cache, clock: clock, policy: policy}
+}
+
+// Execute moves the deadline of the key, the expiry must have one: the key is made infinite by PersistUseCase.
+func (use *ExpireUseCase) Execute(ctx context.Context, key string, expiry Expiry) error {
+	err := validate(key)
+	if err != nil {
+		return err
+	}
+
+	now := use.clock.Now()
+
+	deadline, err := expiry.deadline(now)
+	if err != nil {
+		return err
+	}
+
+	if deadline.IsZero() {
+		return ErrInvalidExpiry
+	}
+
+	deadline, err = use.policy.Deadline(key, deadline, now)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	err = use.cache.Expire(ctx, key, deadline)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+func NewPersistUseCase(cache CacheExpirer, clock Clock, policy TTLPolicy) *PersistUseCase {
+	return &PersistUseCase{cache: cache, clock: clock, policy: policy}
+}
+
+// Execute makes the key infinite, unless the policy gives such keys a deadline.
+func (use *PersistUseCase) Execute(ctx context.Context, key string) error {
+	err := validate(key)
+	if err != nil {
+		return err
+	}
+
+	deadline, err := use.policy.Deadline(key, time.Time{}, use.clock.Now())
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	if !deadline.IsZero() {
+		return ErrInfiniteKey
+	}
+
+	err = use.cache.Persist(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
 func NewDelUseCase(cache CacheDeleter) *DelUseCase {
 	return &DelUseCase{cache: cache}
 }
 
 func (use *DelUseCase) Execute(ctx context.Context, key string) error {
-	if key == "" {
+	err := validate(key)
+	if err != nil {
+		return err
+	}
+
+	err = use.cache.Del(ctx, key)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+func NewFlushUseCase(cache CacheFlusher) *FlushUseCase {
+	return &FlushUseCase{cache: cache}
+}
+
+func (use *FlushUseCase) Execute(ctx context.Context) error {
+	if NamespaceFrom(ctx) == "" {
+		return ErrNoNamespace
+	}
+
+	err := use.cache.Flush(ctx)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	return nil
+}
+
+func NewWatchUseCase(feed ChangeWatcher) *WatchUseCase {
+	return &WatchUseCase{feed: feed}
+}
+
+// Execute starts watching after the last seen ID (e.g. Last-Event-ID), empty one means from now on.
+func (use *WatchUseCase) Execute(prefix string, last string) (Changes, error) {
+	var after uint64
+
+	if last != "" {
+		id, err := strconv.ParseUint(last, 10, 64)
+		if err != nil {
+			return nil, ErrInvalidOffset
+		}
+
+		after = id
+	}
+
+	changes, err := use.feed.Watch(prefix, after)
+	if err != nil {
+		return nil, fmt.Errorf("%w", err)
+	}
+
+	return changes, nil
+}
+
+func NewSubscribeUseCase(hooks Subscriber) *SubscribeUseCase {
+	return &SubscribeUseCase{hooks: hooks}
+}
+
+// Execute validates the subscription, the secret is generated when it is missed.
+func (use *SubscribeUseCase) Execute(sub Subscription) (Subscription, error) {
+	link, err := url.Parse(sub.URL)
+	if err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "" {
+		return Subscription{}, ErrInvalidURL
+	}
+
+	if len(sub.Events) == 0 {
+		return Subscription{}, ErrInvalidEvent
+	}
+
+	for _, event := range sub.Events {
+		if !slices.Contains([]string{EventDelete, EventExpire}, event) {
+			return Subscription{}, ErrInvalidEvent
+		}
+	}
+
+	sub, err = use.hooks.Subscribe(sub)
+	if err != nil {
+		return Subscription{}, fmt.Errorf("%w", err)
+	}
+
+	return sub, nil
+}
+
+func NewUnsubscribeUseCase(hooks Unsubscriber) *UnsubscribeUseCase {
+	return &UnsubscribeUseCase{hooks: hooks}
+}
+
+func (use *UnsubscribeUseCase) Execute(id string) error {
+	if id == "" {
 		return ErrEmptyKey
 	}
 
-	err := use.cache.Del(ctx, key)
+	err := use.hooks.Unsubscribe(id)
 	if err != nil {
 		return fmt.Errorf("%w", err)
 	}
 
 	return nil
 }
+
+func NewSubscriptionsUseCase(hooks SubscriptionLister) *SubscriptionsUseCase {
+	return &SubscriptionsUseCase{hooks: hooks}
+}
+
+// Execute lists the subscriptions without their secrets.
+func (use *SubscriptionsUseCase) Execute() []Subscription {
+	subs := use.hooks.Subscriptions()
+	for i := range subs {
+		subs[i].Secret = ""
+	}
+
+	return subs
+}
+
+func NewDeadLettersUseCase(hooks DeadLetterLister) *DeadLettersUseCase {
+	return &DeadLettersUseCase{hooks: hooks}
+}
+
+func (use *DeadLettersUseCase) Execute() []DeadLetter {
+	return use.hooks.DeadLetters()
+}
+
+// validate rejects the empty key and the key with the Separator, the latter could address the internal keys.
+func validate(key string) error {
+	switch {
+	case key == "":
+		return ErrEmptyKey
+	case strings.Contains(key, Separator):
+		return ErrInvalidKey
+	}
+
+	return nil
+}
+
+// stale decodes the value that is served along with ErrStale, so the caller decides whether to use it.
+func stale(raw []byte, err error) (ValType, error) {
+	val, errD := decode(raw)
+	if errD != nil {
+		return nil, errD
+	}
+
+	if err != nil {
+		return val, fmt.Errorf("%w", err)
+	}
+
+	return val, nil
+}
+
+func decode(raw []byte) (ValType, error) {
+	var val ValType
+
+	err := json.Unmarshal(raw, &val)
+	if err != nil {
+		return nil, ErrDataCorrupted
+	}
+
+	return val, nil
+}
@@ -6,7 +6,10 @@ import (
 	"testing"
 	"time"
 
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/test/mocks"
 	"github.com/therenotomorrow/apicache/test/toolkit"
 )
 
@@ -18,14 +21,27 @@ const (
 	Smoke5 = "smoke5"
 	Smoke6 = "smoke6"
 	Smoke7 = "smoke7"
+	Smoke8 = "smoke8"
+	Smoke9 = "smoke9"
 )
 
-var errDummy = errors.New("dummy error")
+var (
+	errDummy = errors.New("dummy error")
+	now      = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
 
 type (
 	getter        struct{}
+	versioner     struct{}
+	loader        struct{}
 	setter        struct{}
 	deleter       struct{}
+	watcher       struct{}
+	changes       struct{}
+	hooks         struct{}
+	flusher       struct{}
+	policy        struct{}
+	expirer       struct{}
 	cannotMarshal struct{}
 )
 
@@ -39,21 +55,44 @@ func (g getter) Get(_ context.Context, key string) ([]byte, error) {
 		return nil, errDummy
 	case Smoke4:
 		return []byte{}, nil
+	case Smoke5:
+		return []byte(`{"hello":"world","age":42}`), domain.ErrStale
+	case Smoke6:
+		return []byte{}, domain.ErrStale
 	}
 
 	return []byte(`{"hello":"world","age":42}`), nil
 }
 
-func (s setter) Set(_ context.Context, key string, _ []byte, deadline time.Time) error {
+func (v versioner) GetVersion(ctx context.Context, key string) ([]byte, uint64, error) {
+	val, err := getter{}.Get(ctx, key)
+	if err != nil {
+		return val, 0, err
+	}
+
+	return val, 7, nil
+}
+
+func (l loader) GetOrLoad(ctx context.Context, key string) ([]byte, error) {
+	return getter{}.Get(ctx, key)
+}
+
+func (s setter) Set(ctx context.Context, key string, _ []byte, deadline time.Time) error {
 	switch key {
 	case Smoke1, Smoke3:
-		if !deadline.IsZero() {
+		if !deadline.IsZero() || domain.SlidingFrom(ctx) {
 			return errDummy
 		}
 
 		return nil
 	case Smoke2:
-		if deadline.IsZero() {
+		if !deadline.Equal(now.Add(1500*time.Millisecond)) || !domain.SlidingFrom(ctx) {
+			return errDummy
+		}
+
+		return nil
+	case Smoke8:
+		if !deadline.Equal(now.Add(time.Hour)) {
 			return errDummy
 		}
 
@@ -65,6 +104,48 @@ func (s setter) Set(_ context.Context, key string, _ []byte, deadline time.Time)
 	return nil
 }
 
+func (p policy) Deadline(key string, deadline time.Time, now time.Time) (time.Time, error) {
+	if !deadline.IsZero() {
+		return deadline, nil
+	}
+
+	switch key {
+	case Smoke8:
+		return now.Add(time.Hour), nil
+	case Smoke9:
+		return time.Time{}, domain.ErrInfiniteKey
+	}
+
+	return deadline, nil
+}
+
+func (e expirer) Deadline(_ context.Context, key string) (time.Time, error) {
+	switch key {
+	case Smoke2:
+		return time.Time{}, nil
+	case Smoke3:
+		return time.Time{}, errDummy
+	}
+
+	return now.Add(time.Hour), nil
+}
+
+func (e expirer) Expire(_ context.Context, key string, deadline time.Time) error {
+	if key == Smoke3 || !deadline.Equal(now.Add(time.Minute)) {
+		return errDummy
+	}
+
+	return nil
+}
+
+func (e expirer) Persist(_ context.Context, key string) error {
+	if key == Smoke3 {
+		return errDummy
+	}
+
+	return nil
+}
+
 func (d deleter) Del(_ context.Context, key string) error {
 	if key == Smoke3 {
 		return errDummy
@@ -73,6 +154,70 @@ func (d deleter) Del(_ context.Context, key string) error {
 	return nil
 }
 
+func (f flusher) Flush(ctx context.Context) error {
+	if domain.NamespaceFrom(ctx) == Smoke3 {
+		return errDummy
+	}
+
+	return nil
+}
+
+func (f flusher) Exists(namespace string) bool {
+	return namespace == Smoke1
+}
+
+func (w watcher) Watch(prefix string, after uint64) (domain.Changes, error) {
+	if prefix == Smoke3 || after == 42 {
+		return nil, errDummy
+	}
+
+	return changes{}, nil
+}
+
+func (c changes) Next(_ context.Context) ([]domain.Change, error) {
+	return nil, nil
+}
+
+func (c changes) Close() error {
+	return nil
+}
+
+func (h hooks) Subscribe(sub domain.Subscription) (domain.Subscription, error) {
+	if sub.Prefix == Smoke7 {
+		return domain.Subscription{}, errDummy
+	}
+
+	sub.ID = "id"
+	sub.Secret = "secret"
+
+	return sub, nil
+}
+
+func (h hooks) Unsubscribe(id string) error {
+	if id == Smoke3 {
+		return domain.ErrNotSubscribed
+	}
+
+	return nil
+}
+
+func (h hooks) Subscriptions() []domain.Subscription {
+	return []domain.Subscription{{ID: "id", URL: "http://test.loc", Prefix: "", Events: nil, Secret: "secret"}}
+}
+
+func (h hooks) DeadLetters() []domain.DeadLetter {
+	return []domain.DeadLetter{{
+		ID:           "id",
+		Subscription: "id",
+		URL:          "http://test.loc",
+		Event:        domain.EventExpire,
+		Key:          "key",
+		Time:         now,
+		Attempts:     1,
+		Error:        "dummy error",
+	}}
+}
+
 func TestUnitGetUseCase(t *testing.T) {
 	t.Parallel()
 
@@ -95,6 +240,11 @@ func TestUnitGetUseCase(t *testing.T) {
 			args: args{key: ""},
 			want: toolkit.Want[domain.ValType](nil, domain.ErrEmptyKey),
 		},
+		{
+			name: "separator",
+			args: args{key: "team" + domain.Separator + "key"},
+			want: toolkit.Want[domain.ValType](nil, domain.ErrInvalidKey),
+		},
 		{
 			name: Smoke3,
 			args: args{key: Smoke3},
@@ -121,15 +271,138 @@ func TestUnitGetUseCase(t *testing.T) {
 	}
 }
 
-func TestUnitSetUseCase(t *testing.T) {
+func TestUnitGetVersionUseCase(t *testing.T) {
+	t.Parallel()
+
+	type want struct {
+		val     domain.ValType
+		version uint64
+		err     error
+	}
+
+	tests := []struct {
+		name string
+		args string
+		want want
+	}{
+		{
+			name: Smoke1,
+			args: Smoke1,
+			want: want{val: domain.ValType{"hello": "world", "age": float64(42)}, version: 7, err: nil},
+		},
+		{name: Smoke2, args: "", want: want{val: nil, version: 0, err: domain.ErrEmptyKey}},
+		{name: Smoke3, args: Smoke3, want: want{val: nil, version: 0, err: errDummy}},
+		{name: Smoke4, args: Smoke4, want: want{val: nil, version: 0, err: domain.ErrDataCorrupted}},
+		{
+			name: Smoke5,
+			args: Smoke5,
+			want: want{val: domain.ValType{"hello": "world", "age": float64(42)}, version: 0, err: domain.ErrStale},
+		},
+		{name: Smoke6, args: Smoke6, want: want{val: nil, version: 0, err: domain.ErrDataCorrupted}},
+	}
+
+	useCase := domain.NewGetVersionUseCase(versioner{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, version, err := useCase.Execute(context.Background(), test.args)
+
+			require.ErrorIs(t, err, test.want.err)
+			assert.Equal(t, test.want.val, got)
+			assert.Equal(t, test.want.version, version)
+		})
+	}
+}
+
+func TestUnitGetOrLoadUseCase(t *testing.T) {
 	t.Parallel()
 
 	type args struct {
 		key string
-		val domain.ValType
-		ttl int
 	}
 
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[domain.ValType]
+	}{
+		{
+			name: Smoke1,
+			args: args{key: Smoke1},
+			want: toolkit.Want(domain.ValType{"hello": "world", "age": float64(42)}, nil),
+		},
+		{
+			name: Smoke2,
+			args: args{key: ""},
+			want: toolkit.Want[domain.ValType](nil, domain.ErrEmptyKey),
+		},
+		{
+			name: "separator",
+			args: args{key: "team" + domain.Separator + "key"},
+			want: toolkit.Want[domain.ValType](nil, domain.ErrInvalidKey),
+		},
+		{
+			name: Smoke3,
+			args: args{key: Smoke3},
+			want: toolkit.Want[domain.ValType](nil, errDummy),
+		},
+		{
+			name: Smoke4,
+			args: args{key: Smoke4},
+			want: toolkit.Want[domain.ValType](nil, domain.ErrDataCorrupted),
+		},
+	}
+
+	useCase := domain.NewGetOrLoadUseCase(loader{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			got, err := useCase.Execute(ctx, test.args.key)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitGetUseCaseStale(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+
+	for _, useCase := range []interface {
+		Execute(ctx context.Context, key string) (domain.ValType, error)
+	}{domain.NewGetUseCase(getter{}), domain.NewGetOrLoadUseCase(loader{})} {
+		// the stale value is given to the caller along with the error
+		got, err := useCase.Execute(ctx, Smoke5)
+
+		require.ErrorIs(t, err, domain.ErrStale)
+		assert.Equal(t, domain.ValType{"hello": "world", "age": float64(42)}, got)
+
+		got, err = useCase.Execute(ctx, Smoke6)
+
+		toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[domain.ValType](nil, domain.ErrDataCorrupted))
+	}
+}
+
+func TestUnitSetUseCase(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		key    string
+		val    domain.ValType
+		expiry domain.Expiry
+	}
+
+	var (
+		val     = domain.ValType{"hello": "world", "age": 42}
+		forever = domain.Expiry{TTL: 0, ExpireAt: time.Time{}, Sliding: false}
+	)
+
 	tests := []struct {
 		name string
 		args args
@@ -137,51 +410,166 @@ func TestUnitSetUseCase(t *testing.T) {
 	}{
 		{
 			name: Smoke1,
-			args: args{key: Smoke1, val: domain.ValType{"hello": "world", "age": 42}, ttl: 0},
+			args: args{key: Smoke1, val: val, expiry: domain.Expiry{TTL: 0, ExpireAt: time.Time{}, Sliding: true}},
 			want: toolkit.Err(nil),
 		},
 		{
 			name: Smoke2,
-			args: args{key: Smoke2, val: domain.ValType{"hello": "world", "age": 42}, ttl: 10},
+			args: args{key: Smoke2, val: val, expiry: domain.Expiry{TTL: 1500 * time.Millisecond, ExpireAt: time.Time{}, Sliding: true}},
 			want: toolkit.Err(nil),
 		},
 		{
 			name: Smoke3,
-			args: args{key: Smoke3, val: domain.ValType{"hello": "world", "age": 42}, ttl: -10},
+			args: args{key: Smoke3, val: val, expiry: domain.Expiry{TTL: -10 * time.Second, ExpireAt: time.Time{}, Sliding: false}},
 			want: toolkit.Err(nil),
 		},
+		{name: Smoke4, args: args{key: "", val: val, expiry: forever}, want: toolkit.Err(domain.ErrEmptyKey)},
 		{
-			name: Smoke4,
-			args: args{key: "", val: domain.ValType{"hello": "world", "age": 42}, ttl: 0},
-			want: toolkit.Err(domain.ErrEmptyKey),
+			name: "separator",
+			args: args{key: "team" + domain.Separator + "key", val: val, expiry: forever},
+			want: toolkit.Err(domain.ErrInvalidKey),
 		},
-		{name: Smoke5, args: args{key: Smoke5, val: nil, ttl: 0}, want: toolkit.Err(domain.ErrEmptyVal)},
+		{name: Smoke5, args: args{key: Smoke5, val: nil, expiry: forever}, want: toolkit.Err(domain.ErrEmptyVal)},
 		{
 			name: Smoke6,
-			args: args{key: Smoke6, val: domain.ValType{"hello": cannotMarshal{}}, ttl: 0},
+			args: args{key: Smoke6, val: domain.ValType{"hello": cannotMarshal{}}, expiry: forever},
 			want: toolkit.Err(domain.ErrDataCorrupted),
 		},
+		{name: Smoke7, args: args{key: Smoke7, val: val, expiry: forever}, want: toolkit.Err(errDummy)},
+		{
+			name: Smoke8,
+			args: args{key: Smoke8, val: val, expiry: domain.Expiry{TTL: 0, ExpireAt: now.Add(time.Hour), Sliding: false}},
+			want: toolkit.Err(nil),
+		},
 		{
-			name: Smoke7,
-			args: args{key: Smoke7, val: domain.ValType{"hello": "world", "age": 42}, ttl: 0},
-			want: toolkit.Err(errDummy),
+			name: "past deadline",
+			args: args{key: Smoke8, val: val, expiry: domain.Expiry{TTL: 0, ExpireAt: now, Sliding: false}},
+			want: toolkit.Err(domain.ErrPastDeadline),
 		},
+		{
+			name: "both ttl and deadline",
+			args: args{key: Smoke8, val: val, expiry: domain.Expiry{TTL: time.Second, ExpireAt: now.Add(time.Hour), Sliding: false}},
+			want: toolkit.Err(domain.ErrInvalidExpiry),
+		},
+		{name: "policy default", args: args{key: Smoke8, val: val, expiry: forever}, want: toolkit.Err(nil)},
+		{name: "policy infinite", args: args{key: Smoke9, val: val, expiry: forever}, want: toolkit.Err(domain.ErrInfiniteKey)},
 	}
 
-	useCase := domain.NewSetUseCase(setter{})
+	useCase := domain.NewSetUseCase(setter{}, mocks.NewClockMock(now), policy{})
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
 			ctx := context.Background()
-			err := useCase.Execute(ctx, test.args.key, test.args.val, test.args.ttl)
+			err := useCase.Execute(ctx, test.args.key, test.args.val, test.args.expiry)
 
 			toolkit.Assert(t, toolkit.Got[any](err), test.want)
 		})
 	}
 }
 
+func TestUnitTTLUseCase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want toolkit.W[domain.Lifetime]
+	}{
+		{
+			name: Smoke1,
+			args: Smoke1,
+			want: toolkit.Want(domain.Lifetime{TTL: time.Hour, Deadline: now.Add(time.Hour)}, nil),
+		},
+		{name: Smoke2, args: Smoke2, want: toolkit.Want(domain.Lifetime{TTL: 0, Deadline: time.Time{}}, nil)},
+		{name: Smoke3, args: Smoke3, want: toolkit.Want(domain.Lifetime{}, errDummy)},
+		{name: Smoke4, args: "", want: toolkit.Want(domain.Lifetime{}, domain.ErrEmptyKey)},
+	}
+
+	useCase := domain.NewTTLUseCase(expirer{}, mocks.NewClockMock(now))
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := useCase.Execute(context.Background(), test.args)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitExpireUseCase(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		key    string
+		expiry domain.Expiry
+	}
+
+	minute := domain.Expiry{TTL: time.Minute, ExpireAt: time.Time{}, Sliding: false}
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[any]
+	}{
+		{name: Smoke1, args: args{key: Smoke1, expiry: minute}, want: toolkit.Err(nil)},
+		{
+			name: Smoke2,
+			args: args{key: Smoke2, expiry: domain.Expiry{TTL: 0, ExpireAt: now.Add(time.Minute), Sliding: false}},
+			want: toolkit.Err(nil),
+		},
+		{name: Smoke3, args: args{key: Smoke3, expiry: minute}, want: toolkit.Err(errDummy)},
+		{name: Smoke4, args: args{key: "", expiry: minute}, want: toolkit.Err(domain.ErrEmptyKey)},
+		{name: Smoke5, args: args{key: Smoke5, expiry: domain.Expiry{}}, want: toolkit.Err(domain.ErrInvalidExpiry)},
+		{
+			name: Smoke6,
+			args: args{key: Smoke6, expiry: domain.Expiry{TTL: 0, ExpireAt: now, Sliding: false}},
+			want: toolkit.Err(domain.ErrPastDeadline),
+		},
+	}
+
+	useCase := domain.NewExpireUseCase(expirer{}, mocks.NewClockMock(now), policy{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			err := useCase.Execute(context.Background(), test.args.key, test.args.expiry)
+
+			toolkit.Assert(t, toolkit.Got[any](err), test.want)
+		})
+	}
+}
+
+func TestUnitPersistUseCase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want toolkit.W[any]
+	}{
+		{name: Smoke1, args: Smoke1, want: toolkit.Err(nil)},
+		{name: Smoke2, args: "", want: toolkit.Err(domain.ErrEmptyKey)},
+		{name: Smoke3, args: Smoke3, want: toolkit.Err(errDummy)},
+		// the policy gives a default deadline to one key and forbids the other to be infinite
+		{name: Smoke8, args: Smoke8, want: toolkit.Err(domain.ErrInfiniteKey)},
+		{name: Smoke9, args: Smoke9, want: toolkit.Err(domain.ErrInfiniteKey)},
+	}
+
+	useCase := domain.NewPersistUseCase(expirer{}, mocks.NewClockMock(now), policy{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			toolkit.Assert(t, toolkit.Got[any](useCase.Execute(context.Background(), test.args)), test.want)
+		})
+	}
+}
+
 func TestUnitDelUseCase(t *testing.T) {
 	t.Parallel()
 
@@ -196,6 +584,7 @@ func TestUnitDelUseCase(t *testing.T) {
 	}{
 		{name: Smoke1, args: args{key: Smoke1}, want: toolkit.Err(nil)},
 		{name: Smoke2, args: args{key: ""}, want: toolkit.Err(domain.ErrEmptyKey)},
+		{name: "separator", args: args{key: "team" + domain.Separator + "key"}, want: toolkit.Err(domain.ErrInvalidKey)},
 		{name: Smoke3, args: args{key: Smoke3}, want: toolkit.Err(errDummy)},
 	}
 
@@ -212,3 +601,153 @@ func TestUnitDelUseCase(t *testing.T) {
 		})
 	}
 }
+
+func TestUnitFlushUseCase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want toolkit.W[any]
+	}{
+		{name: Smoke1, args: Smoke1, want: toolkit.Err(nil)},
+		{name: Smoke2, args: "", want: toolkit.Err(domain.ErrNoNamespace)},
+		{name: Smoke3, args: Smoke3, want: toolkit.Err(errDummy)},
+	}
+
+	useCase := domain.NewFlushUseCase(flusher{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := domain.WithNamespace(context.Background(), test.args)
+
+			toolkit.Assert(t, toolkit.Got[any](useCase.Execute(ctx)), test.want)
+		})
+	}
+}
+
+func TestUnitWatchUseCase(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		prefix string
+		last   string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[domain.Changes]
+	}{
+		{name: Smoke1, args: args{prefix: Smoke1, last: ""}, want: toolkit.Want[domain.Changes](changes{}, nil)},
+		{name: Smoke2, args: args{prefix: "", last: "7"}, want: toolkit.Want[domain.Changes](changes{}, nil)},
+		{name: Smoke3, args: args{prefix: Smoke3, last: ""}, want: toolkit.Want[domain.Changes](nil, errDummy)},
+		{name: Smoke4, args: args{prefix: "", last: "42"}, want: toolkit.Want[domain.Changes](nil, errDummy)},
+		{
+			name: Smoke5,
+			args: args{prefix: "", last: "invalid"},
+			want: toolkit.Want[domain.Changes](nil, domain.ErrInvalidOffset),
+		},
+		{name: Smoke6, args: args{prefix: "", last: "-1"}, want: toolkit.Want[domain.Changes](nil, domain.ErrInvalidOffset)},
+	}
+
+	useCase := domain.NewWatchUseCase(watcher{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := useCase.Execute(test.args.prefix, test.args.last)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitSubscribeUseCase(t *testing.T) {
+	t.Parallel()
+
+	events := []string{domain.EventDelete, domain.EventExpire}
+	sub := func(link string, prefix string, events ...string) domain.Subscription {
+		return domain.Subscription{ID: "", URL: link, Prefix: prefix, Events: events, Secret: ""}
+	}
+
+	tests := []struct {
+		name string
+		args domain.Subscription
+		want toolkit.W[domain.Subscription]
+	}{
+		{
+			name: Smoke1,
+			args: sub("https://test.loc/hook", Smoke1, events...),
+			want: toolkit.Want(domain.Subscription{
+				ID: "id", URL: "https://test.loc/hook", Prefix: Smoke1, Events: events, Secret: "secret",
+			}, nil),
+		},
+		{name: Smoke2, args: sub("ftp://test.loc", "", events...), want: toolkit.Want(domain.Subscription{}, domain.ErrInvalidURL)},
+		{name: Smoke3, args: sub("http://", "", events...), want: toolkit.Want(domain.Subscription{}, domain.ErrInvalidURL)},
+		{name: Smoke4, args: sub("http://test.loc", ""), want: toolkit.Want(domain.Subscription{}, domain.ErrInvalidEvent)},
+		{
+			name: Smoke5,
+			args: sub("http://test.loc", "", domain.EventExpire, "set"),
+			want: toolkit.Want(domain.Subscription{}, domain.ErrInvalidEvent),
+		},
+		{name: Smoke6, args: sub(":", "", events...), want: toolkit.Want(domain.Subscription{}, domain.ErrInvalidURL)},
+		{name: Smoke7, args: sub("http://test.loc", Smoke7, events...), want: toolkit.Want(domain.Subscription{}, errDummy)},
+	}
+
+	useCase := domain.NewSubscribeUseCase(hooks{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := useCase.Execute(test.args)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitUnsubscribeUseCase(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		args string
+		want toolkit.W[any]
+	}{
+		{name: Smoke1, args: Smoke1, want: toolkit.Err(nil)},
+		{name: Smoke2, args: "", want: toolkit.Err(domain.ErrEmptyKey)},
+		{name: Smoke3, args: Smoke3, want: toolkit.Err(domain.ErrNotSubscribed)},
+	}
+
+	useCase := domain.NewUnsubscribeUseCase(hooks{})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			toolkit.Assert(t, toolkit.Got[any](useCase.Execute(test.args)), test.want)
+		})
+	}
+}
+
+func TestUnitSubscriptionsUseCase(t *testing.T) {
+	t.Parallel()
+
+	got := domain.NewSubscriptionsUseCase(hooks{}).Execute()
+
+	// the secrets are never shown after the subscription
+	assert.Equal(t, []domain.Subscription{{ID: "id", URL: "http://test.loc", Prefix: "", Events: nil, Secret: ""}}, got)
+}
+
+func TestUnitDeadLettersUseCase(t *testing.T) {
+	t.Parallel()
+
+	got := domain.NewDeadLettersUseCase(hooks{}).Execute()
+
+	assert.Equal(t, hooks{}.DeadLetters(), got)
+}
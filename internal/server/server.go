@@ -6,21 +6,36 @@ import (
 	"net/http"
 	"os/signal"
 	"syscall"
+	"time"
 
 	"github.com/labstack/echo/v4"
 	"github.com/labstack/echo/v4/middleware"
 	"github.com/labstack/gommon/log"
+	"github.com/therenotomorrow/apicache/internal/api"
 	apiv1delete "github.com/therenotomorrow/apicache/internal/api/v1/delete"
+	apiv1flush "github.com/therenotomorrow/apicache/internal/api/v1/flush"
 	apiv1get "github.com/therenotomorrow/apicache/internal/api/v1/get"
 	apiv1post "github.com/therenotomorrow/apicache/internal/api/v1/post"
+	apiv1ttl "github.com/therenotomorrow/apicache/internal/api/v1/ttl"
+	apiv1watch "github.com/therenotomorrow/apicache/internal/api/v1/watch"
+	apiv1webhooks "github.com/therenotomorrow/apicache/internal/api/v1/webhooks"
 	"github.com/therenotomorrow/apicache/internal/config"
+	"github.com/therenotomorrow/apicache/internal/domain"
 	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/internal/services/feed"
+	"github.com/therenotomorrow/apicache/internal/services/namespace"
+	"github.com/therenotomorrow/apicache/internal/services/policy"
+	"github.com/therenotomorrow/apicache/internal/services/webhook"
+	"github.com/therenotomorrow/apicache/pkg/clock"
 	"github.com/therenotomorrow/apicache/tools/swagger"
 )
 
 type Server struct {
 	router   *echo.Echo
 	settings *config.Settings
+	cache    *cache.Cache
+	hooks    *webhook.Webhooks
+	spaces   *namespace.Namespaces
 }
 
 func New(settings *config.Settings, cache *cache.Cache) *Server {
@@ -32,14 +47,114 @@ func New(settings *config.Settings, cache *cache.Cache) *Server {
 
 	router.Use(middleware.Logger())
 	router.Use(middleware.Recover())
+	router.Use(api.Client(settings.Server.ClientHeader))
+	router.Use(api.Priority("X-Priority"))
+
+	changes := feed.MustNew(feed.Config{Size: settings.Server.WatchRing})
+	hooks := webhooks(settings)
+	spaces := namespaces(settings, cache, router.Logger)
+	policy := ttlPolicy(settings)
+
+	if cache != nil {
+		changes.Follow(cache)
+		hooks.Follow(cache)
+		spaces.Follow(cache)
+	}
+
+	// the streams never end by themselves, so they are closed before the server waits for them
+	router.Server.RegisterOnShutdown(func() { _ = changes.Close() })
 
-	router.GET("/api/v1/:key/", apiv1get.Get(cache))
-	router.POST("/api/v1/:key/", apiv1post.Post(cache))
+	var loader domain.CacheLoader
+
+	// the origin is the Loader of the cache (see main), the keys of the namespaces are never loaded
+	if settings.Origin.URL != "" {
+		loader = cache
+	}
+
+	router.GET("/api/v1/watch", apiv1watch.Watch(changes))
+	router.GET("/api/v1/:key/", apiv1get.Get(cache, loader))
+	router.POST("/api/v1/:key/", apiv1post.Post(cache, clock.New(), policy))
 	router.DELETE("/api/v1/:key/", apiv1delete.Delete(cache))
+	router.GET("/api/v1/:key/ttl", apiv1ttl.Get(cache, clock.New()))
+	router.PATCH("/api/v1/:key/ttl", apiv1ttl.Patch(cache, clock.New(), policy))
+	router.DELETE("/api/v1/:key/ttl", apiv1ttl.Delete(cache, clock.New(), policy))
+
+	// the same handlers serve the namespaces, the middleware puts the namespace into the context
+	inside := api.Namespace(spaces)
+
+	router.GET("/api/v1/ns/:namespace/:key/", apiv1get.Get(spaces, nil), inside)
+	router.POST("/api/v1/ns/:namespace/:key/", apiv1post.Post(spaces, clock.New(), policy), inside)
+	router.DELETE("/api/v1/ns/:namespace/:key/", apiv1delete.Delete(spaces), inside)
+	router.DELETE("/api/v1/ns/:namespace/", apiv1flush.Flush(spaces), inside)
+
+	// no group here: its catch-all routes would shadow the "admin" key of the cache
+	admin := api.Admin(settings.Server.AdminToken)
+
+	router.POST("/api/v1/admin/webhooks", apiv1webhooks.Create(hooks), admin)
+	router.GET("/api/v1/admin/webhooks", apiv1webhooks.List(hooks), admin)
+	router.DELETE("/api/v1/admin/webhooks/:id", apiv1webhooks.Delete(hooks), admin)
+	router.GET("/api/v1/admin/webhooks/dead", apiv1webhooks.DeadLetters(hooks), admin)
 
 	swagger.Connect(router)
 
-	return &Server{router: router, settings: settings}
+	return &Server{router: router, settings: settings, cache: cache, hooks: hooks, spaces: spaces}
+}
+
+func webhooks(settings *config.Settings) *webhook.Webhooks {
+	subs := make([]domain.Subscription, 0, len(settings.Webhooks.Subscriptions))
+	for _, sub := range settings.Webhooks.Subscriptions {
+		subs = append(subs, domain.Subscription(sub))
+	}
+
+	return webhook.MustNew(webhook.Config{
+		Clock:       nil,
+		Client:      nil,
+		Workers:     settings.Webhooks.Workers,
+		Queue:       0,
+		Retries:     settings.Webhooks.Retries,
+		Backoff:     settings.Webhooks.Backoff,
+		Ceiling:     0,
+		Timeout:     settings.Webhooks.Timeout,
+		DeadLetters: 0,
+	}, subs...)
+}
+
+func namespaces(settings *config.Settings, cache *cache.Cache, logger echo.Logger) *namespace.Namespaces {
+	spaces := make([]namespace.Namespace, 0, len(settings.Namespaces.List))
+	for _, space := range settings.Namespaces.List {
+		spaces = append(spaces, namespace.Namespace{
+			Name:       space.Name,
+			DefaultTTL: time.Duration(space.DefaultTTL),
+			MaxTTL:     time.Duration(space.MaxTTL),
+			MaxKeys:    space.MaxKeys,
+			Share:      space.Share,
+		})
+	}
+
+	return namespace.MustNew(namespace.Config{
+		MaxConn:     settings.Driver.MaxConn,
+		ConnTimeout: settings.Driver.ConnTimeout,
+		Clock:       nil,
+		Failed: func(key string, err error) {
+			logger.Errorf("namespace key %q is left in the store: %v", key, err)
+		},
+		Stateless: settings.Driver.Stateless,
+	}, cache, spaces...)
+}
+
+func ttlPolicy(settings *config.Settings) *policy.Policy {
+	rules := make([]policy.Rule, 0, len(settings.TTL.Rules))
+	for _, rule := range settings.TTL.Rules {
+		rules = append(rules, policy.Rule{
+			Pattern:    rule.Pattern,
+			DefaultTTL: time.Duration(rule.DefaultTTL),
+			MaxTTL:     time.Duration(rule.MaxTTL),
+			Finite:     rule.Finite,
+			Jitter:     rule.Jitter,
+		})
+	}
+
+	return policy.MustNew(rules...)
 }
 
 func (s *Server) UnsafeRouter() *echo.Echo {
@@ -63,7 +178,8 @@ func (s *Server) Serve(ctx context.Context) {
 
 	<-ctx.Done()
 
-	ctx, cancel := context.WithTimeout(ctx, s.settings.Server.ShutdownTimeout)
+	// parent context is already done, but shutdown deserves its own timeout
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), s.settings.Server.ShutdownTimeout)
 	defer cancel()
 
 	if err := s.router.Shutdown(ctx); err != nil {
@@ -71,4 +187,17 @@ func (s *Server) Serve(ctx context.Context) {
 
 		_ = s.router.Close()
 	}
+
+	// the keys the namespaces didn't delete yet are logged, the cache is still open for the ones in progress
+	_ = s.spaces.Close()
+
+	// requests are gone, so cache could drain what left and close the driver
+	if s.cache != nil {
+		if err := s.cache.Shutdown(ctx); err != nil {
+			s.router.Logger.Error(err)
+		}
+	}
+
+	// the last events of the cache are queued already, the undelivered ones become dead letters
+	_ = s.hooks.Close()
 }
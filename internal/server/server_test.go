@@ -4,7 +4,10 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"net/http/httptest"
 	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
@@ -12,6 +15,9 @@ import (
 	"github.com/labstack/gommon/log"
 	"github.com/therenotomorrow/apicache/internal/config"
 	"github.com/therenotomorrow/apicache/internal/server"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
+	"github.com/therenotomorrow/apicache/test/mocks"
 	"github.com/therenotomorrow/apicache/test/toolkit"
 )
 
@@ -59,9 +65,24 @@ func TestUnitNew(t *testing.T) {
 
 			expected := []string{
 				// ---- cache
+				"GET: /api/v1/watch",
 				"GET: /api/v1/:key/",
 				"POST: /api/v1/:key/",
 				"DELETE: /api/v1/:key/",
+				// ---- ttl
+				"GET: /api/v1/:key/ttl",
+				"PATCH: /api/v1/:key/ttl",
+				"DELETE: /api/v1/:key/ttl",
+				// ---- namespaces
+				"GET: /api/v1/ns/:namespace/:key/",
+				"POST: /api/v1/ns/:namespace/:key/",
+				"DELETE: /api/v1/ns/:namespace/:key/",
+				"DELETE: /api/v1/ns/:namespace/",
+				// ---- admin
+				"POST: /api/v1/admin/webhooks",
+				"GET: /api/v1/admin/webhooks",
+				"DELETE: /api/v1/admin/webhooks/:id",
+				"GET: /api/v1/admin/webhooks/dead",
 				// ---- docs
 				"GET: /api/docs/*",
 			}
@@ -74,6 +95,209 @@ func TestUnitNew(t *testing.T) {
 	}
 }
 
+func TestUnitServerAdmin(t *testing.T) {
+	t.Parallel()
+
+	settings := config.MustNew(toolkit.EnvFile())
+	settings.Server.AdminToken = "secret"
+
+	srv := server.New(settings, nil)
+
+	for header, code := range map[string]int{"": http.StatusUnauthorized, "Bearer secret": http.StatusOK} {
+		req := httptest.NewRequest(http.MethodGet, "/api/v1/admin/webhooks", nil)
+		req.Header.Set(echo.HeaderAuthorization, header)
+
+		rec := httptest.NewRecorder()
+
+		srv.UnsafeRouter().ServeHTTP(rec, req)
+
+		toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(code, nil))
+	}
+}
+
+func TestUnitServerNamespaces(t *testing.T) {
+	t.Parallel()
+
+	settings := config.MustNew(toolkit.EnvFile())
+	settings.Namespaces.List = []config.Namespace{{Name: "team", DefaultTTL: 0, MaxTTL: 0, MaxKeys: 0, Share: 0}}
+
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, machine.New())
+	srv := server.New(settings, store)
+
+	steps := []struct {
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{method: http.MethodPost, target: "/api/v1/ns/team/key/", body: `{"val":{"team":true}}`, code: http.StatusCreated},
+		{
+			method: http.MethodPost,
+			target: "/api/v1/ns/team/key/?mode=nx",
+			body:   `{"val":{"team":true}}`,
+			code:   http.StatusConflict,
+		},
+		{method: http.MethodGet, target: "/api/v1/ns/team/key/", body: "", code: http.StatusOK},
+		{method: http.MethodGet, target: "/api/v1/key/", body: "", code: http.StatusNotFound},
+		// the flat API can't address the store key of the namespace
+		{method: http.MethodGet, target: "/api/v1/team%1F0%1Fkey/", body: "", code: http.StatusUnprocessableEntity},
+		{
+			method: http.MethodPost,
+			target: "/api/v1/team%1F0%1Fkey/",
+			body:   `{"val":{"team":false}}`,
+			code:   http.StatusUnprocessableEntity,
+		},
+		{method: http.MethodDelete, target: "/api/v1/team%1F0%1Fkey/", body: "", code: http.StatusUnprocessableEntity},
+		{method: http.MethodGet, target: "/api/v1/team%1F0%1Fkey/ttl", body: "", code: http.StatusUnprocessableEntity},
+		{method: http.MethodGet, target: "/api/v1/ns/team/key/", body: "", code: http.StatusOK},
+		{method: http.MethodGet, target: "/api/v1/ns/other/key/", body: "", code: http.StatusNotFound},
+		{method: http.MethodDelete, target: "/api/v1/ns/other/", body: "", code: http.StatusNotFound},
+		{method: http.MethodDelete, target: "/api/v1/ns/team/", body: "", code: http.StatusNoContent},
+		{method: http.MethodGet, target: "/api/v1/ns/team/key/", body: "", code: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.target, strings.NewReader(step.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+
+		srv.UnsafeRouter().ServeHTTP(rec, req)
+
+		toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(step.code, nil))
+	}
+
+	_ = store.Close()
+}
+
+func TestUnitServerTTLPolicy(t *testing.T) {
+	t.Parallel()
+
+	settings := config.MustNew(toolkit.EnvFile())
+	settings.Namespaces.List = []config.Namespace{{Name: "team", DefaultTTL: 0, MaxTTL: 0, MaxKeys: 0, Share: 0}}
+	settings.TTL.Rules = []config.TTLRule{{Pattern: "lock:*", DefaultTTL: 0, MaxTTL: 0, Finite: true, Jitter: 0}}
+
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, machine.New())
+	srv := server.New(settings, store)
+
+	steps := []struct {
+		target string
+		body   string
+		code   int
+	}{
+		{target: "/api/v1/lock:1/", body: `{"val":{"owner":1}}`, code: http.StatusUnprocessableEntity},
+		{target: "/api/v1/ns/team/lock:1/", body: `{"val":{"owner":1}}`, code: http.StatusUnprocessableEntity},
+		{target: "/api/v1/lock:1/", body: `{"val":{"owner":1},"ttl":"1m"}`, code: http.StatusCreated},
+		{target: "/api/v1/user:1/", body: `{"val":{"owner":1}}`, code: http.StatusCreated},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(http.MethodPost, step.target, strings.NewReader(step.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+
+		srv.UnsafeRouter().ServeHTTP(rec, req)
+
+		toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(step.code, nil))
+	}
+
+	_ = store.Close()
+}
+
+func TestUnitServerTTL(t *testing.T) {
+	t.Parallel()
+
+	settings := config.MustNew(toolkit.EnvFile())
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, machine.New())
+	srv := server.New(settings, store)
+
+	steps := []struct {
+		method string
+		target string
+		body   string
+		code   int
+	}{
+		{method: http.MethodPost, target: "/api/v1/admin/", body: `{"val":{"ttl":true}}`, code: http.StatusCreated},
+		{method: http.MethodPatch, target: "/api/v1/admin/ttl", body: `{"ttl":"1h"}`, code: http.StatusNoContent},
+		{method: http.MethodGet, target: "/api/v1/admin/ttl", body: "", code: http.StatusOK},
+		{method: http.MethodDelete, target: "/api/v1/admin/ttl", body: "", code: http.StatusNoContent},
+		{method: http.MethodGet, target: "/api/v1/admin/", body: "", code: http.StatusOK},
+		{method: http.MethodGet, target: "/api/v1/other/ttl", body: "", code: http.StatusNotFound},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, step.target, strings.NewReader(step.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		rec := httptest.NewRecorder()
+
+		srv.UnsafeRouter().ServeHTTP(rec, req)
+
+		toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(step.code, nil))
+	}
+
+	_ = store.Close()
+}
+
+func TestUnitServerVersion(t *testing.T) {
+	t.Parallel()
+
+	settings := config.MustNew(toolkit.EnvFile())
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, machine.New())
+	srv := server.New(settings, store)
+
+	steps := []struct {
+		method string
+		header string
+		tag    string
+		body   string
+		code   int
+		etag   string
+	}{
+		{method: http.MethodPost, header: "", tag: "", body: `{"val":{"v":1}}`, code: http.StatusCreated, etag: ""},
+		{method: http.MethodGet, header: "", tag: "", body: "", code: http.StatusOK, etag: `"1"`},
+		{method: http.MethodGet, header: "If-None-Match", tag: `"1"`, body: "", code: http.StatusNotModified, etag: `"1"`},
+		{
+			method: http.MethodPost,
+			header: "If-Match",
+			tag:    `"1"`,
+			body:   `{"val":{"v":2}}`,
+			code:   http.StatusCreated,
+			etag:   "",
+		},
+		{
+			method: http.MethodPost,
+			header: "If-Match",
+			tag:    `"1"`,
+			body:   `{"val":{"v":3}}`,
+			code:   http.StatusPreconditionFailed,
+			etag:   "",
+		},
+		{method: http.MethodGet, header: "If-None-Match", tag: `"1"`, body: "", code: http.StatusOK, etag: `"2"`},
+		{method: http.MethodDelete, header: "If-Match", tag: `"1"`, body: "", code: http.StatusPreconditionFailed, etag: ""},
+		{method: http.MethodDelete, header: "If-Match", tag: `"2"`, body: "", code: http.StatusNoContent, etag: ""},
+	}
+
+	for _, step := range steps {
+		req := httptest.NewRequest(step.method, "/api/v1/config/", strings.NewReader(step.body))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+
+		if step.header != "" {
+			req.Header.Set(step.header, step.tag)
+		}
+
+		rec := httptest.NewRecorder()
+
+		srv.UnsafeRouter().ServeHTTP(rec, req)
+
+		toolkit.Assert(t, toolkit.Got(nil, rec.Code), toolkit.Want(step.code, nil))
+		toolkit.Assert(t, toolkit.Got(nil, rec.Header().Get("ETag")), toolkit.Want(step.etag, nil))
+	}
+
+	_ = store.Close()
+}
+
 func TestUnitServerServeStart(t *testing.T) {
 	t.Parallel()
 
@@ -85,6 +309,27 @@ func TestUnitServerServeStart(t *testing.T) {
 	srv.Serve(context.TODO())
 }
 
+func TestUnitServerServeCloseCache(t *testing.T) {
+	t.Parallel()
+
+	closed := atomic.Bool{}
+	driver := mocks.NewDriverMock()
+	driver.CloseMock = func() error {
+		closed.Store(true)
+
+		return nil
+	}
+
+	settings := config.MustNew(toolkit.EnvFile())
+	settings.Server.Address = "invalid"
+
+	srv := server.New(settings, cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, driver))
+
+	srv.Serve(context.TODO())
+
+	toolkit.Assert(t, toolkit.Got(nil, closed.Load()), toolkit.Want(true, nil))
+}
+
 func TestUnitServerServeShutdown(t *testing.T) {
 	t.Parallel()
 
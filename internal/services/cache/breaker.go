@@ -0,0 +1,134 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/clock"
+)
+
+type (
+	circuit int
+	// breaker stops the calls to the failing driver: after threshold of consecutive failures
+	// the circuit opens and calls fail fast, after the interval the single probe call is let
+	// through (half-open), its success closes the circuit and its failure opens it again.
+	// The calls that were let in before the circuit opened decide nothing when they are done.
+	breaker struct {
+		mutex     sync.Mutex
+		clock     clock.Clock
+		threshold int
+		interval  time.Duration
+		state     circuit
+		failures  int
+		until     time.Time
+		probing   bool
+	}
+	probeKey struct{}
+)
+
+const (
+	closed circuit = iota
+	open
+	halfOpen
+)
+
+func newBreaker(threshold int, interval time.Duration, clock clock.Clock) *breaker {
+	return &breaker{
+		mutex:     sync.Mutex{},
+		clock:     clock,
+		threshold: threshold,
+		interval:  interval,
+		state:     closed,
+		failures:  0,
+		until:     time.Time{},
+		probing:   false,
+	}
+}
+
+// allow tells whether the call could go to the driver, probe means that the call decides the state.
+func (b *breaker) allow() (bool, error) {
+	if b.threshold == 0 {
+		return false, nil
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	now := b.clock.Now()
+
+	if b.state == open && !now.Before(b.until) {
+		b.state = halfOpen
+	}
+
+	switch {
+	case b.state == closed:
+		return false, nil
+	case b.state == halfOpen && !b.probing:
+		b.probing = true
+
+		return true, nil
+	case b.state == halfOpen:
+		// the probe is in flight, so the rest waits for its result
+		return false, &domain.RetryError{Err: domain.ErrCircuitOpen, After: b.interval}
+	default:
+		return false, &domain.RetryError{Err: domain.ErrCircuitOpen, After: b.until.Sub(now)}
+	}
+}
+
+// done lets the next probe go when this one didn't reach the driver.
+func (b *breaker) done(probe bool) {
+	if !probe {
+		return
+	}
+
+	b.mutex.Lock()
+	b.probing = false
+	b.mutex.Unlock()
+}
+
+// report counts the result of the driver call, only the probe one is counted while the circuit isn't closed.
+func (b *breaker) report(probe, failed bool) {
+	if b.threshold == 0 {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.state != closed && (!probe || b.state == open) {
+		return
+	}
+
+	if !failed {
+		b.state = closed
+		b.failures = 0
+		b.probing = false
+
+		return
+	}
+
+	b.failures++
+
+	if b.state == halfOpen || b.failures >= b.threshold {
+		b.state = open
+		b.until = b.clock.Now().Add(b.interval)
+		b.probing = false
+	}
+}
+
+// withProbe marks the driver calls of the probe, so the breaker tells them from the late ones.
+func withProbe(ctx context.Context, probe bool) context.Context {
+	if !probe {
+		return ctx
+	}
+
+	return context.WithValue(ctx, probeKey{}, true)
+}
+
+func probing(ctx context.Context) bool {
+	probe, _ := ctx.Value(probeKey{}).(bool)
+
+	return probe
+}
@@ -6,21 +6,54 @@ import (
 	"fmt"
 	"io"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/clock"
 	"github.com/therenotomorrow/apicache/pkg/drivers"
+	"github.com/therenotomorrow/apicache/pkg/eviction"
 )
 
 const (
-	pingWindow     = 10
-	defaultMaxConn = 1
-	defaultTimeout = time.Millisecond
+	defaultMaxConn       = 1
+	defaultTimeout       = time.Millisecond
+	defaultExpiryWorkers = 4
+	defaultDrainTimeout  = time.Second
+	defaultLatency       = 10 * time.Millisecond
+	defaultShedInterval  = 100 * time.Millisecond
+	defaultBreakInterval = time.Second
+	defaultRetryBackoff  = 10 * time.Millisecond
+	defaultRetryCeiling  = time.Second
+	defaultEvents        = 1024
 )
 
 var (
-	ErrInvalidMaxConn     = errors.New("invalid MaxConn")
-	ErrInvalidConnTimeout = errors.New("invalid ConnTimeout")
+	ErrInvalidMaxConn       = errors.New("invalid MaxConn")
+	ErrInvalidConnTimeout   = errors.New("invalid ConnTimeout")
+	ErrInvalidExpiryWorkers = errors.New("invalid ExpiryWorkers")
+	ErrInvalidStateless     = errors.New("invalid Stateless")
+	ErrInvalidDrainTimeout  = errors.New("invalid DrainTimeout")
+	ErrInvalidMaxKeys       = errors.New("invalid MaxKeys")
+	ErrInvalidMaxBytes      = errors.New("invalid MaxBytes")
+	ErrInvalidMinConn       = errors.New("invalid MinConn")
+	ErrInvalidTargetLatency = errors.New("invalid TargetLatency")
+	ErrInvalidWriteMaxConn  = errors.New("invalid WriteMaxConn")
+	ErrInvalidWriteMinConn  = errors.New("invalid WriteMinConn")
+	ErrInvalidWriteTimeout  = errors.New("invalid WriteTimeout")
+	ErrInvalidWeights       = errors.New("invalid Weights")
+	ErrInvalidClientShare   = errors.New("invalid ClientShare")
+	ErrInvalidShedTarget    = errors.New("invalid ShedTarget")
+	ErrInvalidShedInterval  = errors.New("invalid ShedInterval")
+	ErrInvalidBreakAfter    = errors.New("invalid BreakAfter")
+	ErrInvalidBreakInterval = errors.New("invalid BreakInterval")
+	ErrInvalidRetries       = errors.New("invalid Retries")
+	ErrInvalidRetryBackoff  = errors.New("invalid RetryBackoff")
+	ErrInvalidRetryCeiling  = errors.New("invalid RetryCeiling")
+	ErrInvalidStaleGrace    = errors.New("invalid StaleGrace")
+	ErrInvalidEvents        = errors.New("invalid Events")
+	ErrInvalidOverflow      = errors.New("invalid Overflow")
+	ErrDrainTimeout         = errors.New("drain timeout")
 )
 
 type (
@@ -30,17 +63,151 @@ type (
 		Del(ctx context.Context, key string) error
 		io.Closer
 	}
+	// ExpiringDriver is a Driver that is able to expire keys by itself.
+	ExpiringDriver interface {
+		Driver
+		SetEx(ctx context.Context, key string, val string, ttl time.Duration) error
+		// TTL returns zero for the keys without expiration.
+		TTL(ctx context.Context, key string) (time.Duration, error)
+		// Expire moves the expiration of the key in place, the non-positive TTL deletes the key.
+		Expire(ctx context.Context, key string, ttl time.Duration) error
+		// Persist drops the expiration of the key in place.
+		Persist(ctx context.Context, key string) error
+	}
+	// ConditionalDriver is an ExpiringDriver that checks the existence of the key along with the write,
+	// so the replicas of Stateless cache sharing it don't race each other. False means the condition
+	// doesn't hold and nothing was written, zero TTL means infinite key.
+	ConditionalDriver interface {
+		ExpiringDriver
+		// SetNX writes the key only if it doesn't exist yet.
+		SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error)
+		// SetXX writes the key only if it exists already.
+		SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error)
+		// DelXX deletes the key and tells whether it existed.
+		DelXX(ctx context.Context, key string) (bool, error)
+	}
+	// Loader is called on a miss in GetOrLoad, zero TTL means infinite key.
+	Loader interface {
+		Load(ctx context.Context, key string) ([]byte, time.Duration, error)
+	}
 	Config struct {
-		MaxConn     int
-		ConnTimeout time.Duration
+		// MaxConn is the ceiling of the concurrency limit.
+		MaxConn int
+		// MinConn is the floor of the concurrency limit, zero means fixed limit of MaxConn.
+		// Otherwise the limit adapts to the driver: it grows while calls are fast and
+		// successful, and shrinks when they are slower than TargetLatency or fail.
+		MinConn int
+		// TargetLatency is the driver latency that is considered healthy, zero means default.
+		TargetLatency time.Duration
+		ConnTimeout   time.Duration
+		// WriteMaxConn gives Set and Del their own pool, so writes don't starve reads and vice versa.
+		// Zero means that writes share the pool of MaxConn with reads. WriteMinConn and WriteTimeout
+		// are the same as MinConn and ConnTimeout for the write pool, zero means fixed limit and ConnTimeout.
+		WriteMaxConn int
+		WriteMinConn int
+		WriteTimeout time.Duration
+		// Borrow lets the pool take the idle permits of the other one.
+		Borrow bool
+		// Weights of the clients identified by domain.WithClient, one by default.
+		// Waiting clients are served in proportion to their weights.
+		Weights map[string]int
+		// ClientShare is a part of the pool the client of weight one could hold at once,
+		// zero means no bound. The heavier clients get proportionally more.
+		ClientShare float64
+		// ShedTarget is the acceptable time to wait for admission, zero means no shedding.
+		// When the waiting stays above it for the ShedInterval (zero means default), calls of
+		// domain.PriorityLow fail fast with domain.ErrShed until the queue is drained.
+		ShedTarget   time.Duration
+		ShedInterval time.Duration
+		// BreakAfter is a number of consecutive driver failures that opens the circuit, zero means
+		// no breaker. The open circuit fails calls fast with domain.ErrCircuitOpen, and after the
+		// BreakInterval (zero means default) the single probe call decides whether to close it.
+		BreakAfter    int
+		BreakInterval time.Duration
+		// Retries is a number of times the driver call failed with drivers.ErrTransient is repeated,
+		// zero means no retries. The pause starts at RetryBackoff and doubles up to RetryCeiling
+		// (zero means defaults), the retries stop at the deadline of the context.
+		Retries      int
+		RetryBackoff time.Duration
+		RetryCeiling time.Duration
+		// ExpiryWorkers is a number of goroutines that remove expired keys, zero means default.
+		ExpiryWorkers int
+		// Stateless trusts the ExpiringDriver both existence and TTL of the keys,
+		// so nothing is kept in the process and replicas could share the driver.
+		Stateless bool
+		// DrainTimeout limits how long Close waits for in-flight operations, zero means default.
+		DrainTimeout time.Duration
+		// Clock is a source of time for deadlines and timeouts, nil means the real one.
+		Clock clock.Clock
+		// Loader fills the missed keys in GetOrLoad, nil means nothing to load.
+		Loader Loader
+		// MaxKeys and MaxBytes bound the cache, zero means unbounded. Bytes are counted for both keys
+		// and values, including the copies of StaleGrace. Stateless cache leaves the bounds to the driver.
+		MaxKeys  int
+		MaxBytes int
+		// Policy chooses the keys to evict when the cache is full, nil means LRU.
+		Policy eviction.Policy
+		// StaleGrace lets Get serve the last known value along with domain.ErrStale, when the key has
+		// expired not longer than StaleGrace ago or the driver fails. The Loader refreshes such keys
+		// in background. Zero means no stale values. The values are copied into the process, so
+		// Stateless cache can't serve them. The copy counts in MaxBytes and is evicted with its key,
+		// the copy of the expired key is left only for the grace window.
+		StaleGrace time.Duration
+		// Events is a size of the buffer of the events for the listeners, zero means default.
+		// Overflow decides what to do when the listeners can't keep up, OverflowDropNewest by default.
+		Events   int
+		Overflow Overflow
+	}
+	// Stats is a snapshot of the cache counters, evictions are not counted as expirations.
+	Stats struct {
+		// ReadLimit and WriteLimit are the current concurrency limits of the pools.
+		ReadLimit   int
+		WriteLimit  int
+		Keys        int
+		Bytes       int
+		Expirations uint64
+		Evictions   uint64
+		// Dropped is a number of events lost by overflow.
+		Dropped uint64
+	}
+	// entry is a state of the key in the index.
+	entry struct {
+		deadline time.Time
+		// ttl of the sliding key, every read moves the deadline that far from now. Zero means absolute deadline.
+		ttl    time.Duration
+		size   int
+		pinned bool
+		// version grows with every write of the key, the moves of the deadline keep it.
+		version uint64
 	}
 	Cache struct {
-		driver Driver
-		cfg    Config
-		once   sync.Once
-		keys   sync.Map
-		done   chan struct{}
-		queue  chan struct{}
+		driver      Driver
+		native      ExpiringDriver
+		conditional ConditionalDriver
+		expiry      *expiry
+		grace       *expiry
+		events      *events
+		lasts       sync.Map
+		loads       *flight
+		policy      eviction.Policy
+		limiter     *limiter
+		breaker     *breaker
+		reads       *pool
+		writes      *pool
+		cfg         Config
+		once        sync.Once
+		locks       locks
+		keys        sync.Map
+		usage       sync.Mutex
+		count       int
+		bytes       int
+		expirations atomic.Uint64
+		evictions   atomic.Uint64
+		versions    atomic.Uint64
+		state       sync.RWMutex
+		closed      bool
+		flight      sync.WaitGroup
+		done        chan struct{}
 	}
 )
 
@@ -49,18 +216,243 @@ func New(cfg Config, driver Driver) (*Cache, error) {
 		return nil, ErrInvalidMaxConn
 	}
 
+	if cfg.MinConn < 0 || cfg.MinConn > cfg.MaxConn {
+		return nil, ErrInvalidMinConn
+	}
+
+	if cfg.MinConn == 0 {
+		cfg.MinConn = cfg.MaxConn
+	}
+
+	if cfg.TargetLatency < 0 {
+		return nil, ErrInvalidTargetLatency
+	}
+
+	if cfg.TargetLatency == 0 {
+		cfg.TargetLatency = defaultLatency
+	}
+
 	if cfg.ConnTimeout < defaultTimeout {
 		return nil, ErrInvalidConnTimeout
 	}
 
-	return &Cache{
-		driver: driver,
-		cfg:    cfg,
-		once:   sync.Once{},
-		keys:   sync.Map{},
-		done:   make(chan struct{}),
-		queue:  make(chan struct{}, cfg.MaxConn),
-	}, nil
+	if cfg.WriteMaxConn < 0 {
+		return nil, ErrInvalidWriteMaxConn
+	}
+
+	if cfg.WriteMinConn < 0 || cfg.WriteMinConn > cfg.WriteMaxConn {
+		return nil, ErrInvalidWriteMinConn
+	}
+
+	if cfg.WriteMinConn == 0 {
+		cfg.WriteMinConn = cfg.WriteMaxConn
+	}
+
+	if cfg.WriteTimeout != 0 && cfg.WriteTimeout < defaultTimeout {
+		return nil, ErrInvalidWriteTimeout
+	}
+
+	if cfg.WriteTimeout == 0 {
+		cfg.WriteTimeout = cfg.ConnTimeout
+	}
+
+	for _, weight := range cfg.Weights {
+		if weight <= 0 {
+			return nil, ErrInvalidWeights
+		}
+	}
+
+	if cfg.ClientShare < 0 || cfg.ClientShare > 1 {
+		return nil, ErrInvalidClientShare
+	}
+
+	if cfg.ShedTarget < 0 {
+		return nil, ErrInvalidShedTarget
+	}
+
+	if cfg.ShedInterval < 0 {
+		return nil, ErrInvalidShedInterval
+	}
+
+	if cfg.ShedInterval == 0 {
+		cfg.ShedInterval = defaultShedInterval
+	}
+
+	if cfg.BreakAfter < 0 {
+		return nil, ErrInvalidBreakAfter
+	}
+
+	if cfg.BreakInterval < 0 {
+		return nil, ErrInvalidBreakInterval
+	}
+
+	if cfg.BreakInterval == 0 {
+		cfg.BreakInterval = defaultBreakInterval
+	}
+
+	if cfg.Retries < 0 {
+		return nil, ErrInvalidRetries
+	}
+
+	if cfg.RetryBackoff < 0 {
+		return nil, ErrInvalidRetryBackoff
+	}
+
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = defaultRetryBackoff
+	}
+
+	if cfg.RetryCeiling == 0 {
+		cfg.RetryCeiling = max(defaultRetryCeiling, cfg.RetryBackoff)
+	}
+
+	if cfg.RetryCeiling < cfg.RetryBackoff {
+		return nil, ErrInvalidRetryCeiling
+	}
+
+	if cfg.ExpiryWorkers < 0 {
+		return nil, ErrInvalidExpiryWorkers
+	}
+
+	if cfg.ExpiryWorkers == 0 {
+		cfg.ExpiryWorkers = defaultExpiryWorkers
+	}
+
+	if cfg.DrainTimeout < 0 {
+		return nil, ErrInvalidDrainTimeout
+	}
+
+	if cfg.DrainTimeout == 0 {
+		cfg.DrainTimeout = defaultDrainTimeout
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New()
+	}
+
+	if cfg.MaxKeys < 0 {
+		return nil, ErrInvalidMaxKeys
+	}
+
+	if cfg.MaxBytes < 0 {
+		return nil, ErrInvalidMaxBytes
+	}
+
+	bounded := cfg.MaxKeys > 0 || cfg.MaxBytes > 0
+
+	if bounded && cfg.Policy == nil {
+		cfg.Policy = eviction.NewLRU()
+	}
+
+	if cfg.Events < 0 {
+		return nil, ErrInvalidEvents
+	}
+
+	if cfg.Events == 0 {
+		cfg.Events = defaultEvents
+	}
+
+	if cfg.Overflow < OverflowDropNewest || cfg.Overflow > OverflowBlock {
+		return nil, ErrInvalidOverflow
+	}
+
+	if cfg.StaleGrace < 0 {
+		return nil, ErrInvalidStaleGrace
+	}
+
+	native, ok := driver.(ExpiringDriver)
+	if cfg.Stateless && (!ok || bounded || cfg.StaleGrace > 0) {
+		return nil, ErrInvalidStateless
+	}
+
+	pools := []*pool{newPool(cfg.MinConn, cfg.MaxConn, cfg.ConnTimeout, nil)}
+
+	if cfg.WriteMaxConn > 0 {
+		pools = []*pool{
+			newPool(cfg.MinConn, cfg.MaxConn, cfg.ConnTimeout, domain.ErrReadPoolExhausted),
+			newPool(cfg.WriteMinConn, cfg.WriteMaxConn, cfg.WriteTimeout, domain.ErrWritePoolExhausted),
+		}
+	}
+
+	limiter := newLimiter(cfg, pools...)
+	breaker := newBreaker(cfg.BreakAfter, cfg.BreakInterval, cfg.Clock)
+	conditional, supported := driver.(ConditionalDriver)
+
+	observer := &observer{
+		driver:      driver,
+		native:      native,
+		conditional: conditional,
+		limiter:     limiter,
+		breaker:     breaker,
+		clock:       cfg.Clock,
+	}
+
+	retrier := &retrier{
+		driver:      observer,
+		native:      observer,
+		conditional: observer,
+		clock:       cfg.Clock,
+		retries:     cfg.Retries,
+		backoff:     cfg.RetryBackoff,
+		ceiling:     cfg.RetryCeiling,
+	}
+
+	// the driver is observed by the limiter and the breaker from now on, every attempt counts
+	driver, native, conditional = retrier, nil, nil
+
+	if cfg.Stateless {
+		native = retrier
+	}
+
+	// the cache that is not Stateless checks the conditions by itself under the key's lock
+	if cfg.Stateless && supported {
+		conditional = retrier
+	}
+
+	var policy eviction.Policy
+
+	if bounded {
+		policy = cfg.Policy
+	}
+
+	cache := &Cache{
+		driver:      driver,
+		native:      native,
+		conditional: conditional,
+		expiry:      nil,
+		grace:       nil,
+		events:      newEvents(cfg.Events, cfg.Overflow),
+		lasts:       sync.Map{},
+		loads:       newFlight(),
+		policy:      policy,
+		limiter:     limiter,
+		breaker:     breaker,
+		reads:       pools[0],
+		writes:      pools[len(pools)-1],
+		cfg:         cfg,
+		once:        sync.Once{},
+		locks:       locks{},
+		keys:        sync.Map{},
+		usage:       sync.Mutex{},
+		count:       0,
+		bytes:       0,
+		expirations: atomic.Uint64{},
+		evictions:   atomic.Uint64{},
+		state:       sync.RWMutex{},
+		closed:      false,
+		flight:      sync.WaitGroup{},
+		done:        make(chan struct{}),
+	}
+
+	if !cfg.Stateless {
+		cache.expiry = newExpiry(cfg.ExpiryWorkers, cfg.Clock, cache.expire)
+	}
+
+	if cfg.StaleGrace > 0 {
+		cache.grace = newExpiry(1, cfg.Clock, cache.lapse)
+	}
+
+	return cache, nil
 }
 
 func MustNew(cfg Config, driver Driver) *Cache {
@@ -72,27 +464,372 @@ func MustNew(cfg Config, driver Driver) *Cache {
 	return obj
 }
 
+// Get reads the key. With StaleGrace the last known value could be returned along with domain.ErrStale.
 func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
-	err := c.acquire(ctx)
+	val, _, err := c.GetVersion(ctx, key)
+
+	return val, err
+}
+
+// GetVersion reads the key along with its version, the versions are taken from the single counter of the cache,
+// so they never repeat for the key, even after it was deleted. Zero version means unknown one: the stale value
+// and all the keys of Stateless cache that has no index to keep the versions in.
+func (c *Cache) GetVersion(ctx context.Context, key string) ([]byte, uint64, error) {
+	val, version, err := c.read(ctx, key)
+	if err == nil {
+		return val, version, nil
+	}
+
+	val, ok := c.stale(key, err)
+	if !ok {
+		return nil, 0, err
+	}
+
+	c.refresh(ctx, key)
+
+	return val, 0, domain.ErrStale
+}
+
+func (c *Cache) read(ctx context.Context, key string) ([]byte, uint64, error) {
+	tick, ctx, err := c.acquire(ctx, c.reads)
 	if err != nil {
-		return nil, err
+		return nil, 0, err
 	}
-	defer c.release()
+	defer c.release(tick)
 
-	now := time.Now().UTC()
+	val, ent, err := c.lookup(ctx, key)
+	if err != nil {
+		return nil, 0, err
+	}
 
-	deadline, ok := c.keys.Load(key)
+	// the deadline is moved under the write lock, so the readers of the other keys are not held
+	if ent.ttl > 0 {
+		c.slide(key)
+	}
+
+	return val, ent.version, nil
+}
+
+// lookup reads the key under the read lock, the entry is empty in Stateless mode.
+func (c *Cache) lookup(ctx context.Context, key string) ([]byte, entry, error) {
+	lock := c.locks.of(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	if c.native != nil {
+		val, err := c.get(ctx, key)
+
+		return val, entry{}, err
+	}
+
+	now := c.cfg.Clock.Now()
+
+	ent, ok := c.entry(key)
 	if !ok {
-		return nil, domain.ErrKeyNotExist
+		return nil, entry{}, domain.ErrKeyNotExist
 	}
 
 	// don't allow read expired keys, GC will remove it
-	future, _ := deadline.(time.Time)
-	if !future.IsZero() && now.After(future) {
-		return nil, domain.ErrKeyExpired
+	if !ent.deadline.IsZero() && !now.Before(ent.deadline) {
+		return nil, entry{}, domain.ErrKeyExpired
+	}
+
+	// we assume that external driver also will not contain key because of `expire()`
+	val, err := c.get(ctx, key)
+	if err != nil {
+		return nil, entry{}, err
+	}
+
+	c.touch(key)
+
+	return val, ent, nil
+}
+
+// GetOrLoad reads the key and fills it by the Loader on a miss. Concurrent misses of the same key
+// wait for a single load, so expiration of the hot key doesn't cause a thundering herd.
+func (c *Cache) GetOrLoad(ctx context.Context, key string) ([]byte, error) {
+	val, err := c.Get(ctx, key)
+	if !errors.Is(err, domain.ErrKeyNotExist) && !errors.Is(err, domain.ErrKeyExpired) {
+		return val, err
+	}
+
+	if c.cfg.Loader == nil {
+		return nil, err
+	}
+
+	return c.loads.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+		return c.load(ctx, key)
+	})
+}
+
+// Set writes the key, zero deadline means infinite key. The key written with domain.WithSliding
+// gets its TTL back on every read, Stateless cache has no index to keep the TTL in, so it fails with
+// domain.ErrUnsupported.
+// The write with domain.WithCondition checks the existence of the key under the same lock, so it fails with
+// domain.ErrKeyExists or domain.ErrKeyNotExist. The replicas of Stateless cache don't share the lock, so the driver
+// checks the condition (see ConditionalDriver) and the conditional write fails with domain.ErrUnsupported without it.
+// The write with domain.WithVersion is the compare-and-swap, it fails with domain.ErrVersionMismatch when the
+// version of the key is another one. Stateless cache knows no versions, so the swap fails with domain.ErrUnsupported.
+func (c *Cache) Set(ctx context.Context, key string, val []byte, deadline time.Time) error {
+	tick, ctx, err := c.acquire(ctx, c.writes)
+	if err != nil {
+		return err
+	}
+	defer c.release(tick)
+
+	old, err := c.set(ctx, key, val, deadline)
+	if err != nil {
+		return err
+	}
+
+	c.emit(key, ReasonSet, old.deadline)
+
+	// the key's lock is released, so eviction is free to take locks of the victims
+	c.shrink(ctx)
+
+	return nil
+}
+
+// Del deletes the key, domain.WithCondition and domain.WithVersion are checked the same way as in Set.
+func (c *Cache) Del(ctx context.Context, key string) error {
+	tick, ctx, err := c.acquire(ctx, c.writes)
+	if err != nil {
+		return err
+	}
+	defer c.release(tick)
+
+	old, ok, err := c.del(ctx, key)
+	if err != nil {
+		return err
+	}
+
+	if ok {
+		c.emit(key, ReasonDelete, old.deadline)
+	}
+
+	return nil
+}
+
+// OnSet registers the listener of the written keys.
+func (c *Cache) OnSet(listener func(Event)) {
+	c.events.listen(ReasonSet, listener)
+}
+
+// OnDelete registers the listener of the deleted keys, every Del is reported in Stateless mode.
+func (c *Cache) OnDelete(listener func(Event)) {
+	c.events.listen(ReasonDelete, listener)
+}
+
+// OnExpire registers the listener of the keys removed by GC, the driver expires them silently in Stateless mode.
+func (c *Cache) OnExpire(listener func(Event)) {
+	c.events.listen(ReasonExpire, listener)
+}
+
+// OnEvict registers the listener of the keys evicted from the bounded cache.
+func (c *Cache) OnEvict(listener func(Event)) {
+	c.events.listen(ReasonEvict, listener)
+}
+
+// Pin protects the key from eviction, it still could expire or be deleted. Keys are
+// not evictable in Stateless mode at all, so pinning does nothing there.
+func (c *Cache) Pin(ctx context.Context, key string) error {
+	return c.pin(ctx, key, true)
+}
+
+// Unpin makes the key evictable again.
+func (c *Cache) Unpin(ctx context.Context, key string) error {
+	err := c.pin(ctx, key, false)
+	if err != nil {
+		return err
+	}
+
+	c.shrink(ctx)
+
+	return nil
+}
+
+// Deadline returns the moment when the key will expire, zero time means infinite key.
+func (c *Cache) Deadline(ctx context.Context, key string) (time.Time, error) {
+	tick, ctx, err := c.acquire(ctx, c.reads)
+	if err != nil {
+		return time.Time{}, err
+	}
+	defer c.release(tick)
+
+	lock := c.locks.of(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	now := c.cfg.Clock.Now()
+
+	if c.native != nil {
+		return c.deadline(ctx, key, now)
+	}
+
+	ent, ok := c.entry(key)
+	if !ok {
+		return time.Time{}, domain.ErrKeyNotExist
+	}
+
+	if !ent.deadline.IsZero() && !now.Before(ent.deadline) {
+		return time.Time{}, domain.ErrKeyExpired
+	}
+
+	return ent.deadline, nil
+}
+
+// Expire moves the deadline of the key without touching its value, zero deadline makes the key infinite.
+// The sliding key keeps sliding by the TTL left to the new deadline. The driver of Stateless cache moves
+// the deadline by itself.
+func (c *Cache) Expire(ctx context.Context, key string, deadline time.Time) error {
+	tick, ctx, err := c.acquire(ctx, c.writes)
+	if err != nil {
+		return err
+	}
+	defer c.release(tick)
+
+	return c.retime(ctx, key, deadline)
+}
+
+// Persist makes the key infinite, it's the Expire with zero deadline.
+func (c *Cache) Persist(ctx context.Context, key string) error {
+	return c.Expire(ctx, key, time.Time{})
+}
+
+func (c *Cache) Stats() Stats {
+	c.usage.Lock()
+	defer c.usage.Unlock()
+
+	return Stats{
+		ReadLimit:   c.limiter.current(c.reads),
+		WriteLimit:  c.limiter.current(c.writes),
+		Keys:        c.count,
+		Bytes:       c.bytes,
+		Expirations: c.expirations.Load(),
+		Evictions:   c.evictions.Load(),
+		Dropped:     c.events.dropped.Load(),
+	}
+}
+
+// Close is a Shutdown limited by the DrainTimeout.
+func (c *Cache) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), c.cfg.DrainTimeout)
+	defer cancel()
+
+	return c.Shutdown(ctx)
+}
+
+// Shutdown refuses new operations, waits for in-flight ones until context is done,
+// stops the GC and only then closes the driver. Calling it twice is safely do nothing.
+func (c *Cache) Shutdown(ctx context.Context) error {
+	var err error
+
+	c.once.Do(func() {
+		c.state.Lock()
+		c.closed = true
+		// wakes up everyone who waits for the queue
+		close(c.done)
+		c.state.Unlock()
+
+		drained := make(chan struct{})
+
+		go func() {
+			c.flight.Wait()
+			close(drained)
+		}()
+
+		select {
+		case <-drained:
+		case <-ctx.Done():
+			err = ErrDrainTimeout
+		}
+
+		if c.expiry != nil {
+			c.expiry.stop()
+		}
+
+		if c.grace != nil {
+			c.grace.stop()
+		}
+
+		// GC is stopped, so nobody emits the events anymore
+		c.events.stop()
+
+		errClose := c.driver.Close()
+		if errClose != nil {
+			err = errors.Join(err, fmt.Errorf("driver error: %w", errClose))
+		}
+	})
+
+	return err
+}
+
+// acquire takes the permit of the pool, the returned context marks the driver calls of the breaker probe.
+func (c *Cache) acquire(ctx context.Context, pool *pool) (*ticket, context.Context, error) {
+	// the state lock guarantees that nobody joins the flight after Shutdown started to wait for it
+	c.state.RLock()
+
+	if c.closed {
+		c.state.RUnlock()
+
+		return nil, ctx, domain.ErrClosed
+	}
+
+	// the open circuit fails fast, so the calls don't hold the permits until the timeout
+	probe, err := c.breaker.allow()
+	if err != nil {
+		c.state.RUnlock()
+
+		return nil, ctx, err
+	}
+
+	c.flight.Add(1)
+	c.state.RUnlock()
+
+	timer := c.cfg.Clock.Timer(c.cfg.Clock.Now().Add(pool.timeout))
+	defer timer.Stop()
+
+	tick := c.limiter.wait(pool, domain.ClientFrom(ctx), domain.PriorityFrom(ctx))
+	tick.probe = probe
+
+	select {
+	case <-tick.ready:
+		if !tick.shed {
+			return tick, withProbe(ctx, probe), nil
+		}
+
+		err = domain.ErrShed
+	case <-c.done:
+		err = domain.ErrClosed
+	case <-timer.C():
+		err = exhausted(pool, domain.ErrConnTimeout)
+	case <-ctx.Done():
+		err = exhausted(pool, domain.ErrContextTimeout)
 	}
 
-	// we assume that external driver also will not contain key because of `followEx()`
+	c.limiter.cancel(tick)
+	c.breaker.done(probe)
+	c.flight.Done()
+
+	return nil, ctx, err
+}
+
+func (c *Cache) release(tick *ticket) {
+	c.limiter.release(tick)
+	c.breaker.done(tick.probe)
+	c.flight.Done()
+}
+
+// exhausted tells which pool had no permits, the shared pool doesn't need to be named.
+func exhausted(pool *pool, err error) error {
+	if pool.err == nil {
+		return err
+	}
+
+	return fmt.Errorf("%w: %w", pool.err, err)
+}
+
+func (c *Cache) get(ctx context.Context, key string) ([]byte, error) {
 	raw, err := c.driver.Get(ctx, key)
 	if errors.Is(err, drivers.ErrNotExist) {
 		return nil, domain.ErrKeyNotExist
@@ -105,62 +842,267 @@ func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
 	return []byte(raw), nil
 }
 
-func (c *Cache) Set(ctx context.Context, key string, val []byte, deadline time.Time) error {
-	err := c.acquire(ctx)
+// set returns the previous entry of the key, it's empty in Stateless mode.
+func (c *Cache) set(ctx context.Context, key string, val []byte, deadline time.Time) (entry, error) {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if c.native != nil {
+		return entry{}, c.setIf(ctx, key, val, deadline)
+	}
+
+	err := c.check(ctx, key)
 	if err != nil {
-		return err
+		return entry{}, err
 	}
-	defer c.release()
 
 	err = c.driver.Set(ctx, key, string(val))
 	if err != nil {
-		return fmt.Errorf("driver error: %w", err)
+		return entry{}, fmt.Errorf("driver error: %w", err)
 	}
 
-	// set infinite key
-	previous, _ := c.keys.Swap(key, deadline)
+	var ttl time.Duration
 
-	if deadline.IsZero() {
+	if domain.SlidingFrom(ctx) && !deadline.IsZero() {
+		ttl = max(0, deadline.Sub(c.cfg.Clock.Now()))
+	}
+
+	size := len(key) + len(val)
+	if c.grace != nil {
+		// the copy kept for the stale reads takes the memory as well
+		size += len(val)
+	}
+
+	// pinned key stays pinned after update
+	old, _ := c.entry(key)
+	c.store(key, entry{
+		deadline: deadline,
+		ttl:      ttl,
+		size:     size,
+		pinned:   old.pinned,
+		version:  c.versions.Add(1),
+	})
+	c.keep(key, val, deadline)
+	// zero deadline makes key infinite and drops it from the GC
+	c.expiry.schedule(key, deadline)
+
+	return old, nil
+}
+
+// check tells whether the condition and the version of the write hold, must be called under the key's lock.
+func (c *Cache) check(ctx context.Context, key string) error {
+	condition := domain.ConditionFrom(ctx)
+	version := domain.VersionFrom(ctx)
+
+	if condition == domain.ConditionNone && version == 0 {
 		return nil
 	}
 
-	// if last key was zero - we need to run GC on it
-	last, _ := previous.(time.Time)
-	if last.IsZero() {
-		ttl := deadline.Sub(time.Now().UTC())
+	current, exists := c.current(key)
 
-		go c.followEx(context.WithoutCancel(ctx), key, ttl/pingWindow)
+	switch {
+	case condition == domain.ConditionAbsent && exists:
+		return domain.ErrKeyExists
+	case condition == domain.ConditionPresent && !exists:
+		return domain.ErrKeyNotExist
+	case version > 0 && (!exists || current != version):
+		return domain.ErrVersionMismatch
 	}
 
 	return nil
 }
 
-func (c *Cache) Del(ctx context.Context, key string) error {
-	err := c.acquire(ctx)
-	if err != nil {
-		return err
+// current tells the version of the key and whether it could be read, the expired key doesn't exist
+// even if GC hasn't removed it yet.
+func (c *Cache) current(key string) (uint64, bool) {
+	ent, ok := c.entry(key)
+	if !ok || (!ent.deadline.IsZero() && !c.cfg.Clock.Now().Before(ent.deadline)) {
+		return 0, false
+	}
+
+	return ent.version, true
+}
+
+// setIf writes the key of Stateless cache, the condition is checked by the driver along with the write.
+func (c *Cache) setIf(ctx context.Context, key string, val []byte, deadline time.Time) error {
+	condition := domain.ConditionFrom(ctx)
+
+	switch {
+	case domain.VersionFrom(ctx) > 0:
+		// there is no index to keep the versions in, so there is nothing to swap
+		return domain.ErrUnsupported
+	case domain.SlidingFrom(ctx):
+		// nor the TTL to give the key back on read
+		return domain.ErrUnsupported
+	case condition == domain.ConditionNone:
+		return c.setEx(ctx, key, val, deadline)
+	case c.conditional == nil:
+		return domain.ErrUnsupported
+	}
+
+	var ttl time.Duration
+
+	if !deadline.IsZero() {
+		// the expired key still has to pass the condition, so it lives for a moment
+		ttl = max(deadline.Sub(c.cfg.Clock.Now()), time.Millisecond)
+	}
+
+	write, fail := c.conditional.SetXX, domain.ErrKeyNotExist
+	if condition == domain.ConditionAbsent {
+		write, fail = c.conditional.SetNX, domain.ErrKeyExists
+	}
+
+	ok, err := write(ctx, key, string(val), ttl)
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("driver error: %w", err)
+	case !ok:
+		return fail
+	}
+
+	return nil
+}
+
+func (c *Cache) setEx(ctx context.Context, key string, val []byte, deadline time.Time) error {
+	var err error
+
+	ttl := deadline.Sub(c.cfg.Clock.Now())
+
+	switch {
+	case deadline.IsZero():
+		err = c.native.Set(ctx, key, string(val))
+	case ttl > 0:
+		err = c.native.SetEx(ctx, key, string(val), ttl)
+	default:
+		// the key is already expired, so nothing to keep
+		err = c.native.Del(ctx, key)
 	}
-	defer c.release()
 
-	err = c.driver.Del(ctx, key)
 	if err != nil {
 		return fmt.Errorf("driver error: %w", err)
 	}
 
-	c.keys.Delete(key)
+	return nil
+}
+
+func (c *Cache) deadline(ctx context.Context, key string, now time.Time) (time.Time, error) {
+	ttl, err := c.native.TTL(ctx, key)
+	if errors.Is(err, drivers.ErrNotExist) {
+		return time.Time{}, domain.ErrKeyNotExist
+	}
+
+	if err != nil {
+		return time.Time{}, fmt.Errorf("driver error: %w", err)
+	}
+
+	if ttl == 0 {
+		return time.Time{}, nil
+	}
+
+	return now.Add(ttl), nil
+}
+
+func (c *Cache) load(ctx context.Context, key string) ([]byte, error) {
+	// the key could be filled while we were waiting for the flight
+	val, _, err := c.read(ctx, key)
+	if err == nil {
+		return val, nil
+	}
+
+	val, ttl, err := c.cfg.Loader.Load(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("loader error: %w", err)
+	}
+
+	var deadline time.Time
+
+	if ttl > 0 {
+		deadline = c.cfg.Clock.Now().Add(ttl)
+	}
+
+	err = c.Set(ctx, key, val, deadline)
+	if err != nil {
+		return nil, err
+	}
+
+	return val, nil
+}
+
+// slide moves the deadline of the sliding key by its TTL from now, the value in the driver stays untouched.
+func (c *Cache) slide(key string) {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// key was rewritten, deleted or expired after it was read
+	ent, ok := c.entry(key)
+	if !ok || ent.ttl == 0 {
+		return
+	}
+
+	deadline := c.cfg.Clock.Now().Add(ent.ttl)
+	if !deadline.After(ent.deadline) {
+		return
+	}
+
+	ent.deadline = deadline
+	c.keys.Store(key, ent)
+	c.expiry.schedule(key, deadline)
+	c.prolong(key, deadline)
+}
+
+// retime replaces the deadline of the key in the index, the entry keeps its size, pin and place in the policy.
+func (c *Cache) retime(ctx context.Context, key string, deadline time.Time) error {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if c.native != nil {
+		return c.reexpire(ctx, key, deadline)
+	}
+
+	now := c.cfg.Clock.Now()
+
+	ent, ok := c.entry(key)
+	if !ok {
+		return domain.ErrKeyNotExist
+	}
+
+	if !ent.deadline.IsZero() && !now.Before(ent.deadline) {
+		return domain.ErrKeyExpired
+	}
+
+	if ent.ttl > 0 && !deadline.IsZero() {
+		ent.ttl = max(0, deadline.Sub(now))
+	} else {
+		ent.ttl = 0
+	}
+
+	ent.deadline = deadline
+	c.keys.Store(key, ent)
+	// zero deadline drops the key from the GC
+	c.expiry.schedule(key, deadline)
+	c.prolong(key, deadline)
 
 	return nil
 }
 
-func (c *Cache) Close() error {
+// reexpire moves the deadline of the key of Stateless cache by the driver, the value is never written back,
+// so the newer one of the other replica is not overwritten.
+func (c *Cache) reexpire(ctx context.Context, key string, deadline time.Time) error {
 	var err error
 
-	c.once.Do(func() {
-		close(c.done)
-		close(c.queue)
+	if deadline.IsZero() {
+		err = c.native.Persist(ctx, key)
+	} else {
+		err = c.native.Expire(ctx, key, deadline.Sub(c.cfg.Clock.Now()))
+	}
 
-		err = c.driver.Close()
-	})
+	if errors.Is(err, drivers.ErrNotExist) {
+		return domain.ErrKeyNotExist
+	}
 
 	if err != nil {
 		return fmt.Errorf("driver error: %w", err)
@@ -169,58 +1111,267 @@ func (c *Cache) Close() error {
 	return nil
 }
 
-func (c *Cache) acquire(ctx context.Context) error {
-	select {
-	case <-c.done:
-		return domain.ErrClosed
-	default:
+// expire is called by GC when the deadline of the key has come.
+func (c *Cache) expire(key string, deadline time.Time) error {
+	ok, err := c.expired(key, deadline)
+	if ok {
+		c.emit(key, ReasonExpire, deadline)
 	}
 
-	timer := time.NewTimer(c.cfg.ConnTimeout)
-	defer timer.Stop()
+	return err
+}
 
-	select {
-	case c.queue <- struct{}{}:
-		return nil
-	case <-timer.C:
-		return domain.ErrConnTimeout
-	case <-ctx.Done():
-		return domain.ErrContextTimeout
+func (c *Cache) expired(key string, deadline time.Time) (bool, error) {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// key was rescheduled or deleted after GC took it
+	ent, ok := c.entry(key)
+	if !ok || !ent.deadline.Equal(deadline) {
+		return false, nil
 	}
+
+	err := c.driver.Del(context.Background(), key)
+	if err != nil {
+		return false, fmt.Errorf("driver error: %w", err)
+	}
+
+	c.forget(key)
+	c.expirations.Add(1)
+
+	return true, nil
 }
 
-func (c *Cache) release() {
-	<-c.queue
+func (c *Cache) pin(ctx context.Context, key string, pinned bool) error {
+	tick, ctx, err := c.acquire(ctx, c.writes)
+	if err != nil {
+		return err
+	}
+	defer c.release(tick)
+
+	if c.native != nil {
+		return nil
+	}
+
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	ent, ok := c.entry(key)
+	if !ok {
+		return domain.ErrKeyNotExist
+	}
+
+	if !ent.deadline.IsZero() && !c.cfg.Clock.Now().Before(ent.deadline) {
+		return domain.ErrKeyExpired
+	}
+
+	ent.pinned = pinned
+	c.store(key, ent)
+
+	return nil
 }
 
-func (c *Cache) followEx(ctx context.Context, key string, ping time.Duration) {
-	ticker := time.NewTicker(ping)
-	defer ticker.Stop()
+// shrink evicts the keys chosen by the policy until the cache fits its bounds.
+// It gives up when only pinned keys are left or the driver fails.
+func (c *Cache) shrink(ctx context.Context) {
+	// eviction is a consequence of the finished write, so don't break it in the middle
+	ctx = context.WithoutCancel(ctx)
+
+	for {
+		c.usage.Lock()
+
+		if !c.overflow() {
+			c.usage.Unlock()
 
-	for tick := range ticker.C {
-		deadline, ok := c.keys.Load(key)
-		if !ok {
 			return
 		}
 
-		future, _ := deadline.(time.Time)
-		// no reason to wait for not-ex keys
-		if future.IsZero() {
+		key, ok := c.policy.Evict()
+		c.usage.Unlock()
+
+		if !ok {
 			return
 		}
 
-		if future.After(tick) {
-			continue
+		if err := c.evict(ctx, key); err != nil {
+			return
 		}
+	}
+}
+
+func (c *Cache) evict(ctx context.Context, key string) error {
+	old, ok, err := c.evicted(ctx, key)
+	if ok {
+		c.emit(key, ReasonEvict, old.deadline)
+	}
+
+	return err
+}
+
+func (c *Cache) evicted(ctx context.Context, key string) (entry, bool, error) {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	// key was deleted, expired or pinned after the policy chose it
+	ent, ok := c.entry(key)
+	if !ok || ent.pinned {
+		return entry{}, false, nil
+	}
+
+	err := c.driver.Del(ctx, key)
+	if err != nil {
+		// the key is still here, so keep it evictable
+		c.usage.Lock()
+		c.policy.Push(key)
+		c.usage.Unlock()
+
+		return entry{}, false, fmt.Errorf("driver error: %w", err)
+	}
+
+	c.drop(key)
+	c.forget(key)
+	c.expiry.cancel(key)
+	c.evictions.Add(1)
 
-		// we will not stop GC if driver cause error
-		err := c.driver.Del(ctx, key)
+	return ent, true, nil
+}
+
+// del returns the deleted entry of the key, every key is reported as existed in Stateless mode.
+func (c *Cache) del(ctx context.Context, key string) (entry, bool, error) {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if c.native != nil {
+		return entry{}, true, c.delIf(ctx, key)
+	}
+
+	err := c.check(ctx, key)
+	if err != nil {
+		return entry{}, false, err
+	}
+
+	err = c.driver.Del(ctx, key)
+	if err != nil {
+		return entry{}, false, fmt.Errorf("driver error: %w", err)
+	}
+
+	old, ok := c.entry(key)
+
+	c.drop(key)
+	c.forget(key)
+	c.expiry.cancel(key)
+
+	return old, ok, nil
+}
+
+// delIf deletes the key of Stateless cache, the condition is checked by the driver along with the deletion.
+func (c *Cache) delIf(ctx context.Context, key string) error {
+	condition := domain.ConditionFrom(ctx)
+
+	switch {
+	case domain.VersionFrom(ctx) > 0:
+		return domain.ErrUnsupported
+	case condition == domain.ConditionNone:
+		err := c.native.Del(ctx, key)
 		if err != nil {
-			continue
+			return fmt.Errorf("driver error: %w", err)
 		}
 
-		c.keys.Delete(key)
+		return nil
+	case c.conditional == nil || condition == domain.ConditionAbsent:
+		// deletion of the absent key has no atomic counterpart in the drivers
+		return domain.ErrUnsupported
+	}
+
+	ok, err := c.conditional.DelXX(ctx, key)
+
+	switch {
+	case err != nil:
+		return fmt.Errorf("driver error: %w", err)
+	case !ok:
+		return domain.ErrKeyNotExist
+	}
+
+	return nil
+}
+
+func (c *Cache) emit(key string, reason Reason, deadline time.Time) {
+	c.events.emit(Event{Key: key, Reason: reason, Deadline: deadline, Time: c.cfg.Clock.Now(), Lost: 0})
+}
+
+func (c *Cache) overflow() bool {
+	if c.policy == nil {
+		return false
+	}
+
+	return (c.cfg.MaxKeys > 0 && c.count > c.cfg.MaxKeys) || (c.cfg.MaxBytes > 0 && c.bytes > c.cfg.MaxBytes)
+}
+
+func (c *Cache) entry(key string) (entry, bool) {
+	val, ok := c.keys.Load(key)
+	ent, _ := val.(entry)
+
+	return ent, ok
+}
+
+// store puts the entry into the index and updates usage and the policy, must be called under the key's lock.
+func (c *Cache) store(key string, ent entry) {
+	old, ok := c.entry(key)
+	c.keys.Store(key, ent)
+
+	c.usage.Lock()
+	defer c.usage.Unlock()
 
-		break
+	if !ok {
+		c.count++
+	}
+
+	c.bytes += ent.size - old.size
+
+	if c.policy == nil {
+		return
+	}
+
+	switch {
+	case ent.pinned:
+		c.policy.Remove(key)
+	case ok && !old.pinned:
+		c.policy.Touch(key)
+	default:
+		c.policy.Push(key)
 	}
 }
+
+// forget drops the key from the index, usage and the policy, must be called under the key's lock.
+func (c *Cache) forget(key string) {
+	old, ok := c.entry(key)
+	if !ok {
+		return
+	}
+
+	c.keys.Delete(key)
+
+	c.usage.Lock()
+	defer c.usage.Unlock()
+
+	c.count--
+	c.bytes -= old.size
+
+	if c.policy != nil {
+		c.policy.Remove(key)
+	}
+}
+
+func (c *Cache) touch(key string) {
+	if c.policy == nil {
+		return
+	}
+
+	c.usage.Lock()
+	c.policy.Touch(key)
+	c.usage.Unlock()
+}
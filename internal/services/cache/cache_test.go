@@ -5,6 +5,8 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"runtime"
 	"strconv"
 	"sync"
 	"sync/atomic"
@@ -16,15 +18,39 @@ import (
 	"github.com/therenotomorrow/apicache/internal/domain"
 	"github.com/therenotomorrow/apicache/internal/services/cache"
 	"github.com/therenotomorrow/apicache/pkg/drivers"
+	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
+	"github.com/therenotomorrow/apicache/pkg/eviction"
 	"github.com/therenotomorrow/apicache/test/mocks"
 	"github.com/therenotomorrow/apicache/test/toolkit"
 )
 
 const (
-	maxConn            = 1
-	invalidMaxConn     = -1
-	connTimeout        = 10 * time.Millisecond
-	invalidConnTimeout = 0
+	maxConn              = 1
+	invalidMaxConn       = -1
+	connTimeout          = 10 * time.Millisecond
+	invalidConnTimeout   = 0
+	expiryWorkers        = 2
+	invalidExpiryWorkers = -1
+	invalidDrainTimeout  = -1
+	invalidMaxKeys       = -1
+	invalidMaxBytes      = -1
+	maxKeys              = 3
+	invalidMinConn       = -1
+	invalidTargetLatency = -1
+	invalidWriteMaxConn  = -1
+	invalidWriteTimeout  = time.Microsecond
+	invalidClientShare   = 2
+	invalidShedTarget    = -1
+	invalidShedInterval  = -1
+	invalidBreakAfter    = -1
+	invalidBreakInterval = -1
+	invalidRetries       = -1
+	invalidRetryBackoff  = -1
+	invalidRetryCeiling  = time.Microsecond
+	invalidStaleGrace    = -1
+	staleGrace           = time.Minute
+	invalidEvents        = -1
+	invalidOverflow      = cache.OverflowBlock + 1
 )
 
 var (
@@ -42,6 +68,28 @@ func config() cache.Config {
 	return cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout}
 }
 
+func stateless() cache.Config {
+	return cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Stateless: true}
+}
+
+// fake freezes the time, so it moves only by Advance.
+func fake() (cache.Config, *mocks.ClockMock) {
+	clock := mocks.NewClockMock(time.Now())
+
+	return cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Clock: clock}, clock
+}
+
+// gone waits for the GC to remove the key, time is frozen so nothing else happens meanwhile.
+func gone(t *testing.T, obj *cache.Cache, key string) {
+	t.Helper()
+
+	require.Eventually(t, func() bool {
+		_, err := obj.Get(context.Background(), key)
+
+		return errors.Is(err, domain.ErrKeyNotExist)
+	}, time.Second, time.Millisecond)
+}
+
 func driver() *mocks.DriverMock {
 	return mocks.NewDriverMock()
 }
@@ -69,6 +117,203 @@ func TestUnitNew(t *testing.T) {
 			args: args{cfg: cache.Config{MaxConn: maxConn, ConnTimeout: invalidConnTimeout}, driver: driver()},
 			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidConnTimeout),
 		},
+		{
+			name: "invalid MinConn",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, MinConn: invalidMinConn, ConnTimeout: connTimeout},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidMinConn),
+		},
+		{
+			name: "MinConn above MaxConn",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, MinConn: maxConn + 1, ConnTimeout: connTimeout},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidMinConn),
+		},
+		{
+			name: "invalid TargetLatency",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, TargetLatency: invalidTargetLatency},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidTargetLatency),
+		},
+		{
+			name: "invalid WriteMaxConn",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, WriteMaxConn: invalidWriteMaxConn},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidWriteMaxConn),
+		},
+		{
+			name: "WriteMinConn above WriteMaxConn",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, WriteMaxConn: 1, WriteMinConn: 2},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidWriteMinConn),
+		},
+		{
+			name: "invalid WriteTimeout",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, WriteTimeout: invalidWriteTimeout},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidWriteTimeout),
+		},
+		{
+			name: "invalid Weights",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Weights: map[string]int{"client": 0}},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidWeights),
+		},
+		{
+			name: "invalid ClientShare",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, ClientShare: invalidClientShare},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidClientShare),
+		},
+		{
+			name: "invalid ShedTarget",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, ShedTarget: invalidShedTarget},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidShedTarget),
+		},
+		{
+			name: "invalid ShedInterval",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, ShedInterval: invalidShedInterval},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidShedInterval),
+		},
+		{
+			name: "invalid BreakAfter",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, BreakAfter: invalidBreakAfter},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidBreakAfter),
+		},
+		{
+			name: "invalid BreakInterval",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, BreakInterval: invalidBreakInterval},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidBreakInterval),
+		},
+		{
+			name: "invalid Retries",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Retries: invalidRetries},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidRetries),
+		},
+		{
+			name: "invalid RetryBackoff",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, RetryBackoff: invalidRetryBackoff},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidRetryBackoff),
+		},
+		{
+			name: "invalid RetryCeiling",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, RetryCeiling: invalidRetryCeiling},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidRetryCeiling),
+		},
+		{
+			name: "invalid ExpiryWorkers",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, ExpiryWorkers: invalidExpiryWorkers},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidExpiryWorkers),
+		},
+		{
+			name: "invalid DrainTimeout",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, DrainTimeout: invalidDrainTimeout},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidDrainTimeout),
+		},
+		{
+			name: "invalid MaxKeys",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, MaxKeys: invalidMaxKeys},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidMaxKeys),
+		},
+		{
+			name: "invalid MaxBytes",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, MaxBytes: invalidMaxBytes},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidMaxBytes),
+		},
+		{
+			name: "invalid Stateless",
+			args: args{cfg: stateless(), driver: nil},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidStateless),
+		},
+		{
+			name: "invalid Events",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Events: invalidEvents},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidEvents),
+		},
+		{
+			name: "invalid Overflow",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Overflow: invalidOverflow},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidOverflow),
+		},
+		{
+			name: "invalid StaleGrace",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, StaleGrace: invalidStaleGrace},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidStaleGrace),
+		},
+		{
+			name: "invalid stale Stateless",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Stateless: true, StaleGrace: staleGrace},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidStateless),
+		},
+		{
+			name: "invalid bounded Stateless",
+			args: args{
+				cfg:    cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, Stateless: true, MaxKeys: maxKeys},
+				driver: driver(),
+			},
+			want: toolkit.Want[*cache.Cache](nil, cache.ErrInvalidStateless),
+		},
 		{
 			name: "success",
 			args: args{cfg: config(), driver: driver()},
@@ -80,7 +325,12 @@ func TestUnitNew(t *testing.T) {
 		t.Run(test.name, func(t *testing.T) {
 			t.Parallel()
 
-			obj, err := cache.New(test.args.cfg, test.args.driver)
+			var driver cache.Driver = test.args.driver
+			if test.args.driver == nil {
+				driver = machine.New()
+			}
+
+			obj, err := cache.New(test.args.cfg, driver)
 
 			if test.name == "success" {
 				assert.NotEmpty(t, obj)
@@ -490,26 +740,25 @@ func TestUnitCacheLogicNonExKey(t *testing.T) {
 func TestUnitCacheLogicExKey(t *testing.T) {
 	t.Parallel()
 
-	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
-
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(connTimeout))
-	time.Sleep(2 * connTimeout)
+	obj := cache.MustNew(cfg, driver())
 
-	_, err := obj.Get(ctx, "key")
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(connTimeout))
+	clock.Advance(2 * connTimeout)
 
-	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+	gone(t, obj, "key")
 }
 
 func TestUnitCacheLogicNonExKeyBecomesEx(t *testing.T) {
 	t.Parallel()
 
 	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	obj := cache.MustNew(cfg, driver)
 
 	driver.GetMock = func(_ context.Context, _ string) (string, error) {
 		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
@@ -521,25 +770,24 @@ func TestUnitCacheLogicNonExKeyBecomesEx(t *testing.T) {
 	}
 
 	_ = obj.Set(ctx, "key", value(), time.Time{})
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(2*connTimeout))
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(2*connTimeout))
 
 	val, err := obj.Get(ctx, "key")
 
 	toolkit.Assert(t, toolkit.Got(err, val), toolkit.Want(value(), nil))
-	time.Sleep(4 * connTimeout)
-
-	_, err = obj.Get(ctx, "key")
+	clock.Advance(4 * connTimeout)
 
-	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+	gone(t, obj, "key")
 }
 
 func TestUnitCacheLogicIncreaseExOnKey(t *testing.T) {
 	t.Parallel()
 
 	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	obj := cache.MustNew(cfg, driver)
 
 	driver.GetMock = func(_ context.Context, _ string) (string, error) {
 		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
@@ -550,29 +798,28 @@ func TestUnitCacheLogicIncreaseExOnKey(t *testing.T) {
 		return string(bytes), nil
 	}
 
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(5*connTimeout))
-	time.Sleep(2 * connTimeout)
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(5*connTimeout))
+	clock.Advance(2 * connTimeout)
 
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(10*connTimeout))
-	time.Sleep(6 * connTimeout)
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(10*connTimeout))
+	clock.Advance(6 * connTimeout)
 
 	val, err := obj.Get(ctx, "key")
 
 	toolkit.Assert(t, toolkit.Got(err, val), toolkit.Want(value(), nil))
-	time.Sleep(6 * connTimeout)
-
-	_, err = obj.Get(ctx, "key")
+	clock.Advance(6 * connTimeout)
 
-	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+	gone(t, obj, "key")
 }
 
 func TestUnitCacheLogicDecreaseExOnKey(t *testing.T) {
 	t.Parallel()
 
 	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	obj := cache.MustNew(cfg, driver)
 
 	driver.GetMock = func(_ context.Context, _ string) (string, error) {
 		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
@@ -583,24 +830,23 @@ func TestUnitCacheLogicDecreaseExOnKey(t *testing.T) {
 		return string(bytes), nil
 	}
 
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(10*connTimeout))
-	time.Sleep(connTimeout)
-
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(5*connTimeout))
-	time.Sleep(7 * connTimeout)
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(10*connTimeout))
+	clock.Advance(connTimeout)
 
-	_, err := obj.Get(ctx, "key")
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(5*connTimeout))
+	clock.Advance(7 * connTimeout)
 
-	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+	gone(t, obj, "key")
 }
 
 func TestUnitCacheLogicExKeyBecomesNonEx(t *testing.T) {
 	t.Parallel()
 
 	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	obj := cache.MustNew(cfg, driver)
 
 	driver.GetMock = func(_ context.Context, _ string) (string, error) {
 		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
@@ -611,12 +857,12 @@ func TestUnitCacheLogicExKeyBecomesNonEx(t *testing.T) {
 		return string(bytes), nil
 	}
 
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(5*connTimeout))
-	time.Sleep(2 * connTimeout)
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(5*connTimeout))
+	clock.Advance(2 * connTimeout)
 
 	_ = obj.Set(ctx, "key", value(), time.Time{})
 
-	time.Sleep(6 * connTimeout)
+	clock.Advance(6 * connTimeout)
 
 	val, err := obj.Get(ctx, "key")
 
@@ -626,17 +872,17 @@ func TestUnitCacheLogicExKeyBecomesNonEx(t *testing.T) {
 func TestUnitCacheLogicExKeyWasDeleted(t *testing.T) {
 	t.Parallel()
 
-	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	obj := cache.MustNew(cfg, driver())
 
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(5*connTimeout))
-	time.Sleep(2 * connTimeout)
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(5*connTimeout))
+	clock.Advance(2 * connTimeout)
 
 	_ = obj.Del(ctx, "key")
 
-	time.Sleep(6 * connTimeout)
+	clock.Advance(6 * connTimeout)
 
 	_, err := obj.Get(ctx, "key")
 
@@ -647,10 +893,11 @@ func TestUnitCacheLogicExKeyWaitOnDeletion(t *testing.T) {
 	t.Parallel()
 
 	driver := driver()
+	cfg, clock := fake()
 
 	cnt := atomic.Int64{}
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	obj := cache.MustNew(cfg, driver)
 
 	driver.GetMock = func(_ context.Context, _ string) (string, error) {
 		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
@@ -670,122 +917,2113 @@ func TestUnitCacheLogicExKeyWaitOnDeletion(t *testing.T) {
 		return errDummy
 	}
 
-	_ = obj.Set(ctx, "key", value(), time.Now().UTC().Add(2*connTimeout))
-	time.Sleep(2 * connTimeout)
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(2*connTimeout))
+	clock.Advance(2 * connTimeout)
+
+	// first deletion failed and the next one waits for the clock
+	require.Eventually(t, func() bool { return cnt.Load() == 1 }, time.Second, time.Millisecond)
 
 	_, err := obj.Get(ctx, "key")
 
 	// key was expired but still not deleted
 	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyExpired))
 
-	time.Sleep(4 * connTimeout)
+	require.Eventually(t, func() bool {
+		clock.Advance(time.Second)
 
-	_, err = obj.Get(ctx, "key")
+		_, err = obj.Get(ctx, "key")
+
+		return errors.Is(err, domain.ErrKeyNotExist)
+	}, time.Second, time.Millisecond)
 
 	// key was deleted successfully
-	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+	toolkit.Assert(t, toolkit.Got(nil, cnt.Load()), toolkit.Want(int64(3), nil))
 }
 
-func TestUnitCacheLogicKeyNotFoundInDriver(t *testing.T) {
+func TestUnitCacheLogicTinyExOnKey(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver())
+
+	// too small TTL must not break GC
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(time.Nanosecond))
+	clock.Advance(time.Nanosecond)
+
+	gone(t, obj, "key")
+}
+
+func TestUnitCacheLogicMillisecondExOnKey(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, machine.New())
+
+	// short locks and rate-limit windows live exactly as long as they were asked
+	require.NoError(t, obj.Set(ctx, "key", value(), clock.Now().Add(1500*time.Millisecond)))
+	clock.Advance(1499 * time.Millisecond)
+
+	got, err := obj.Get(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(value(), nil))
+
+	clock.Advance(time.Millisecond)
+
+	gone(t, obj, "key")
+}
+
+func TestUnitCacheLogicExKeysGoroutines(t *testing.T) {
+	ctx := context.Background()
+	before := runtime.NumGoroutine()
+	obj := cache.MustNew(cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, ExpiryWorkers: expiryWorkers}, driver())
+
+	for idx := range 1000 {
+		_ = obj.Set(ctx, strconv.Itoa(idx), value(), time.Now().UTC().Add(time.Hour))
+	}
+
+	// GC loop and workers only
+	assert.LessOrEqual(t, runtime.NumGoroutine()-before, expiryWorkers+1)
+
+	require.NoError(t, obj.Close())
+	assert.LessOrEqual(t, runtime.NumGoroutine(), before)
+}
+
+func TestUnitCacheDeadline(t *testing.T) {
 	t.Parallel()
 
 	driver := driver()
+	cfg, clock := fake()
 
 	ctx := context.Background()
-	obj := cache.MustNew(config(), driver)
+	future := clock.Now().Add(time.Hour)
+	obj := cache.MustNew(cfg, driver)
 
-	driver.GetMock = func(_ context.Context, _ string) (string, error) {
-		return "", drivers.ErrNotExist
+	_ = obj.Set(ctx, "infinite", value(), time.Time{})
+	_ = obj.Set(ctx, "future", value(), future)
+
+	got, err := obj.Deadline(ctx, "infinite")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, nil))
+
+	got, err = obj.Deadline(ctx, "future")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(future, nil))
+
+	got, err = obj.Deadline(ctx, "invalid")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, domain.ErrKeyNotExist))
+
+	driver.DelMock = func(_ context.Context, _ string) error { return errDummy }
+
+	clock.Advance(2 * time.Hour)
+
+	got, err = obj.Deadline(ctx, "future")
+
+	// GC can't remove the key, but it's expired anyway
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, domain.ErrKeyExpired))
+
+	_ = obj.Close()
+
+	got, err = obj.Deadline(ctx, "future")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, domain.ErrClosed))
+}
+
+func TestUnitCacheSliding(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+
+	ctx := context.Background()
+	sliding := domain.WithSliding(ctx, true)
+	start := clock.Now()
+	obj := cache.MustNew(cfg, machine.New())
+
+	require.NoError(t, obj.Set(sliding, "session", value(), start.Add(10*time.Second)))
+	require.NoError(t, obj.Set(sliding, "infinite", value(), time.Time{}))
+	require.NoError(t, obj.Set(ctx, "absolute", value(), start.Add(10*time.Second)))
+
+	// every read gives the sliding key its TTL back
+	clock.Advance(6 * time.Second)
+
+	for _, key := range []string{"session", "infinite", "absolute"} {
+		_, err := obj.Get(ctx, key)
+		require.NoError(t, err)
 	}
 
-	_ = obj.Set(ctx, "key", value(), time.Time{})
+	for key, want := range map[string]time.Time{
+		"session":  start.Add(16 * time.Second),
+		"infinite": {},
+		"absolute": start.Add(10 * time.Second),
+	} {
+		got, err := obj.Deadline(ctx, key)
 
-	_, err := obj.Get(ctx, "key")
+		toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(want, nil))
+	}
 
-	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+	clock.Advance(6 * time.Second)
+	gone(t, obj, "absolute")
+
+	_, err := obj.Get(ctx, "session")
+	require.NoError(t, err)
+
+	// the key left alone for its TTL expires as usual
+	clock.Advance(10 * time.Second)
+	gone(t, obj, "session")
+
+	// the rewritten key without the option has the absolute deadline
+	require.NoError(t, obj.Set(sliding, "session", value(), clock.Now().Add(time.Second)))
+	require.NoError(t, obj.Set(ctx, "session", value(), clock.Now().Add(time.Second)))
+
+	clock.Advance(time.Millisecond)
+
+	_, err = obj.Get(ctx, "session")
+	require.NoError(t, err)
+
+	got, err := obj.Deadline(ctx, "session")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(clock.Now().Add(time.Second-time.Millisecond), nil))
+	require.NoError(t, obj.Close())
 }
 
-func TestUnitCacheLogicSmoke(t *testing.T) {
+func TestUnitCacheExpire(t *testing.T) {
 	t.Parallel()
 
-	driver := driver()
+	cfg, clock := fake()
 
-	waiter := sync.WaitGroup{}
 	ctx := context.Background()
-	obj := cache.MustNew(cache.Config{MaxConn: 20, ConnTimeout: connTimeout}, driver)
+	start := clock.Now()
+	obj := cache.MustNew(cfg, machine.New())
 
-	driver.SetMock = func(_ context.Context, _ string, _ string) error {
-		time.Sleep(connTimeout)
+	require.NoError(t, obj.Set(ctx, "short", value(), start.Add(time.Second)))
+	require.NoError(t, obj.Set(ctx, "long", value(), start.Add(time.Hour)))
+	require.NoError(t, obj.Set(ctx, "infinite", value(), time.Time{}))
+	require.NoError(t, obj.Set(domain.WithSliding(ctx, true), "session", value(), start.Add(10*time.Second)))
 
-		return nil
+	require.NoError(t, obj.Expire(ctx, "short", start.Add(time.Hour)))
+	require.NoError(t, obj.Persist(ctx, "long"))
+	require.NoError(t, obj.Expire(ctx, "infinite", start.Add(time.Second)))
+	require.NoError(t, obj.Expire(ctx, "session", start.Add(20*time.Second)))
+	require.ErrorIs(t, obj.Expire(ctx, "invalid", start.Add(time.Hour)), domain.ErrKeyNotExist)
+	require.ErrorIs(t, obj.Persist(ctx, "invalid"), domain.ErrKeyNotExist)
+
+	// the keys outlive their old deadlines with the values untouched, the infinite one got a deadline
+	clock.Advance(2 * time.Second)
+	gone(t, obj, "infinite")
+
+	for _, key := range []string{"short", "long", "session"} {
+		got, err := obj.Get(ctx, key)
+
+		toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(value(), nil))
+	}
+
+	// the sliding key slides by its new TTL
+	for key, want := range map[string]time.Time{
+		"short":   start.Add(time.Hour),
+		"long":    {},
+		"session": clock.Now().Add(20 * time.Second),
+	} {
+		got, err := obj.Deadline(ctx, key)
+
+		toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(want, nil))
 	}
+
+	assert.Equal(t, 3, obj.Stats().Keys)
+	require.NoError(t, obj.Close())
+	require.ErrorIs(t, obj.Persist(ctx, "long"), domain.ErrClosed)
+}
+
+func TestUnitCacheStatelessExpire(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	calls := sync.Map{}
+	cfg := stateless()
+	cfg.Clock = mocks.NewClockMock(time.Now())
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+
+	// the driver moves the deadline in place, the value is never read and written back
 	driver.GetMock = func(_ context.Context, _ string) (string, error) {
-		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
-		if err != nil {
-			panic(err)
+		return "", errDummy
+	}
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		return errDummy
+	}
+	driver.SetExMock = func(_ context.Context, _ string, _ string, _ time.Duration) error {
+		return errDummy
+	}
+	driver.ExpireMock = func(_ context.Context, key string, ttl time.Duration) error {
+		if key == "invalid" {
+			return drivers.ErrNotExist
 		}
 
-		return string(bytes), nil
+		calls.Store(key, ttl)
+
+		return nil
 	}
+	driver.PersistMock = func(_ context.Context, key string) error {
+		calls.Store(key, time.Duration(0))
 
-	waiter.Add(100)
+		return nil
+	}
 
-	success := atomic.Int64{}
-	failure := atomic.Int64{}
+	require.NoError(t, obj.Expire(ctx, "future", cfg.Clock.Now().Add(time.Hour)))
+	require.NoError(t, obj.Persist(ctx, "infinite"))
+	require.ErrorIs(t, obj.Expire(ctx, "invalid", cfg.Clock.Now().Add(time.Hour)), domain.ErrKeyNotExist)
 
-	for idx := range 100 {
-		go func(key string) {
-			err := obj.Set(ctx, key, value(), time.Time{})
-			if err != nil {
-				failure.Add(1)
-			} else {
-				success.Add(1)
-			}
+	for key, ttl := range map[string]time.Duration{"infinite": 0, "future": time.Hour} {
+		got, _ := calls.Load(key)
 
-			waiter.Done()
-		}(strconv.Itoa(idx))
+		assert.Equal(t, ttl, got)
 	}
 
-	waiter.Wait()
+	driver.PersistMock = func(_ context.Context, _ string) error {
+		return errDummy
+	}
 
-	assert.Less(t, success.Load(), failure.Load())
-	assert.LessOrEqual(t, success.Load(), int64(40))
-	assert.GreaterOrEqual(t, failure.Load(), int64(60))
+	require.ErrorIs(t, obj.Persist(ctx, "infinite"), errDummy)
 }
 
-func TestUnitCacheClose(t *testing.T) {
+func TestUnitCacheCondition(t *testing.T) {
 	t.Parallel()
 
-	type args struct {
-		cfg    cache.Config
-		driver *mocks.DriverMock
-	}
+	cfg, clock := fake()
 
-	tests := []struct {
-		name string
-		args args
-		want toolkit.W[any]
-	}{
-		{name: "success", args: args{cfg: config(), driver: driver()}, want: toolkit.Err(nil)},
-		{name: "failure", args: args{cfg: config(), driver: driver()}, want: toolkit.Err(errClosed)},
-	}
+	ctx := context.Background()
+	absent := domain.WithCondition(ctx, domain.ConditionAbsent)
+	present := domain.WithCondition(ctx, domain.ConditionPresent)
+	obj := cache.MustNew(cfg, machine.New())
 
-	for _, test := range tests {
-		t.Run(test.name, func(t *testing.T) {
-			t.Parallel()
+	require.ErrorIs(t, obj.Set(present, "key", value(), time.Time{}), domain.ErrKeyNotExist)
+	require.NoError(t, obj.Set(absent, "key", value(), time.Time{}))
+	require.ErrorIs(t, obj.Set(absent, "key", value(), time.Time{}), domain.ErrKeyExists)
+	require.NoError(t, obj.Set(present, "key", []byte(`{"age":43}`), time.Time{}))
 
-			obj := cache.MustNew(test.args.cfg, test.args.driver)
+	got, err := obj.Get(ctx, "key")
 
-			if test.name == "failure" {
-				test.args.driver.CloseMock = func() error { return errClosedDriver }
-			}
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte(`{"age":43}`), nil))
 
-			err := obj.Close()
+	// the expired key is absent even if GC hasn't removed it yet
+	require.NoError(t, obj.Set(ctx, "short", value(), clock.Now().Add(time.Second)))
+	clock.Advance(time.Second)
+	require.ErrorIs(t, obj.Set(present, "short", value(), time.Time{}), domain.ErrKeyNotExist)
+	require.NoError(t, obj.Set(absent, "short", value(), time.Time{}))
 
-			toolkit.Assert(t, toolkit.Got[any](err), test.want)
+	// the only one of the concurrent writers creates the key
+	created := atomic.Int64{}
+	group := sync.WaitGroup{}
 
-			// calling Close() twice is safely do nothing
-			require.NoError(t, obj.Close())
-		})
+	for range 10 {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			if obj.Set(absent, "leader", value(), time.Time{}) == nil {
+				created.Add(1)
+			}
+		}()
 	}
+
+	group.Wait()
+
+	assert.Equal(t, int64(1), created.Load())
+	require.NoError(t, obj.Close())
+}
+
+func TestUnitCacheVersion(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, machine.New())
+
+	require.NoError(t, obj.Set(ctx, "key", value(), time.Time{}))
+
+	_, first, err := obj.GetVersion(ctx, "key")
+	require.NoError(t, err)
+
+	// the move of the deadline keeps the version, the write changes it
+	require.NoError(t, obj.Expire(ctx, "key", clock.Now().Add(time.Hour)))
+
+	_, version, err := obj.GetVersion(ctx, "key")
+	require.NoError(t, err)
+	assert.Equal(t, first, version)
+
+	require.NoError(t, obj.Set(domain.WithVersion(ctx, first), "key", []byte(`{"age":43}`), time.Time{}))
+	require.ErrorIs(t, obj.Set(domain.WithVersion(ctx, first), "key", value(), time.Time{}), domain.ErrVersionMismatch)
+	require.ErrorIs(t, obj.Set(domain.WithVersion(ctx, first), "absent", value(), time.Time{}), domain.ErrVersionMismatch)
+
+	got, second, err := obj.GetVersion(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte(`{"age":43}`), nil))
+	assert.Greater(t, second, first)
+
+	// the only one of the concurrent writers swaps the version
+	swapped := atomic.Int64{}
+	group := sync.WaitGroup{}
+
+	for range 10 {
+		group.Add(1)
+
+		go func() {
+			defer group.Done()
+
+			if obj.Set(domain.WithVersion(ctx, second), "key", value(), time.Time{}) == nil {
+				swapped.Add(1)
+			}
+		}()
+	}
+
+	group.Wait()
+
+	assert.Equal(t, int64(1), swapped.Load())
+
+	_, third, err := obj.GetVersion(ctx, "key")
+	require.NoError(t, err)
+
+	require.ErrorIs(t, obj.Del(domain.WithVersion(ctx, second), "key"), domain.ErrVersionMismatch)
+	require.NoError(t, obj.Del(domain.WithVersion(ctx, third), "key"))
+
+	_, err = obj.Get(ctx, "key")
+
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+	require.NoError(t, obj.Close())
+}
+
+func TestUnitCacheStatelessCondition(t *testing.T) {
+	t.Parallel()
+
+	driver := mocks.NewConditionalDriverMock()
+	exists := func(key string) (bool, error) {
+		switch key {
+		case "invalid":
+			return false, nil
+		case "failure":
+			return false, drivers.Transient(errDummy)
+		}
+
+		return true, nil
+	}
+
+	cfg := stateless()
+	cfg.Retries = 2
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+	calls := atomic.Int32{}
+	ttls := sync.Map{}
+
+	driver.SetNXMock = func(_ context.Context, key string, _ string, ttl time.Duration) (bool, error) {
+		calls.Add(1)
+		ttls.Store(key, ttl)
+
+		ok, err := exists(key)
+
+		return !ok && err == nil, err
+	}
+	driver.SetXXMock = func(_ context.Context, key string, _ string, ttl time.Duration) (bool, error) {
+		calls.Add(1)
+		ttls.Store(key, ttl)
+
+		return exists(key)
+	}
+	driver.DelXXMock = func(_ context.Context, key string) (bool, error) {
+		calls.Add(1)
+
+		return exists(key)
+	}
+
+	absent := domain.WithCondition(ctx, domain.ConditionAbsent)
+	present := domain.WithCondition(ctx, domain.ConditionPresent)
+
+	// the driver checks the condition along with the write, nothing is read before
+	require.ErrorIs(t, obj.Set(absent, "key", value(), time.Time{}), domain.ErrKeyExists)
+	require.NoError(t, obj.Set(present, "key", value(), time.Now().UTC().Add(time.Hour)))
+	require.ErrorIs(t, obj.Set(present, "invalid", value(), time.Time{}), domain.ErrKeyNotExist)
+	require.NoError(t, obj.Set(absent, "invalid", value(), time.Now().UTC().Add(-time.Hour)))
+	require.NoError(t, obj.Del(present, "key"))
+	require.ErrorIs(t, obj.Del(present, "invalid"), domain.ErrKeyNotExist)
+	require.ErrorIs(t, obj.Del(absent, "invalid"), domain.ErrUnsupported)
+
+	// the expired key lives for a moment to pass the condition
+	got, _ := ttls.Load("invalid")
+
+	assert.Equal(t, time.Millisecond, got)
+
+	got, _ = ttls.Load("key")
+
+	assert.InDelta(t, time.Hour, got, float64(time.Second))
+
+	calls.Store(0)
+
+	// the failed attempt could have written the key, so it's not repeated
+	err := obj.Set(absent, "failure", value(), time.Time{})
+
+	require.ErrorIs(t, err, drivers.ErrTransient)
+	assert.Equal(t, int32(1), calls.Load())
+
+	driver.GetMock = func(_ context.Context, _ string) (string, error) {
+		return string(value()), nil
+	}
+
+	// there is no index to keep the versions in, so the swap is refused instead of failing as a mismatch
+	_, version, err := obj.GetVersion(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, version), toolkit.Want(uint64(0), nil))
+	require.ErrorIs(t, obj.Set(domain.WithVersion(ctx, 1), "key", value(), time.Time{}), domain.ErrUnsupported)
+	require.ErrorIs(t, obj.Del(domain.WithVersion(ctx, 1), "key"), domain.ErrUnsupported)
+
+	// the driver without atomic conditions can't serve them, it's not read before the write either
+	plain := cache.MustNew(stateless(), mocks.NewDriverMock())
+
+	require.ErrorIs(t, plain.Set(absent, "key", value(), time.Time{}), domain.ErrUnsupported)
+	require.ErrorIs(t, plain.Set(present, "key", value(), time.Time{}), domain.ErrUnsupported)
+	require.ErrorIs(t, plain.Del(present, "key"), domain.ErrUnsupported)
+	require.NoError(t, plain.Set(ctx, "key", value(), time.Time{}))
+	require.NoError(t, plain.Del(ctx, "key"))
+}
+
+func TestUnitCacheStatelessSet(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	calls := sync.Map{}
+	ctx := context.Background()
+	obj := cache.MustNew(stateless(), driver)
+
+	driver.SetMock = func(_ context.Context, key string, _ string) error {
+		calls.Store(key, "set")
+
+		return nil
+	}
+	driver.SetExMock = func(_ context.Context, key string, _ string, ttl time.Duration) error {
+		if ttl <= 0 || ttl > time.Hour {
+			return errDummy
+		}
+
+		calls.Store(key, "setEx")
+
+		return nil
+	}
+	driver.DelMock = func(_ context.Context, key string) error {
+		calls.Store(key, "del")
+
+		return nil
+	}
+
+	require.NoError(t, obj.Set(ctx, "infinite", value(), time.Time{}))
+	require.NoError(t, obj.Set(ctx, "future", value(), time.Now().UTC().Add(time.Hour)))
+	require.NoError(t, obj.Set(ctx, "expired", value(), time.Now().UTC().Add(-time.Hour)))
+
+	for key, call := range map[string]string{"infinite": "set", "future": "setEx", "expired": "del"} {
+		got, _ := calls.Load(key)
+
+		assert.Equal(t, call, got)
+	}
+
+	driver.SetExMock = func(_ context.Context, _ string, _ string, _ time.Duration) error {
+		return errDummy
+	}
+
+	err := obj.Set(ctx, "future", value(), time.Now().UTC().Add(time.Hour))
+
+	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(errDummyDriver))
+
+	// there is no index to keep the TTL of the sliding key in
+	err = obj.Set(domain.WithSliding(ctx, true), "sliding", value(), time.Now().UTC().Add(time.Hour))
+
+	require.ErrorIs(t, err, domain.ErrUnsupported)
+
+	_, ok := calls.Load("sliding")
+	assert.False(t, ok)
+}
+
+func TestUnitCacheStatelessGet(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	ctx := context.Background()
+	obj := cache.MustNew(stateless(), driver)
+
+	driver.GetMock = func(_ context.Context, key string) (string, error) {
+		switch key {
+		case "invalid":
+			return "", drivers.ErrNotExist
+		case "failure":
+			return "", errDummy
+		}
+
+		return string(value()), nil
+	}
+
+	// nothing was set through this instance, but driver is trusted
+	got, err := obj.Get(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(value(), nil))
+
+	got, err = obj.Get(ctx, "invalid")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, domain.ErrKeyNotExist))
+
+	got, err = obj.Get(ctx, "failure")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, errDummyDriver))
+
+	require.NoError(t, obj.Del(ctx, "key"))
+}
+
+func TestUnitCacheStatelessDeadline(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	ctx := context.Background()
+	obj := cache.MustNew(stateless(), driver)
+
+	driver.TTLMock = func(_ context.Context, key string) (time.Duration, error) {
+		switch key {
+		case "invalid":
+			return 0, drivers.ErrNotExist
+		case "failure":
+			return 0, errDummy
+		case "infinite":
+			return 0, nil
+		}
+
+		return time.Hour, nil
+	}
+
+	got, err := obj.Deadline(ctx, "infinite")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, nil))
+
+	got, err = obj.Deadline(ctx, "future")
+
+	require.NoError(t, err)
+	assert.WithinDuration(t, time.Now().UTC().Add(time.Hour), got, time.Second)
+
+	got, err = obj.Deadline(ctx, "invalid")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, domain.ErrKeyNotExist))
+
+	got, err = obj.Deadline(ctx, "failure")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(time.Time{}, errDummyDriver))
+}
+
+func TestUnitCacheLogicKeyNotFoundInDriver(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	ctx := context.Background()
+	obj := cache.MustNew(config(), driver)
+
+	driver.GetMock = func(_ context.Context, _ string) (string, error) {
+		return "", drivers.ErrNotExist
+	}
+
+	_ = obj.Set(ctx, "key", value(), time.Time{})
+
+	_, err := obj.Get(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(domain.ErrKeyNotExist))
+}
+
+func TestUnitCacheLogicLinearizable(t *testing.T) {
+	t.Parallel()
+
+	store := machine.New()
+	driver := driver()
+
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 16, ConnTimeout: time.Second}, driver)
+
+	// widen the window between driver call and index update
+	driver.GetMock = store.Get
+	driver.SetMock = func(ctx context.Context, key string, val string) error {
+		defer time.Sleep(rand.N(100 * time.Microsecond))
+
+		return store.Set(ctx, key, val)
+	}
+	driver.DelMock = func(ctx context.Context, key string) error {
+		defer time.Sleep(rand.N(100 * time.Microsecond))
+
+		return store.Del(ctx, key)
+	}
+
+	base := time.Now().UTC().Add(time.Hour)
+
+	for round := range 50 {
+		waiter := sync.WaitGroup{}
+		waiter.Add(16)
+
+		for writer := range 16 {
+			go func() {
+				defer waiter.Done()
+
+				if writer%5 == 0 {
+					_ = obj.Del(ctx, "key")
+
+					return
+				}
+
+				deadline := base.Add(time.Duration(round*16+writer) * time.Millisecond)
+
+				_ = obj.Set(ctx, "key", []byte(deadline.Format(time.RFC3339Nano)), deadline)
+			}()
+		}
+
+		waiter.Wait()
+
+		raw, errGet := obj.Get(ctx, "key")
+		deadline, errDeadline := obj.Deadline(ctx, "key")
+
+		if errGet != nil || errDeadline != nil {
+			require.ErrorIs(t, errGet, domain.ErrKeyNotExist)
+			require.ErrorIs(t, errDeadline, domain.ErrKeyNotExist)
+
+			continue
+		}
+
+		// value and deadline must come from the same writer
+		require.Equal(t, deadline.Format(time.RFC3339Nano), string(raw))
+	}
+}
+
+func TestUnitCacheLogicSmoke(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 20, ConnTimeout: connTimeout}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		time.Sleep(connTimeout)
+
+		return nil
+	}
+	driver.GetMock = func(_ context.Context, _ string) (string, error) {
+		bytes, err := json.Marshal(map[string]any{"hello": "world", "age": 42})
+		if err != nil {
+			panic(err)
+		}
+
+		return string(bytes), nil
+	}
+
+	waiter.Add(100)
+
+	success := atomic.Int64{}
+	failure := atomic.Int64{}
+
+	for idx := range 100 {
+		go func(key string) {
+			err := obj.Set(ctx, key, value(), time.Time{})
+			if err != nil {
+				failure.Add(1)
+			} else {
+				success.Add(1)
+			}
+
+			waiter.Done()
+		}(strconv.Itoa(idx))
+	}
+
+	waiter.Wait()
+
+	assert.Less(t, success.Load(), failure.Load())
+	assert.LessOrEqual(t, success.Load(), int64(40))
+	assert.GreaterOrEqual(t, failure.Load(), int64(60))
+}
+
+func TestUnitCacheClose(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		cfg    cache.Config
+		driver *mocks.DriverMock
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[any]
+	}{
+		{name: "success", args: args{cfg: config(), driver: driver()}, want: toolkit.Err(nil)},
+		{name: "failure", args: args{cfg: config(), driver: driver()}, want: toolkit.Err(errClosed)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			obj := cache.MustNew(test.args.cfg, test.args.driver)
+
+			if test.name == "failure" {
+				test.args.driver.CloseMock = func() error { return errClosedDriver }
+			}
+
+			err := obj.Close()
+
+			toolkit.Assert(t, toolkit.Got[any](err), test.want)
+
+			// calling Close() twice is safely do nothing
+			require.NoError(t, obj.Close())
+		})
+	}
+}
+
+func TestUnitCacheCloseDrain(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	order := make(chan string, 3)
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: maxConn, ConnTimeout: time.Second}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		time.Sleep(5 * connTimeout)
+
+		order <- "set"
+
+		return nil
+	}
+	driver.CloseMock = func() error {
+		order <- "close"
+
+		return nil
+	}
+
+	waiter.Add(2)
+
+	var errSet, errWait error
+
+	go func() {
+		defer waiter.Done()
+
+		errSet = obj.Set(ctx, "key", value(), time.Time{})
+	}()
+	time.Sleep(connTimeout / 2)
+
+	// waits for the queue that will never be free before close
+	go func() {
+		defer waiter.Done()
+
+		errWait = obj.Set(ctx, "newKey", value(), time.Time{})
+	}()
+	time.Sleep(connTimeout / 2)
+
+	require.NoError(t, obj.Close())
+
+	waiter.Wait()
+
+	require.NoError(t, errSet)
+	require.ErrorIs(t, errWait, domain.ErrClosed)
+
+	// in-flight operation finished before the driver was closed
+	toolkit.Assert(t, toolkit.Got(nil, <-order), toolkit.Want("set", nil))
+	toolkit.Assert(t, toolkit.Got(nil, <-order), toolkit.Want("close", nil))
+}
+
+func TestUnitCacheCloseDrainTimeout(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	closed := atomic.Bool{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: maxConn, ConnTimeout: connTimeout, DrainTimeout: connTimeout}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		time.Sleep(10 * connTimeout)
+
+		return nil
+	}
+	driver.CloseMock = func() error {
+		closed.Store(true)
+
+		return nil
+	}
+
+	go func() { _ = obj.Set(ctx, "key", value(), time.Time{}) }()
+	time.Sleep(connTimeout / 2)
+
+	err := obj.Close()
+
+	toolkit.Assert(t, toolkit.Got[any](err), toolkit.Err(cache.ErrDrainTimeout))
+	assert.True(t, closed.Load())
+}
+
+func TestUnitCacheCloseStopsGC(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+
+	cfg, clock := fake()
+
+	deleted := atomic.Int64{}
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+
+	driver.DelMock = func(_ context.Context, _ string) error {
+		deleted.Add(1)
+
+		return nil
+	}
+
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(2*connTimeout))
+
+	require.NoError(t, obj.Close())
+	clock.Advance(4 * connTimeout)
+
+	// closed driver is never touched by GC
+	assert.Zero(t, deleted.Load())
+}
+
+func TestUnitCacheGetOrLoad(t *testing.T) {
+	t.Parallel()
+
+	store := machine.New()
+	driver := driver()
+	loader := mocks.NewLoaderMock()
+
+	cfg, clock := fake()
+	cfg.Loader = loader
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+
+	driver.GetMock = store.Get
+	driver.SetMock = store.Set
+	driver.DelMock = store.Del
+	loader.LoadMock = func(_ context.Context, key string) ([]byte, time.Duration, error) {
+		switch key {
+		case "invalid":
+			return nil, 0, domain.ErrKeyNotExist
+		case "failure":
+			return nil, 0, errDummy
+		case "infinite":
+			return value(), 0, nil
+		}
+
+		return value(), time.Minute, nil
+	}
+
+	got, err := obj.GetOrLoad(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(value(), nil))
+
+	deadline, err := obj.Deadline(ctx, "key")
+
+	// loaded key was stored with TTL of the loader
+	toolkit.Assert(t, toolkit.Got(err, deadline), toolkit.Want(clock.Now().Add(time.Minute), nil))
+
+	_, _ = obj.GetOrLoad(ctx, "infinite")
+	deadline, err = obj.Deadline(ctx, "infinite")
+
+	toolkit.Assert(t, toolkit.Got(err, deadline), toolkit.Want(time.Time{}, nil))
+
+	got, err = obj.GetOrLoad(ctx, "invalid")
+
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+	assert.Empty(t, got)
+
+	got, err = obj.GetOrLoad(ctx, "failure")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, fmt.Errorf("loader error: %w", errDummy)))
+
+	// hit doesn't touch the loader
+	loader.LoadMock = nil
+
+	got, err = obj.GetOrLoad(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want(value(), nil))
+}
+
+func TestUnitCacheGetOrLoadWithoutLoader(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := cache.MustNew(config(), driver())
+
+	got, err := obj.GetOrLoad(ctx, "key")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, domain.ErrKeyNotExist))
+}
+
+func TestUnitCacheGetOrLoadCoalescing(t *testing.T) {
+	t.Parallel()
+
+	store := machine.New()
+	driver := driver()
+	loader := mocks.NewLoaderMock()
+
+	loads := atomic.Int64{}
+	release := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 100, ConnTimeout: time.Second, Loader: loader}, driver)
+
+	driver.GetMock = store.Get
+	driver.SetMock = store.Set
+	loader.LoadMock = func(_ context.Context, _ string) ([]byte, time.Duration, error) {
+		loads.Add(1)
+		<-release
+
+		return value(), time.Minute, nil
+	}
+
+	// the first caller gives up, but the load is still shared with others
+	cancelled, cancel := context.WithCancel(ctx)
+
+	go func() {
+		time.Sleep(connTimeout)
+		cancel()
+	}()
+
+	_, err := obj.GetOrLoad(cancelled, "key")
+
+	require.ErrorIs(t, err, domain.ErrContextTimeout)
+
+	results := make(chan []byte, 100)
+
+	waiter.Add(100)
+
+	for range 100 {
+		go func() {
+			defer waiter.Done()
+
+			val, _ := obj.GetOrLoad(ctx, "key")
+			results <- val
+		}()
+	}
+
+	time.Sleep(connTimeout)
+	close(release)
+	waiter.Wait()
+	close(results)
+
+	for val := range results {
+		assert.Equal(t, value(), val)
+	}
+
+	toolkit.Assert(t, toolkit.Got(nil, loads.Load()), toolkit.Want(int64(1), nil))
+}
+
+// bounded returns the cache over the in-memory store, so evicted keys are really gone.
+func bounded(cfg cache.Config) (*cache.Cache, *mocks.DriverMock) {
+	store := machine.New()
+	driver := driver()
+
+	driver.GetMock = store.Get
+	driver.SetMock = store.Set
+	driver.DelMock = store.Del
+
+	cfg.MaxConn = maxConn
+	cfg.ConnTimeout = connTimeout
+
+	return cache.MustNew(cfg, driver), driver
+}
+
+func TestUnitCacheEvictMaxKeys(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj, _ := bounded(cache.Config{MaxKeys: maxKeys})
+
+	for i := range maxKeys {
+		_ = obj.Set(ctx, "key"+strconv.Itoa(i), value(), time.Time{})
+	}
+
+	// the oldest key becomes the recently used one
+	_, _ = obj.Get(ctx, "key0")
+	_ = obj.Set(ctx, "key"+strconv.Itoa(maxKeys), value(), time.Time{})
+
+	_, err := obj.Get(ctx, "key1")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	_, err = obj.Get(ctx, "key0")
+	require.NoError(t, err)
+
+	stats := obj.Stats()
+
+	assert.Equal(t, maxKeys, stats.Keys)
+	assert.Equal(t, maxKeys*len("key0"+string(value())), stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, uint64(0), stats.Expirations)
+}
+
+func TestUnitCacheEvictMaxBytes(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	size := len("key0" + string(value()))
+	obj, _ := bounded(cache.Config{MaxBytes: 2 * size, Policy: eviction.NewLFU()})
+
+	_ = obj.Set(ctx, "key0", value(), time.Time{})
+	_ = obj.Set(ctx, "key1", value(), time.Time{})
+	_, _ = obj.Get(ctx, "key0")
+	_ = obj.Set(ctx, "key2", value(), time.Time{})
+
+	_, err := obj.Get(ctx, "key1")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	// update of the existing key changes only its size
+	_ = obj.Set(ctx, "key0", []byte("{}"), time.Time{})
+
+	stats := obj.Stats()
+
+	assert.Equal(t, 2, stats.Keys)
+	assert.Equal(t, size+len("key0{}"), stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Evictions)
+}
+
+func TestUnitCacheEvictPinned(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj, _ := bounded(cache.Config{MaxKeys: 1})
+
+	require.ErrorIs(t, obj.Pin(ctx, "key0"), domain.ErrKeyNotExist)
+
+	_ = obj.Set(ctx, "key0", value(), time.Time{})
+	require.NoError(t, obj.Pin(ctx, "key0"))
+
+	// the pinned key stays pinned after update, so the new key is the only victim
+	_ = obj.Set(ctx, "key0", value(), time.Time{})
+	_ = obj.Set(ctx, "key1", value(), time.Time{})
+
+	_, err := obj.Get(ctx, "key0")
+	require.NoError(t, err)
+
+	_, err = obj.Get(ctx, "key1")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	require.NoError(t, obj.Unpin(ctx, "key0"))
+	_ = obj.Set(ctx, "key1", value(), time.Time{})
+
+	_, err = obj.Get(ctx, "key0")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	assert.Equal(t, uint64(2), obj.Stats().Evictions)
+}
+
+func TestUnitCacheEvictErrDriver(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj, driver := bounded(cache.Config{MaxKeys: 1})
+	del := driver.DelMock
+
+	driver.DelMock = func(_ context.Context, _ string) error {
+		return errDummy
+	}
+
+	_ = obj.Set(ctx, "key0", value(), time.Time{})
+	_ = obj.Set(ctx, "key1", value(), time.Time{})
+
+	assert.Equal(t, uint64(0), obj.Stats().Evictions)
+
+	// the failed victim is still evictable
+	driver.DelMock = del
+	_ = obj.Set(ctx, "key1", value(), time.Time{})
+
+	_, err := obj.Get(ctx, "key0")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	assert.Equal(t, uint64(1), obj.Stats().Evictions)
+}
+
+func TestUnitCacheStatsExpirations(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+	cfg.MaxKeys = maxKeys
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver())
+
+	_ = obj.Set(ctx, "key", value(), clock.Now().Add(connTimeout))
+	clock.Advance(connTimeout)
+	gone(t, obj, "key")
+
+	stats := obj.Stats()
+
+	assert.Equal(t, 0, stats.Keys)
+	assert.Equal(t, 0, stats.Bytes)
+	assert.Equal(t, uint64(1), stats.Expirations)
+	assert.Equal(t, uint64(0), stats.Evictions)
+}
+
+func TestUnitCacheAdaptiveLimit(t *testing.T) {
+	t.Parallel()
+
+	const (
+		minConn = 2
+		maxConn = 10
+	)
+
+	driver := driver()
+	cfg, clock := fake()
+	cfg.MinConn = minConn
+	cfg.MaxConn = maxConn
+	cfg.TargetLatency = connTimeout
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+	slow := atomic.Bool{}
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		if slow.Load() {
+			clock.Advance(2 * connTimeout)
+		}
+
+		return nil
+	}
+
+	assert.Equal(t, maxConn, obj.Stats().ReadLimit)
+
+	// degraded driver shrinks the limit down to the floor
+	slow.Store(true)
+
+	for range 100 {
+		_ = obj.Set(ctx, "key", value(), time.Time{})
+	}
+
+	assert.Equal(t, minConn, obj.Stats().ReadLimit)
+
+	// failures shrink it as well as the slow calls
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		return errDummy
+	}
+
+	for range 100 {
+		_ = obj.Set(ctx, "key", value(), time.Time{})
+	}
+
+	assert.Equal(t, minConn, obj.Stats().ReadLimit)
+
+	// healthy driver grows it back up to the ceiling
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		return nil
+	}
+
+	for range 1000 {
+		_ = obj.Set(ctx, "key", value(), time.Time{})
+	}
+
+	assert.Equal(t, maxConn, obj.Stats().ReadLimit)
+}
+
+func TestUnitCacheAdaptiveLimitAdmission(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	release := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 2, MinConn: 1, ConnTimeout: connTimeout}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		return errDummy
+	}
+
+	// the only failure is enough to lose one of two permits
+	_ = obj.Set(ctx, "key", value(), time.Time{})
+
+	require.Equal(t, 1, obj.Stats().ReadLimit)
+
+	driver.DelMock = func(_ context.Context, _ string) error {
+		<-release
+
+		return nil
+	}
+
+	waiter.Add(1)
+
+	go func() {
+		defer waiter.Done()
+
+		_ = obj.Del(ctx, "key")
+	}()
+
+	time.Sleep(connTimeout)
+
+	err := obj.Set(ctx, "key", value(), time.Time{})
+
+	require.ErrorIs(t, err, domain.ErrConnTimeout)
+
+	close(release)
+	waiter.Wait()
+}
+
+func TestUnitCachePools(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: connTimeout, WriteMaxConn: 1}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		close(started)
+		<-release
+
+		return nil
+	}
+
+	waiter.Add(1)
+
+	go func() {
+		defer waiter.Done()
+
+		_ = obj.Set(ctx, "key", value(), time.Time{})
+	}()
+
+	<-started
+
+	// the busy write pool doesn't affect reads
+	_, err := obj.Get(ctx, "other")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	err = obj.Del(ctx, "other")
+	require.ErrorIs(t, err, domain.ErrConnTimeout)
+	require.ErrorIs(t, err, domain.ErrWritePoolExhausted)
+
+	close(release)
+	waiter.Wait()
+
+	stats := obj.Stats()
+
+	assert.Equal(t, 1, stats.ReadLimit)
+	assert.Equal(t, 1, stats.WriteLimit)
+}
+
+func TestUnitCachePoolsBorrow(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	calls := atomic.Int64{}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: connTimeout, WriteMaxConn: 1, Borrow: true}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		if calls.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+
+		return nil
+	}
+
+	waiter.Add(1)
+
+	go func() {
+		defer waiter.Done()
+
+		_ = obj.Set(ctx, "key1", value(), time.Time{})
+	}()
+
+	<-started
+
+	// the idle read permit is lent to the write
+	require.NoError(t, obj.Set(ctx, "key2", value(), time.Time{}))
+
+	close(release)
+	waiter.Wait()
+}
+
+func TestUnitCachePoolsShared(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(config(), driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		close(started)
+		<-release
+
+		return nil
+	}
+
+	waiter.Add(1)
+
+	go func() {
+		defer waiter.Done()
+
+		_ = obj.Set(ctx, "key", value(), time.Time{})
+	}()
+
+	<-started
+
+	// the shared pool is not named in the error
+	_, err := obj.Get(ctx, "key")
+	require.Equal(t, domain.ErrConnTimeout, err)
+
+	close(release)
+	waiter.Wait()
+}
+
+func TestUnitCacheFairQueuing(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	order := make(chan string, 10)
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second, Weights: map[string]int{"heavy": 2}}, driver)
+
+	driver.SetMock = func(_ context.Context, key string, _ string) error {
+		if key == "hold" {
+			close(started)
+			<-release
+		}
+
+		return nil
+	}
+	driver.DelMock = func(ctx context.Context, _ string) error {
+		order <- domain.ClientFrom(ctx)
+
+		return nil
+	}
+
+	go func() {
+		_ = obj.Set(ctx, "hold", value(), time.Time{})
+	}()
+
+	<-started
+
+	// the noisy client comes first with many requests, others come later with a few
+	clients := []string{"noisy", "noisy", "noisy", "noisy", "heavy", "heavy", "heavy", "light"}
+
+	waiter.Add(len(clients))
+
+	for i, client := range clients {
+		go func() {
+			defer waiter.Done()
+
+			_ = obj.Del(domain.WithClient(ctx, client), strconv.Itoa(i))
+		}()
+
+		time.Sleep(connTimeout)
+	}
+
+	close(release)
+	waiter.Wait()
+	close(order)
+
+	got := make([]string, 0)
+	for client := range order {
+		got = append(got, client)
+	}
+
+	// the heavy client is served twice as often as others, the noisy one doesn't block anyone
+	want := []string{"heavy", "heavy", "light", "noisy", "heavy", "noisy", "noisy", "noisy"}
+
+	assert.Equal(t, want, got)
+}
+
+func TestUnitCacheClientShare(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	noisy := domain.WithClient(ctx, "noisy")
+	obj := cache.MustNew(cache.Config{MaxConn: 2, ConnTimeout: connTimeout, ClientShare: 0.5}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		close(started)
+		<-release
+
+		return nil
+	}
+
+	waiter.Add(1)
+
+	go func() {
+		defer waiter.Done()
+
+		_ = obj.Set(noisy, "hold", value(), time.Time{})
+	}()
+
+	<-started
+
+	// the half of the pool is free, but it isn't the share of the noisy client
+	_, err := obj.Get(noisy, "key")
+	require.ErrorIs(t, err, domain.ErrConnTimeout)
+
+	_, err = obj.Get(domain.WithClient(ctx, "other"), "key")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	close(release)
+	waiter.Wait()
+}
+
+func TestUnitCachePriority(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	started := make(chan struct{})
+	release := make(chan struct{})
+	order := make(chan domain.Priority, 10)
+	waiter := sync.WaitGroup{}
+	ctx := context.Background()
+	obj := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, driver)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		close(started)
+		<-release
+
+		return nil
+	}
+	driver.DelMock = func(ctx context.Context, _ string) error {
+		order <- domain.PriorityFrom(ctx)
+
+		return nil
+	}
+
+	go func() {
+		_ = obj.Set(ctx, "hold", value(), time.Time{})
+	}()
+
+	<-started
+
+	priorities := []domain.Priority{domain.PriorityLow, domain.PriorityNormal, domain.PriorityHigh, domain.PriorityLow}
+
+	waiter.Add(len(priorities))
+
+	for i, priority := range priorities {
+		go func() {
+			defer waiter.Done()
+
+			_ = obj.Del(domain.WithPriority(ctx, priority), strconv.Itoa(i))
+		}()
+
+		time.Sleep(connTimeout)
+	}
+
+	close(release)
+	waiter.Wait()
+	close(order)
+
+	got := make([]domain.Priority, 0)
+	for priority := range order {
+		got = append(got, priority)
+	}
+
+	want := []domain.Priority{domain.PriorityHigh, domain.PriorityNormal, domain.PriorityLow, domain.PriorityLow}
+
+	assert.Equal(t, want, got)
+}
+
+func TestUnitCacheShed(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	gate := make(chan struct{})
+	waiter := sync.WaitGroup{}
+	cfg, clock := fake()
+	cfg.ConnTimeout = time.Minute
+	cfg.ShedTarget = connTimeout
+	cfg.ShedInterval = 2 * connTimeout
+
+	ctx := context.Background()
+	low := domain.WithPriority(ctx, domain.PriorityLow)
+	high := domain.WithPriority(ctx, domain.PriorityHigh)
+	obj := cache.MustNew(cfg, driver)
+
+	driver.DelMock = func(_ context.Context, _ string) error {
+		<-gate
+
+		return nil
+	}
+
+	// queue: the call holds the permit, the next one waits longer than the target
+	enqueue := func(key string) {
+		waiter.Add(1)
+
+		go func() {
+			defer waiter.Done()
+
+			_ = obj.Del(ctx, key)
+		}()
+
+		time.Sleep(connTimeout)
+	}
+
+	enqueue("key0")
+	enqueue("key1")
+
+	// the first slow admission starts the interval
+	clock.Advance(5 * connTimeout)
+	gate <- struct{}{}
+	time.Sleep(connTimeout)
+
+	// not overloaded yet, so the low priority waits as usual
+	short, cancel := context.WithTimeout(low, connTimeout)
+	defer cancel()
+
+	_, err := obj.Get(short, "key")
+	require.ErrorIs(t, err, domain.ErrContextTimeout)
+
+	// the queue stays above the target for the whole interval, so the pool is overloaded
+	enqueue("key2")
+	clock.Advance(5 * connTimeout)
+	gate <- struct{}{}
+	time.Sleep(connTimeout)
+	enqueue("key3")
+
+	start := time.Now()
+	_, err = obj.Get(low, "key")
+
+	require.ErrorIs(t, err, domain.ErrShed)
+	assert.Less(t, time.Since(start), connTimeout)
+
+	// higher priorities still wait for the permit
+	waiter.Add(1)
+
+	go func() {
+		defer waiter.Done()
+
+		_, err := obj.Get(high, "key")
+		assert.ErrorIs(t, err, domain.ErrKeyNotExist)
+	}()
+
+	time.Sleep(connTimeout)
+
+	// the drained queue ends the overload
+	gate <- struct{}{}
+	gate <- struct{}{}
+	waiter.Wait()
+
+	_, err = obj.Get(low, "key")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+}
+
+func TestUnitCacheBreaker(t *testing.T) {
+	t.Parallel()
+
+	const (
+		breakAfter    = 3
+		breakInterval = time.Second
+	)
+
+	driver := driver()
+	cfg, clock := fake()
+	cfg.BreakAfter = breakAfter
+	cfg.BreakInterval = breakInterval
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+	calls := atomic.Int32{}
+	failing := atomic.Bool{}
+
+	failing.Store(true)
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		calls.Add(1)
+
+		if failing.Load() {
+			return errDummy
+		}
+
+		return nil
+	}
+
+	// consecutive failures open the circuit
+	for range breakAfter {
+		err := obj.Set(ctx, "key", value(), time.Time{})
+		require.ErrorIs(t, err, errDummy)
+	}
+
+	// the open circuit fails fast without calling the driver
+	err := obj.Set(ctx, "key", value(), time.Time{})
+
+	var retry *domain.RetryError
+
+	require.ErrorIs(t, err, domain.ErrCircuitOpen)
+	require.ErrorAs(t, err, &retry)
+	assert.Equal(t, breakInterval, retry.After)
+	assert.Equal(t, int32(breakAfter), calls.Load())
+
+	// the failed probe opens it again
+	clock.Advance(breakInterval)
+
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.ErrorIs(t, err, errDummy)
+
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.ErrorIs(t, err, domain.ErrCircuitOpen)
+
+	// the probe that didn't reach the driver leaves the place to the next one
+	clock.Advance(breakInterval)
+
+	_, err = obj.Get(ctx, "missing")
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+
+	// the successful probe closes it
+	failing.Store(false)
+
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.NoError(t, err)
+
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.NoError(t, err)
+	assert.Equal(t, int32(breakAfter+3), calls.Load())
+}
+
+func TestUnitCacheBreakerLate(t *testing.T) {
+	t.Parallel()
+
+	const breakInterval = time.Second
+
+	driver := driver()
+	cfg, clock := fake()
+	cfg.MaxConn = 4
+	cfg.BreakAfter = 1
+	cfg.BreakInterval = breakInterval
+
+	ctx := context.Background()
+	obj := cache.MustNew(cfg, driver)
+	late := make(chan error)
+	probe := make(chan error)
+	entered := make(chan struct{}, 3)
+	results := make(chan error, 3)
+
+	driver.SetMock = func(_ context.Context, key string, _ string) error {
+		switch key {
+		case "late1", "late2":
+			entered <- struct{}{}
+
+			return <-late
+		case "probe":
+			entered <- struct{}{}
+
+			return <-probe
+		}
+
+		return errDummy
+	}
+
+	set := func(key string) {
+		results <- obj.Set(ctx, key, value(), time.Time{})
+	}
+
+	// both calls are let in while the circuit is closed
+	go set("late1")
+	go set("late2")
+
+	<-entered
+	<-entered
+
+	err := obj.Set(ctx, "key", value(), time.Time{})
+	require.ErrorIs(t, err, errDummy)
+
+	// the late success doesn't close the open circuit
+	late <- nil
+	require.NoError(t, <-results)
+
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.ErrorIs(t, err, domain.ErrCircuitOpen)
+
+	// nor the half-open one, only the probe decides
+	clock.Advance(breakInterval)
+
+	go set("probe")
+
+	<-entered
+
+	late <- nil
+	require.NoError(t, <-results)
+
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.ErrorIs(t, err, domain.ErrCircuitOpen)
+
+	probe <- nil
+	require.NoError(t, <-results)
+
+	// the closed circuit lets the calls reach the driver
+	err = obj.Set(ctx, "key", value(), time.Time{})
+	require.ErrorIs(t, err, errDummy)
+}
+
+func TestUnitCacheRetry(t *testing.T) {
+	t.Parallel()
+
+	const retries = 2
+
+	errTransient := drivers.Transient(errDummy)
+
+	tests := []struct {
+		name  string
+		fails []error
+		calls int32
+		want  error
+	}{
+		{name: "recovered", fails: []error{errTransient, errTransient}, calls: retries + 1, want: nil},
+		{name: "exhausted", fails: []error{errTransient, errTransient, errTransient}, calls: retries + 1, want: errTransient},
+		{name: "permanent", fails: []error{errDummy, errTransient}, calls: 1, want: errDummy},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			driver := driver()
+			cfg := config()
+			cfg.ConnTimeout = time.Second
+			cfg.Retries = retries
+			cfg.RetryBackoff = time.Microsecond
+
+			obj := cache.MustNew(cfg, driver)
+			calls := atomic.Int32{}
+
+			driver.SetMock = func(_ context.Context, _ string, _ string) error {
+				call := int(calls.Add(1))
+				if call <= len(test.fails) {
+					return test.fails[call-1]
+				}
+
+				return nil
+			}
+
+			err := obj.Set(context.Background(), "key", value(), time.Time{})
+
+			if test.want == nil {
+				require.NoError(t, err)
+			} else {
+				require.ErrorIs(t, err, test.want)
+			}
+
+			assert.Equal(t, test.calls, calls.Load())
+		})
+	}
+}
+
+func TestUnitCacheRetryDeadline(t *testing.T) {
+	t.Parallel()
+
+	driver := driver()
+	cfg := config()
+	cfg.Retries = 1
+	cfg.RetryBackoff = 24 * time.Hour
+
+	obj := cache.MustNew(cfg, driver)
+	calls := atomic.Int32{}
+
+	driver.SetMock = func(_ context.Context, _ string, _ string) error {
+		calls.Add(1)
+
+		return drivers.Transient(errDummy)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+
+	// the pause doesn't fit into the deadline, so nothing to wait for
+	err := obj.Set(ctx, "key", value(), time.Time{})
+
+	require.ErrorIs(t, err, drivers.ErrTransient)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func TestUnitCacheStale(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+	cfg.StaleGrace = staleGrace
+
+	ctx := context.Background()
+	obj, driver := bounded(cfg)
+
+	require.NoError(t, obj.Set(ctx, "key", value(), clock.Now().Add(time.Second)))
+	require.NoError(t, obj.Set(ctx, "infinite", value(), time.Time{}))
+	require.NoError(t, obj.Set(ctx, "deleted", value(), time.Time{}))
+	require.NoError(t, obj.Del(ctx, "deleted"))
+
+	// the expired key is served stale within the grace window
+	clock.Advance(time.Second)
+
+	got, err := obj.Get(ctx, "key")
+
+	require.ErrorIs(t, err, domain.ErrStale)
+	assert.Equal(t, value(), got)
+
+	// GC has removed it, but the value is still known
+	require.Eventually(t, func() bool {
+		return obj.Stats().Expirations == 1
+	}, time.Second, time.Millisecond)
+
+	got, err = obj.Get(ctx, "key")
+
+	require.ErrorIs(t, err, domain.ErrStale)
+	assert.Equal(t, value(), got)
+
+	// and isn't after it
+	clock.Advance(staleGrace)
+
+	got, err = obj.Get(ctx, "key")
+
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+	assert.Empty(t, got)
+
+	// the failed driver serves the last known values, but not the deleted ones
+	driver.GetMock = func(_ context.Context, _ string) (string, error) {
+		return "", errDummy
+	}
+
+	got, err = obj.Get(ctx, "infinite")
+
+	require.ErrorIs(t, err, domain.ErrStale)
+	assert.Equal(t, value(), got)
+
+	got, err = obj.Get(ctx, "deleted")
+
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+	assert.Empty(t, got)
+}
+
+func TestUnitCacheStaleBytes(t *testing.T) {
+	t.Parallel()
+
+	// every key takes its value twice: in the driver and in the copy of the grace window
+	size := len("key1") + 2*len(value())
+
+	cfg, _ := fake()
+	cfg.StaleGrace = staleGrace
+	cfg.MaxBytes = 2 * size
+
+	ctx := context.Background()
+	obj, driver := bounded(cfg)
+
+	require.NoError(t, obj.Set(ctx, "key1", value(), time.Time{}))
+	require.NoError(t, obj.Set(ctx, "key2", value(), time.Time{}))
+	assert.Equal(t, 2*size, obj.Stats().Bytes)
+
+	// the infinite key takes its copy along, when evicted
+	require.NoError(t, obj.Set(ctx, "key3", value(), time.Time{}))
+	assert.Equal(t, 2*size, obj.Stats().Bytes)
+
+	driver.GetMock = func(_ context.Context, _ string) (string, error) {
+		return "", errDummy
+	}
+
+	got, err := obj.Get(ctx, "key1")
+
+	require.ErrorIs(t, err, domain.ErrKeyNotExist)
+	assert.Empty(t, got)
+
+	got, err = obj.Get(ctx, "key3")
+
+	require.ErrorIs(t, err, domain.ErrStale)
+	assert.Equal(t, value(), got)
+}
+
+func TestUnitCacheStaleRefresh(t *testing.T) {
+	t.Parallel()
+
+	loader := mocks.NewLoaderMock()
+	loader.LoadMock = func(_ context.Context, _ string) ([]byte, time.Duration, error) {
+		return []byte(`"fresh"`), 0, nil
+	}
+
+	cfg, clock := fake()
+	cfg.StaleGrace = staleGrace
+	cfg.Loader = loader
+
+	ctx := context.Background()
+	obj, _ := bounded(cfg)
+
+	require.NoError(t, obj.Set(ctx, "key", value(), clock.Now().Add(time.Second)))
+
+	clock.Advance(time.Second)
+
+	got, err := obj.Get(ctx, "key")
+
+	require.ErrorIs(t, err, domain.ErrStale)
+	assert.Equal(t, value(), got)
+
+	// the reader doesn't wait, the loader refreshes the key in background
+	require.Eventually(t, func() bool {
+		got, err = obj.Get(ctx, "key")
+
+		return err == nil && string(got) == `"fresh"`
+	}, time.Second, time.Millisecond)
+}
+
+func TestUnitCacheEvents(t *testing.T) {
+	t.Parallel()
+
+	cfg, clock := fake()
+	cfg.MaxKeys = 1
+
+	ctx := context.Background()
+	obj, _ := bounded(cfg)
+	events := make(chan cache.Event, 10)
+	listener := func(event cache.Event) { events <- event }
+
+	obj.OnSet(listener)
+	obj.OnDelete(listener)
+	obj.OnExpire(listener)
+	obj.OnEvict(listener)
+
+	now := clock.Now()
+	deadline := now.Add(time.Second)
+
+	require.NoError(t, obj.Set(ctx, "key1", value(), deadline))
+	require.NoError(t, obj.Set(ctx, "key1", value(), deadline))
+	require.NoError(t, obj.Set(ctx, "key2", value(), time.Time{}))
+	require.NoError(t, obj.Del(ctx, "key2"))
+	require.NoError(t, obj.Del(ctx, "missing"))
+	require.NoError(t, obj.Set(ctx, "key3", value(), deadline))
+
+	clock.Advance(time.Second)
+
+	want := []cache.Event{
+		{Key: "key1", Reason: cache.ReasonSet, Deadline: time.Time{}, Time: now, Lost: 0},
+		{Key: "key1", Reason: cache.ReasonSet, Deadline: deadline, Time: now, Lost: 0},
+		{Key: "key2", Reason: cache.ReasonSet, Deadline: time.Time{}, Time: now, Lost: 0},
+		{Key: "key1", Reason: cache.ReasonEvict, Deadline: deadline, Time: now, Lost: 0},
+		{Key: "key2", Reason: cache.ReasonDelete, Deadline: time.Time{}, Time: now, Lost: 0},
+		{Key: "key3", Reason: cache.ReasonSet, Deadline: time.Time{}, Time: now, Lost: 0},
+		{Key: "key3", Reason: cache.ReasonExpire, Deadline: deadline, Time: clock.Now(), Lost: 0},
+	}
+
+	for _, event := range want {
+		select {
+		case got := <-events:
+			assert.Equal(t, event, got)
+		case <-time.After(time.Second):
+			require.Fail(t, "no event", event.Key)
+		}
+	}
+
+	require.NoError(t, obj.Close())
+	assert.Empty(t, events)
+	assert.Equal(t, uint64(0), obj.Stats().Dropped)
+}
+
+func TestUnitCacheEventsOverflow(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		overflow cache.Overflow
+		want     []string
+		lost     []uint64
+		dropped  uint64
+	}{
+		{
+			name:     "drop newest",
+			overflow: cache.OverflowDropNewest,
+			want:     []string{"key1", "key2"},
+			lost:     []uint64{0, 0},
+			dropped:  1,
+		},
+		{
+			// the event after the lost one tells about it
+			name:     "drop oldest",
+			overflow: cache.OverflowDropOldest,
+			want:     []string{"key1", "key3"},
+			lost:     []uint64{0, 1},
+			dropped:  1,
+		},
+		{
+			name:     "block",
+			overflow: cache.OverflowBlock,
+			want:     []string{"key1", "key2", "key3"},
+			lost:     []uint64{0, 0, 0},
+			dropped:  0,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config()
+			cfg.ConnTimeout = time.Second
+			cfg.Events = 1
+			cfg.Overflow = test.overflow
+
+			ctx := context.Background()
+			obj, _ := bounded(cfg)
+			gate := make(chan struct{})
+			events := make(chan cache.Event, 3)
+
+			obj.OnSet(func(event cache.Event) {
+				events <- event
+				<-gate
+			})
+
+			// the first event holds the listener, the second one holds the buffer
+			require.NoError(t, obj.Set(ctx, "key1", value(), time.Time{}))
+			assert.Equal(t, "key1", (<-events).Key)
+			require.NoError(t, obj.Set(ctx, "key2", value(), time.Time{}))
+
+			done := make(chan error)
+
+			go func() { done <- obj.Set(ctx, "key3", value(), time.Time{}) }()
+
+			if test.overflow != cache.OverflowBlock {
+				require.NoError(t, <-done)
+			}
+
+			close(gate)
+
+			if test.overflow == cache.OverflowBlock {
+				require.NoError(t, <-done)
+			}
+
+			for i, key := range test.want[1:] {
+				event := <-events
+
+				assert.Equal(t, key, event.Key)
+				assert.Equal(t, test.lost[i+1], event.Lost)
+			}
+
+			require.NoError(t, obj.Close())
+			assert.Equal(t, test.dropped, obj.Stats().Dropped)
+		})
+	}
+}
+
+func TestUnitReasonString(t *testing.T) {
+	t.Parallel()
+
+	assert.Equal(t, "set", cache.ReasonSet.String())
+	assert.Equal(t, "delete", cache.ReasonDelete.String())
+	assert.Equal(t, "expire", cache.ReasonExpire.String())
+	assert.Equal(t, "evict", cache.ReasonEvict.String())
+	assert.Equal(t, "unknown", cache.Reason(-1).String())
 }
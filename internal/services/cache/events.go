@@ -0,0 +1,207 @@
+package cache
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const (
+	ReasonSet Reason = iota
+	ReasonDelete
+	ReasonExpire
+	ReasonEvict
+	reasons = int(ReasonEvict) + 1
+)
+
+const (
+	// OverflowDropNewest loses the event that doesn't fit into the buffer.
+	OverflowDropNewest Overflow = iota
+	// OverflowDropOldest makes room for the event by losing the oldest one in the buffer.
+	OverflowDropOldest
+	// OverflowBlock makes the operation wait for room in the buffer, so no event is lost.
+	OverflowBlock
+)
+
+type (
+	// Reason tells why the key has changed.
+	Reason int
+	// Overflow is a policy for the events that don't fit into the buffer.
+	Overflow int
+	Event    struct {
+		Key    string
+		Reason Reason
+		// Deadline is the deadline of the key before the event, zero for the new and infinite keys.
+		Deadline time.Time
+		Time     time.Time
+		// Lost is the number of the events of any reason lost by the Overflow right before this one,
+		// so the listener knows that it missed the changes.
+		Lost uint64
+	}
+	// events delivers the events to the listeners in background, in order of their occurrence.
+	// The goroutine of the delivery starts with the first listener. Every event is numbered when
+	// it's queued, so the gap in the numbers delivered is the number of the lost events.
+	events struct {
+		mutex     sync.RWMutex
+		listeners [reasons][]func(Event)
+		sending   sync.Mutex
+		sent      uint64
+		delivered uint64
+		queue     chan queued
+		overflow  Overflow
+		dropped   atomic.Uint64
+		done      chan struct{}
+		stopped   chan struct{}
+		once      sync.Once
+		running   bool
+	}
+	queued struct {
+		event Event
+		seq   uint64
+	}
+)
+
+func (r Reason) String() string {
+	switch r {
+	case ReasonSet:
+		return "set"
+	case ReasonDelete:
+		return "delete"
+	case ReasonExpire:
+		return "expire"
+	case ReasonEvict:
+		return "evict"
+	}
+
+	return "unknown"
+}
+
+func newEvents(size int, overflow Overflow) *events {
+	evs := &events{
+		mutex:     sync.RWMutex{},
+		listeners: [reasons][]func(Event){},
+		sending:   sync.Mutex{},
+		sent:      0,
+		delivered: 0,
+		queue:     make(chan queued, size),
+		overflow:  overflow,
+		dropped:   atomic.Uint64{},
+		done:      make(chan struct{}),
+		stopped:   make(chan struct{}),
+		once:      sync.Once{},
+		running:   false,
+	}
+
+	return evs
+}
+
+func (e *events) listen(reason Reason, listener func(Event)) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	e.listeners[reason] = append(e.listeners[reason], listener)
+
+	if !e.running {
+		e.running = true
+
+		go e.loop()
+	}
+}
+
+// emit must not be called under the key's lock, the listener could wait for it with OverflowBlock.
+func (e *events) emit(event Event) {
+	e.mutex.RLock()
+	idle := len(e.listeners[event.Reason]) == 0
+	e.mutex.RUnlock()
+
+	// nobody listens, so nothing to deliver
+	if idle {
+		return
+	}
+
+	// the numbers are queued in order, so the sending is serialized
+	e.sending.Lock()
+	defer e.sending.Unlock()
+
+	e.sent++
+	item := queued{event: event, seq: e.sent}
+
+	switch e.overflow {
+	case OverflowBlock:
+		select {
+		case e.queue <- item:
+		case <-e.done:
+			e.dropped.Add(1)
+		}
+	case OverflowDropOldest:
+		for {
+			select {
+			case e.queue <- item:
+				return
+			default:
+			}
+
+			select {
+			case <-e.queue:
+				e.dropped.Add(1)
+			default:
+			}
+		}
+	default:
+		select {
+		case e.queue <- item:
+		default:
+			e.dropped.Add(1)
+		}
+	}
+}
+
+func (e *events) loop() {
+	defer close(e.stopped)
+
+	for {
+		select {
+		case item := <-e.queue:
+			e.deliver(item)
+		case <-e.done:
+			// the events that happened before the stop are still delivered
+			for {
+				select {
+				case item := <-e.queue:
+					e.deliver(item)
+				default:
+					return
+				}
+			}
+		}
+	}
+}
+
+// deliver is called by the loop only, so the delivered number needs no lock.
+func (e *events) deliver(item queued) {
+	event := item.event
+	event.Lost = item.seq - e.delivered - 1
+	e.delivered = item.seq
+
+	e.mutex.RLock()
+	listeners := e.listeners[event.Reason]
+	e.mutex.RUnlock()
+
+	for _, listener := range listeners {
+		listener(event)
+	}
+}
+
+func (e *events) stop() {
+	e.once.Do(func() {
+		close(e.done)
+
+		e.mutex.RLock()
+		running := e.running
+		e.mutex.RUnlock()
+
+		if running {
+			<-e.stopped
+		}
+	})
+}
@@ -0,0 +1,238 @@
+package cache
+
+import (
+	"container/heap"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/pkg/clock"
+)
+
+const (
+	retryMinBackoff = time.Millisecond
+	retryMaxBackoff = time.Second
+	retryMaxShift   = 10
+)
+
+type (
+	expireFunc func(key string, deadline time.Time) error
+	schedule   struct {
+		key      string
+		deadline time.Time
+		at       time.Time
+		attempt  int
+		index    int
+	}
+	schedules []*schedule
+	// expiry keeps deadlines of the keys in a min-heap and hands over the due keys
+	// to a fixed pool of workers, so number of goroutines doesn't grow with number of keys.
+	expiry struct {
+		expire expireFunc
+		clock  clock.Clock
+		mutex  sync.Mutex
+		heap   schedules
+		keys   map[string]*schedule
+		wakeup chan struct{}
+		queue  chan *schedule
+		done   chan struct{}
+		once   sync.Once
+		waiter sync.WaitGroup
+	}
+)
+
+func (s schedules) Len() int { return len(s) }
+
+func (s schedules) Less(i, j int) bool { return s[i].at.Before(s[j].at) }
+
+func (s schedules) Swap(i, j int) {
+	s[i], s[j] = s[j], s[i]
+	s[i].index = i
+	s[j].index = j
+}
+
+func (s *schedules) Push(x any) {
+	item, _ := x.(*schedule)
+	item.index = len(*s)
+	*s = append(*s, item)
+}
+
+func (s *schedules) Pop() any {
+	old := *s
+	last := len(old) - 1
+	item := old[last]
+	old[last] = nil
+	*s = old[:last]
+
+	return item
+}
+
+func newExpiry(workers int, clock clock.Clock, expire expireFunc) *expiry {
+	exp := &expiry{
+		expire: expire,
+		clock:  clock,
+		mutex:  sync.Mutex{},
+		heap:   make(schedules, 0),
+		keys:   make(map[string]*schedule),
+		wakeup: make(chan struct{}, 1),
+		queue:  make(chan *schedule, workers),
+		done:   make(chan struct{}),
+		once:   sync.Once{},
+		waiter: sync.WaitGroup{},
+	}
+
+	exp.waiter.Add(workers + 1)
+
+	go exp.loop()
+
+	for range workers {
+		go exp.work()
+	}
+
+	return exp
+}
+
+// schedule sets (or moves) the deadline of the key, zero deadline removes it.
+func (e *expiry) schedule(key string, deadline time.Time) {
+	if deadline.IsZero() {
+		e.cancel(key)
+
+		return
+	}
+
+	e.mutex.Lock()
+
+	if item, ok := e.keys[key]; ok {
+		item.deadline = deadline
+		item.at = deadline
+		item.attempt = 0
+
+		heap.Fix(&e.heap, item.index)
+	} else {
+		item = &schedule{key: key, deadline: deadline, at: deadline, attempt: 0, index: 0}
+		e.keys[key] = item
+
+		heap.Push(&e.heap, item)
+	}
+
+	e.mutex.Unlock()
+	e.notify()
+}
+
+func (e *expiry) cancel(key string) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	if item, ok := e.keys[key]; ok {
+		heap.Remove(&e.heap, item.index)
+		delete(e.keys, key)
+	}
+}
+
+func (e *expiry) stop() {
+	e.once.Do(func() {
+		close(e.done)
+		e.waiter.Wait()
+	})
+}
+
+func (e *expiry) notify() {
+	select {
+	case e.wakeup <- struct{}{}:
+	default:
+	}
+}
+
+// collect pops all the due schedules and returns the moment of the next one.
+func (e *expiry) collect(now time.Time) ([]*schedule, time.Time, bool) {
+	e.mutex.Lock()
+	defer e.mutex.Unlock()
+
+	batch := make([]*schedule, 0)
+
+	for e.heap.Len() > 0 && !e.heap[0].at.After(now) {
+		item, _ := heap.Pop(&e.heap).(*schedule)
+		delete(e.keys, item.key)
+
+		batch = append(batch, item)
+	}
+
+	if e.heap.Len() == 0 {
+		return batch, time.Time{}, false
+	}
+
+	return batch, e.heap[0].at, true
+}
+
+func (e *expiry) loop() {
+	defer e.waiter.Done()
+
+	timer := e.clock.Timer(e.clock.Now())
+	defer timer.Stop()
+
+	for {
+		batch, next, ok := e.collect(e.clock.Now())
+
+		for _, item := range batch {
+			select {
+			case e.queue <- item:
+			case <-e.done:
+				return
+			}
+		}
+
+		if len(batch) > 0 {
+			continue
+		}
+
+		var wait <-chan time.Time
+
+		if ok {
+			timer.Reset(next)
+
+			wait = timer.C()
+		}
+
+		select {
+		case <-wait:
+		case <-e.wakeup:
+		case <-e.done:
+			return
+		}
+	}
+}
+
+func (e *expiry) work() {
+	defer e.waiter.Done()
+
+	for {
+		select {
+		case item := <-e.queue:
+			if err := e.expire(item.key, item.deadline); err != nil {
+				e.retry(item)
+			}
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// retry puts back the failed schedule with exponential backoff, unless the key was rescheduled meanwhile.
+func (e *expiry) retry(item *schedule) {
+	backoff := min(retryMinBackoff<<min(item.attempt, retryMaxShift), retryMaxBackoff)
+
+	e.mutex.Lock()
+
+	if _, ok := e.keys[item.key]; ok {
+		e.mutex.Unlock()
+
+		return
+	}
+
+	item.at = e.clock.Now().Add(backoff)
+	item.attempt++
+	e.keys[item.key] = item
+
+	heap.Push(&e.heap, item)
+	e.mutex.Unlock()
+	e.notify()
+}
@@ -0,0 +1,56 @@
+package cache
+
+import (
+	"context"
+	"sync"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+type (
+	call struct {
+		done chan struct{}
+		val  []byte
+		err  error
+	}
+	// flight collapses concurrent calls for the same key into a single one.
+	flight struct {
+		mutex sync.Mutex
+		calls map[string]*call
+	}
+)
+
+func newFlight() *flight {
+	return &flight{mutex: sync.Mutex{}, calls: make(map[string]*call)}
+}
+
+// do runs fn once for all the concurrent callers of the key. The call doesn't depend on context
+// of the caller who started it, so every caller waits as long as its own context allows.
+func (f *flight) do(ctx context.Context, key string, fn func(ctx context.Context) ([]byte, error)) ([]byte, error) {
+	f.mutex.Lock()
+
+	cur, ok := f.calls[key]
+	if !ok {
+		cur = &call{done: make(chan struct{}), val: nil, err: nil}
+		f.calls[key] = cur
+
+		go func() {
+			cur.val, cur.err = fn(context.WithoutCancel(ctx))
+
+			f.mutex.Lock()
+			delete(f.calls, key)
+			f.mutex.Unlock()
+
+			close(cur.done)
+		}()
+	}
+
+	f.mutex.Unlock()
+
+	select {
+	case <-cur.done:
+		return cur.val, cur.err
+	case <-ctx.Done():
+		return nil, domain.ErrContextTimeout
+	}
+}
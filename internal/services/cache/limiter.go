@@ -0,0 +1,485 @@
+package cache
+
+import (
+	"container/list"
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/clock"
+	"github.com/therenotomorrow/apicache/pkg/drivers"
+)
+
+const (
+	backoffRatio = 0.9
+	levels       = int(domain.PriorityHigh) + 1
+)
+
+type (
+	// client keeps the queues of the single client in the pool, one per priority.
+	client struct {
+		id     string
+		weight float64
+		queues [levels]*list.List
+		last   [levels]float64
+		used   int
+	}
+	// pool is a semaphore whose size follows the driver health by AIMD:
+	// every healthy call grows the limit by 1/limit (so about one permit per limit calls),
+	// every slow or failed call shrinks it multiplicatively, both within [floor, ceiling].
+	// Higher priorities are served first. Clients of the same priority are served by
+	// start-time fair queuing: every ticket is tagged by the virtual time it deserves
+	// to start, so the client of weight 2 gets twice as many permits as the client
+	// of weight 1, no matter how many requests each of them sends.
+	pool struct {
+		err      error
+		timeout  time.Duration
+		limit    float64
+		floor    float64
+		ceiling  float64
+		used     int
+		waiting  int
+		virtual  [levels]float64
+		clients  map[string]*client
+		above    time.Time
+		dropping bool
+	}
+	// ticket is a place in the queue of the client, the permit could be lent by the other pool.
+	ticket struct {
+		ready    chan struct{}
+		elem     *list.Element
+		tag      float64
+		level    domain.Priority
+		enqueued time.Time
+		owner    *pool
+		client   *client
+		from     *pool
+		shed     bool
+		probe    bool
+	}
+	// limiter admits the calls through the pools, optionally lending idle permits between them.
+	limiter struct {
+		mutex    sync.Mutex
+		pools    []*pool
+		clock    clock.Clock
+		target   time.Duration
+		borrow   bool
+		weights  map[string]int
+		share    float64
+		sojourn  time.Duration
+		interval time.Duration
+	}
+	// observer is a Driver that reports latency and errors of the calls to the limiter and the breaker.
+	observer struct {
+		driver      Driver
+		native      ExpiringDriver
+		conditional ConditionalDriver
+		limiter     *limiter
+		breaker     *breaker
+		clock       clock.Clock
+	}
+)
+
+// newPool creates the pool, err explains the exhaustion of the pool to the caller.
+func newPool(floor int, ceiling int, timeout time.Duration, err error) *pool {
+	return &pool{
+		err:      err,
+		timeout:  timeout,
+		limit:    float64(ceiling),
+		floor:    float64(floor),
+		ceiling:  float64(ceiling),
+		used:     0,
+		waiting:  0,
+		virtual:  [levels]float64{},
+		clients:  make(map[string]*client),
+		above:    time.Time{},
+		dropping: false,
+	}
+}
+
+func (p *pool) current() int {
+	return int(p.limit)
+}
+
+func newLimiter(cfg Config, pools ...*pool) *limiter {
+	return &limiter{
+		mutex:    sync.Mutex{},
+		pools:    pools,
+		clock:    cfg.Clock,
+		target:   cfg.TargetLatency,
+		borrow:   cfg.Borrow,
+		weights:  cfg.Weights,
+		share:    cfg.ClientShare,
+		sojourn:  cfg.ShedTarget,
+		interval: cfg.ShedInterval,
+	}
+}
+
+// wait enqueues the caller into the pool, ticket is ready when permit is granted or the call is shed.
+func (l *limiter) wait(owner *pool, id string, level domain.Priority) *ticket {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.clock.Now()
+	tick := &ticket{
+		ready:    make(chan struct{}),
+		elem:     nil,
+		tag:      0,
+		level:    level,
+		enqueued: now,
+		owner:    owner,
+		client:   nil,
+		from:     nil,
+		shed:     false,
+		probe:    false,
+	}
+
+	// the call is going to be admitted at once, so there is no standing queue anymore
+	if owner.waiting == 0 && owner.used < owner.current() {
+		owner.above = time.Time{}
+		owner.dropping = false
+	}
+
+	if owner.dropping && level == domain.PriorityLow {
+		tick.shed = true
+		close(tick.ready)
+
+		return tick
+	}
+
+	cli, ok := owner.clients[id]
+	if !ok {
+		cli = &client{id: id, weight: l.weight(id), queues: [levels]*list.List{}, last: [levels]float64{}, used: 0}
+		for i := range cli.queues {
+			cli.queues[i] = list.New()
+		}
+
+		owner.clients[id] = cli
+	}
+
+	// idle client starts from the current virtual time, so it can't save up the credit
+	cli.last[level] = max(owner.virtual[level], cli.last[level]) + 1/cli.weight
+
+	tick.tag = cli.last[level]
+	tick.client = cli
+	tick.elem = cli.queues[level].PushBack(tick)
+	owner.waiting++
+
+	l.grant()
+
+	return tick
+}
+
+// cancel gives up the waiting, the permit granted meanwhile is returned back.
+func (l *limiter) cancel(tick *ticket) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	switch {
+	case tick.shed:
+		return
+	case tick.from != nil:
+		tick.from.used--
+		tick.client.used--
+	default:
+		tick.client.queues[tick.level].Remove(tick.elem)
+		tick.owner.waiting--
+	}
+
+	l.forget(tick)
+	l.grant()
+}
+
+func (l *limiter) release(tick *ticket) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	tick.from.used--
+	tick.client.used--
+
+	l.forget(tick)
+	l.grant()
+}
+
+// observe adapts all the pools, because they share the same driver.
+func (l *limiter) observe(latency time.Duration, failed bool) {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	for _, pool := range l.pools {
+		if failed || latency > l.target {
+			pool.limit = max(pool.floor, pool.limit*backoffRatio)
+		} else {
+			pool.limit = min(pool.ceiling, pool.limit+1/pool.limit)
+		}
+	}
+
+	l.grant()
+}
+
+func (l *limiter) current(pool *pool) int {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	return pool.current()
+}
+
+// grant hands out the free permits to the fairest waiters, then lends the idle
+// permits to the pools that are still waiting. Must be called under the mutex.
+func (l *limiter) grant() {
+	for _, pool := range l.pools {
+		l.shed(pool)
+
+		for pool.used < pool.current() {
+			tick := l.next(pool)
+			if tick == nil {
+				break
+			}
+
+			l.give(tick, pool)
+		}
+	}
+
+	if !l.borrow {
+		return
+	}
+
+	for _, pool := range l.pools {
+		for _, lender := range l.pools {
+			for lender != pool && lender.used < lender.current() && l.next(lender) == nil {
+				tick := l.next(pool)
+				if tick == nil {
+					break
+				}
+
+				l.give(tick, lender)
+			}
+		}
+	}
+}
+
+// next returns the ticket of the highest priority and the least tag among
+// the clients that didn't reach their share.
+func (l *limiter) next(pool *pool) *ticket {
+	for level := levels - 1; level >= 0; level-- {
+		var best *ticket
+
+		for _, cli := range pool.clients {
+			if cli.queues[level].Len() == 0 || l.capped(pool, cli) {
+				continue
+			}
+
+			tick, _ := cli.queues[level].Front().Value.(*ticket)
+
+			// ties are broken by identity to keep the order stable
+			if best == nil || tick.tag < best.tag || (tick.tag == best.tag && cli.id < best.client.id) {
+				best = tick
+			}
+		}
+
+		if best != nil {
+			return best
+		}
+	}
+
+	return nil
+}
+
+// capped tells whether the client holds its bounded share of the pool already.
+// Anonymous calls are not capped, they come from the cache users that don't identify clients.
+func (l *limiter) capped(pool *pool, cli *client) bool {
+	if cli.id == "" || l.share == 0 {
+		return false
+	}
+
+	return cli.used >= max(1, int(pool.limit*l.share*cli.weight))
+}
+
+func (l *limiter) give(tick *ticket, lender *pool) {
+	now := l.clock.Now()
+
+	tick.client.queues[tick.level].Remove(tick.elem)
+	tick.owner.waiting--
+	tick.owner.virtual[tick.level] = max(tick.owner.virtual[tick.level], tick.tag)
+	tick.from = lender
+	tick.client.used++
+	lender.used++
+
+	l.control(tick.owner, now.Sub(tick.enqueued), now)
+	close(tick.ready)
+}
+
+// control is a CoDel rule: the pool is overloaded when sojourn time of the granted
+// calls stays above the target for the whole interval, the single fast call resets it.
+func (l *limiter) control(pool *pool, sojourn time.Duration, now time.Time) {
+	if l.sojourn == 0 {
+		return
+	}
+
+	switch {
+	case sojourn < l.sojourn:
+		pool.above = time.Time{}
+		pool.dropping = false
+	case pool.above.IsZero():
+		pool.above = now.Add(l.interval)
+	case !now.Before(pool.above):
+		pool.dropping = true
+	}
+}
+
+// shed fails the queued low priority calls that already waited longer than the target
+// while the pool is overloaded, so they don't hold their clients until the timeout.
+func (l *limiter) shed(pool *pool) {
+	if !pool.dropping {
+		return
+	}
+
+	now := l.clock.Now()
+
+	for _, cli := range pool.clients {
+		queue := cli.queues[domain.PriorityLow]
+
+		for elem := queue.Front(); elem != nil; {
+			tick, _ := elem.Value.(*ticket)
+			elem = elem.Next()
+
+			if now.Sub(tick.enqueued) < l.sojourn {
+				continue
+			}
+
+			queue.Remove(tick.elem)
+			pool.waiting--
+			tick.shed = true
+
+			close(tick.ready)
+			l.forget(tick)
+		}
+	}
+}
+
+// forget drops the client that has nothing in the pool, so the map doesn't grow with every client seen.
+func (l *limiter) forget(tick *ticket) {
+	if tick.client.used > 0 {
+		return
+	}
+
+	for _, queue := range tick.client.queues {
+		if queue.Len() > 0 {
+			return
+		}
+	}
+
+	delete(tick.owner.clients, tick.client.id)
+}
+
+func (l *limiter) weight(id string) float64 {
+	if weight, ok := l.weights[id]; ok {
+		return float64(weight)
+	}
+
+	return 1
+}
+
+func (o *observer) Get(ctx context.Context, key string) (string, error) {
+	start := o.clock.Now()
+	val, err := o.driver.Get(ctx, key)
+
+	o.observe(ctx, start, err)
+
+	return val, err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) Set(ctx context.Context, key string, val string) error {
+	start := o.clock.Now()
+	err := o.driver.Set(ctx, key, val)
+
+	o.observe(ctx, start, err)
+
+	return err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) Del(ctx context.Context, key string) error {
+	start := o.clock.Now()
+	err := o.driver.Del(ctx, key)
+
+	o.observe(ctx, start, err)
+
+	return err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) SetEx(ctx context.Context, key string, val string, ttl time.Duration) error {
+	start := o.clock.Now()
+	err := o.native.SetEx(ctx, key, val, ttl)
+
+	o.observe(ctx, start, err)
+
+	return err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) TTL(ctx context.Context, key string) (time.Duration, error) {
+	start := o.clock.Now()
+	ttl, err := o.native.TTL(ctx, key)
+
+	o.observe(ctx, start, err)
+
+	return ttl, err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	start := o.clock.Now()
+	err := o.native.Expire(ctx, key, ttl)
+
+	o.observe(ctx, start, err)
+
+	return err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) Persist(ctx context.Context, key string) error {
+	start := o.clock.Now()
+	err := o.native.Persist(ctx, key)
+
+	o.observe(ctx, start, err)
+
+	return err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	start := o.clock.Now()
+	ok, err := o.conditional.SetNX(ctx, key, val, ttl)
+
+	o.observe(ctx, start, err)
+
+	return ok, err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	start := o.clock.Now()
+	ok, err := o.conditional.SetXX(ctx, key, val, ttl)
+
+	o.observe(ctx, start, err)
+
+	return ok, err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) DelXX(ctx context.Context, key string) (bool, error) {
+	start := o.clock.Now()
+	ok, err := o.conditional.DelXX(ctx, key)
+
+	o.observe(ctx, start, err)
+
+	return ok, err //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) Close() error {
+	return o.driver.Close() //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (o *observer) observe(ctx context.Context, start time.Time, err error) {
+	// missed key and cancelled request say nothing about the driver health
+	failed := err != nil && !errors.Is(err, drivers.ErrNotExist) && !errors.Is(err, context.Canceled)
+
+	o.limiter.observe(o.clock.Now().Sub(start), failed)
+	o.breaker.report(probing(ctx), failed)
+}
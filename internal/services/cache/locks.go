@@ -0,0 +1,25 @@
+package cache
+
+import "sync"
+
+const (
+	stripes     = 256
+	offset32    = 2166136261
+	primeNumber = 16777619
+)
+
+// locks serializes operations on the same key, so the driver and the index of the keys
+// always change together. Keys share a fixed number of mutexes to keep memory flat.
+type locks [stripes]sync.RWMutex
+
+func (l *locks) of(key string) *sync.RWMutex {
+	// inlined FNV-1a to not allocate a hasher on every call
+	hash := uint32(offset32)
+
+	for idx := range len(key) {
+		hash ^= uint32(key[idx])
+		hash *= primeNumber
+	}
+
+	return &l[hash%stripes]
+}
@@ -0,0 +1,172 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+
+	"github.com/therenotomorrow/apicache/pkg/clock"
+	"github.com/therenotomorrow/apicache/pkg/drivers"
+)
+
+// retrier is a Driver that repeats the calls failed with drivers.ErrTransient. All the calls
+// of the Driver are idempotent, so it's safe to repeat them. The calls of the ConditionalDriver
+// are not: the failed attempt could have written the key, so the next one would fail the condition.
+// The pause between the attempts grows exponentially up to the ceiling and is randomized (full jitter),
+// so the clients don't come back all at once. It never sleeps past the deadline of the context.
+type retrier struct {
+	driver      Driver
+	native      ExpiringDriver
+	conditional ConditionalDriver
+	clock       clock.Clock
+	retries     int
+	backoff     time.Duration
+	ceiling     time.Duration
+}
+
+func (r *retrier) Get(ctx context.Context, key string) (string, error) {
+	var val string
+
+	err := r.retry(ctx, func() error {
+		var err error
+
+		val, err = r.driver.Get(ctx, key)
+
+		return err //nolint:wrapcheck // decorator is transparent for the errors
+	})
+
+	return val, err
+}
+
+func (r *retrier) Set(ctx context.Context, key string, val string) error {
+	return r.retry(ctx, func() error {
+		return r.driver.Set(ctx, key, val) //nolint:wrapcheck // decorator is transparent for the errors
+	})
+}
+
+func (r *retrier) Del(ctx context.Context, key string) error {
+	return r.retry(ctx, func() error {
+		return r.driver.Del(ctx, key) //nolint:wrapcheck // decorator is transparent for the errors
+	})
+}
+
+// SetEx keeps the deadline of the key, so the repeated call doesn't prolong its life.
+func (r *retrier) SetEx(ctx context.Context, key string, val string, ttl time.Duration) error {
+	deadline := r.clock.Now().Add(ttl)
+
+	var last error
+
+	return r.retry(ctx, func() error {
+		left := deadline.Sub(r.clock.Now())
+		if left <= 0 {
+			// the key has expired while retrying, the last failure is the answer
+			return last
+		}
+
+		last = r.native.SetEx(ctx, key, val, left)
+
+		return last //nolint:wrapcheck // decorator is transparent for the errors
+	})
+}
+
+func (r *retrier) TTL(ctx context.Context, key string) (time.Duration, error) {
+	var ttl time.Duration
+
+	err := r.retry(ctx, func() error {
+		var err error
+
+		ttl, err = r.native.TTL(ctx, key)
+
+		return err //nolint:wrapcheck // decorator is transparent for the errors
+	})
+
+	return ttl, err
+}
+
+// Expire keeps the deadline of the key the same way as SetEx, the one passed while retrying deletes the key.
+func (r *retrier) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	deadline := r.clock.Now().Add(ttl)
+
+	return r.retry(ctx, func() error {
+		left := deadline.Sub(r.clock.Now())
+
+		return r.native.Expire(ctx, key, left) //nolint:wrapcheck // decorator is transparent for the errors
+	})
+}
+
+func (r *retrier) Persist(ctx context.Context, key string) error {
+	return r.retry(ctx, func() error {
+		return r.native.Persist(ctx, key) //nolint:wrapcheck // decorator is transparent for the errors
+	})
+}
+
+// SetNX is called once, see retrier.
+func (r *retrier) SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	return r.conditional.SetNX(ctx, key, val, ttl) //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+// SetXX is called once, see retrier.
+func (r *retrier) SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	return r.conditional.SetXX(ctx, key, val, ttl) //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+// DelXX is called once, see retrier.
+func (r *retrier) DelXX(ctx context.Context, key string) (bool, error) {
+	return r.conditional.DelXX(ctx, key) //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (r *retrier) Close() error {
+	return r.driver.Close() //nolint:wrapcheck // decorator is transparent for the errors
+}
+
+func (r *retrier) retry(ctx context.Context, call func() error) error {
+	err := call()
+
+	for attempt := 0; attempt < r.retries && errors.Is(err, drivers.ErrTransient); attempt++ {
+		if !r.sleep(ctx, r.delay(attempt)) {
+			break
+		}
+
+		err = call()
+	}
+
+	return err
+}
+
+// delay is a random pause within the exponential backoff of the attempt.
+func (r *retrier) delay(attempt int) time.Duration {
+	backoff := r.backoff
+
+	for range attempt {
+		backoff *= 2
+
+		if backoff >= r.ceiling {
+			backoff = r.ceiling
+
+			break
+		}
+	}
+
+	return rand.N(backoff + 1) //nolint:gosec // jitter doesn't need the secure random
+}
+
+// sleep tells whether the pause is over, there is no point to wait when the context expires meanwhile.
+func (r *retrier) sleep(ctx context.Context, delay time.Duration) bool {
+	at := r.clock.Now().Add(delay)
+
+	deadline, ok := ctx.Deadline()
+	if ok && at.After(deadline) {
+		return false
+	}
+
+	timer := r.clock.Timer(at)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,123 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+// last is the known value of the key that could be served stale until the end of the grace window.
+type last struct {
+	val   []byte
+	until time.Time
+}
+
+// keep remembers the value of the key, must be called under the key's lock.
+func (c *Cache) keep(key string, val []byte, deadline time.Time) {
+	if c.grace == nil {
+		return
+	}
+
+	var until time.Time
+
+	if !deadline.IsZero() {
+		until = deadline.Add(c.cfg.StaleGrace)
+	}
+
+	c.lasts.Store(key, last{val: append([]byte(nil), val...), until: until})
+	// zero deadline keeps the value as long as the key lives
+	c.grace.schedule(key, until)
+}
+
+// prolong moves the grace window after the new deadline of the key, must be called under the key's lock.
+func (c *Cache) prolong(key string, deadline time.Time) {
+	if c.grace == nil {
+		return
+	}
+
+	val, ok := c.lasts.Load(key)
+	if !ok {
+		return
+	}
+
+	var until time.Time
+
+	if !deadline.IsZero() {
+		until = deadline.Add(c.cfg.StaleGrace)
+	}
+
+	old, _ := val.(last)
+
+	c.lasts.Store(key, last{val: old.val, until: until})
+	c.grace.schedule(key, until)
+}
+
+// drop forgets the value of the key, must be called under the key's lock.
+func (c *Cache) drop(key string) {
+	if c.grace == nil {
+		return
+	}
+
+	c.lasts.Delete(key)
+	c.grace.cancel(key)
+}
+
+// lapse is called by GC when the grace window of the key is over.
+func (c *Cache) lapse(key string, until time.Time) error {
+	lock := c.locks.of(key)
+	lock.Lock()
+	defer lock.Unlock()
+
+	val, ok := c.lasts.Load(key)
+	old, _ := val.(last)
+
+	// key was set again after GC took it
+	if ok && old.until.Equal(until) {
+		c.lasts.Delete(key)
+	}
+
+	return nil
+}
+
+// stale returns the last known value of the key instead of the error: the key has expired
+// or the driver has failed. The cache that is closed or overloaded doesn't serve at all.
+func (c *Cache) stale(key string, err error) ([]byte, bool) {
+	if c.grace == nil || errors.Is(err, domain.ErrClosed) || errors.Is(err, domain.ErrShed) ||
+		errors.Is(err, domain.ErrConnTimeout) || errors.Is(err, domain.ErrContextTimeout) {
+		return nil, false
+	}
+
+	lock := c.locks.of(key)
+	lock.RLock()
+	defer lock.RUnlock()
+
+	val, ok := c.lasts.Load(key)
+	if !ok {
+		return nil, false
+	}
+
+	old, _ := val.(last)
+	if !old.until.IsZero() && !c.cfg.Clock.Now().Before(old.until) {
+		return nil, false
+	}
+
+	return old.val, true
+}
+
+// refresh loads the stale key in background, so the next readers get the fresh value.
+func (c *Cache) refresh(ctx context.Context, key string) {
+	if c.cfg.Loader == nil {
+		return
+	}
+
+	// the reader doesn't wait for the refresh, so it shouldn't outrun the readers either
+	ctx = domain.WithPriority(context.WithoutCancel(ctx), domain.PriorityLow)
+
+	go func() {
+		_, _ = c.loads.do(ctx, key, func(ctx context.Context) ([]byte, error) {
+			return c.load(ctx, key)
+		})
+	}()
+}
@@ -0,0 +1,228 @@
+package feed
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+)
+
+const (
+	defaultSize = 1024
+	// lost is the kind of the change that stands for the events lost by the cache, it's never watched.
+	lost = "lost"
+)
+
+var ErrInvalidSize = errors.New("invalid Size")
+
+type (
+	// Config of the feed, Size is the number of the last changes kept for the watchers to resume.
+	Config struct {
+		Size int
+	}
+	// Feed keeps the last changes of the cache in the ring and wakes up the watchers on every new one.
+	// The watcher that falls behind the ring gets domain.ErrOffsetGone, because the changes are lost for it.
+	// So does the one behind the events lost by the cache (see cache.Event), they take the ID of their own.
+	Feed struct {
+		mutex    sync.Mutex
+		ring     []domain.Change
+		last     uint64
+		lost     uint64
+		watchers map[*watcher]struct{}
+		done     chan struct{}
+		once     sync.Once
+	}
+	watcher struct {
+		feed   *Feed
+		prefix string
+		cursor uint64
+		wake   chan struct{}
+		once   sync.Once
+	}
+)
+
+func New(cfg Config) (*Feed, error) {
+	if cfg.Size < 0 {
+		return nil, ErrInvalidSize
+	}
+
+	if cfg.Size == 0 {
+		cfg.Size = defaultSize
+	}
+
+	feed := &Feed{
+		mutex:    sync.Mutex{},
+		ring:     make([]domain.Change, cfg.Size),
+		last:     0,
+		lost:     0,
+		watchers: make(map[*watcher]struct{}),
+		done:     make(chan struct{}),
+		once:     sync.Once{},
+	}
+
+	return feed, nil
+}
+
+func MustNew(cfg Config) *Feed {
+	obj, err := New(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+// Follow records the sets, deletions and expirations of the cache.
+func (f *Feed) Follow(cache *cache.Cache) {
+	cache.OnSet(f.Record)
+	cache.OnDelete(f.Record)
+	cache.OnExpire(f.Record)
+}
+
+// Record assigns the next ID to the event and wakes up the watchers. The internal keys (see domain.Separator),
+// e.g. the ones of the namespaces, are not the changes of the flat keys, so they are skipped. The events lost
+// before this one are recorded anyway, they could be the changes of the flat keys.
+func (f *Feed) Record(event cache.Event) {
+	internal := strings.Contains(event.Key, domain.Separator)
+	if internal && event.Lost == 0 {
+		return
+	}
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	if event.Lost > 0 {
+		f.last++
+		f.lost = f.last
+		f.ring[f.slot(f.last)] = domain.Change{ID: f.last, Kind: lost, Key: "", Time: event.Time}
+	}
+
+	if !internal {
+		f.last++
+		f.ring[f.slot(f.last)] = domain.Change{ID: f.last, Kind: event.Reason.String(), Key: event.Key, Time: event.Time}
+	}
+
+	for watcher := range f.watchers {
+		select {
+		case watcher.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Watch follows the changes of the keys with the prefix after the given ID, zero means from now on.
+func (f *Feed) Watch(prefix string, after uint64) (domain.Changes, error) {
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	select {
+	case <-f.done:
+		return nil, domain.ErrClosed
+	default:
+	}
+
+	if after == 0 {
+		after = f.last
+	}
+
+	if !f.retains(after) {
+		return nil, domain.ErrOffsetGone
+	}
+
+	watcher := &watcher{feed: f, prefix: prefix, cursor: after, wake: make(chan struct{}, 1), once: sync.Once{}}
+	f.watchers[watcher] = struct{}{}
+
+	return watcher, nil
+}
+
+// Close ends the watching, so the long-lived streams don't hold the server shutdown.
+func (f *Feed) Close() error {
+	err := domain.ErrClosed
+
+	f.once.Do(func() {
+		close(f.done)
+
+		err = nil
+	})
+
+	return err
+}
+
+// since returns the changes after the cursor. Must be called under the mutex.
+func (f *Feed) since(cursor uint64) ([]domain.Change, error) {
+	if !f.retains(cursor) {
+		return nil, domain.ErrOffsetGone
+	}
+
+	changes := make([]domain.Change, 0, f.last-cursor)
+	for id := cursor + 1; id <= f.last; id++ {
+		changes = append(changes, f.ring[f.slot(id)])
+	}
+
+	return changes, nil
+}
+
+// retains tells whether all the changes after the cursor are still in the ring and none of them was lost.
+// The cursor ahead of the feed is unknown to it, e.g. it was seen before the restart.
+func (f *Feed) retains(cursor uint64) bool {
+	return cursor <= f.last && f.last-cursor <= uint64(len(f.ring)) && cursor >= f.lost
+}
+
+func (f *Feed) slot(id uint64) int {
+	return int((id - 1) % uint64(len(f.ring)))
+}
+
+func (w *watcher) Next(ctx context.Context) ([]domain.Change, error) {
+	for {
+		changes, err := w.poll()
+		if err != nil || len(changes) > 0 {
+			return changes, err
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("%w", ctx.Err())
+		case <-w.feed.done:
+			return nil, domain.ErrClosed
+		case <-w.wake:
+		}
+	}
+}
+
+func (w *watcher) Close() error {
+	w.once.Do(func() {
+		w.feed.mutex.Lock()
+		defer w.feed.mutex.Unlock()
+
+		delete(w.feed.watchers, w)
+	})
+
+	return nil
+}
+
+// poll moves the cursor over the recorded changes, keeping the ones with the prefix.
+func (w *watcher) poll() ([]domain.Change, error) {
+	w.feed.mutex.Lock()
+	defer w.feed.mutex.Unlock()
+
+	changes, err := w.feed.since(w.cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	matched := changes[:0]
+
+	for _, change := range changes {
+		w.cursor = change.ID
+
+		if strings.HasPrefix(change.Key, w.prefix) {
+			matched = append(matched, change)
+		}
+	}
+
+	return matched, nil
+}
@@ -0,0 +1,211 @@
+package feed_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/internal/services/feed"
+	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+var now = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func record(obj *feed.Feed, reason cache.Reason, keys ...string) {
+	for _, key := range keys {
+		obj.Record(cache.Event{Key: key, Reason: reason, Deadline: time.Time{}, Time: now, Lost: 0})
+	}
+}
+
+func change(id uint64, kind string, key string) domain.Change {
+	return domain.Change{ID: id, Kind: kind, Key: key, Time: now}
+}
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	obj, err := feed.New(feed.Config{Size: -1})
+
+	toolkit.Assert(t, toolkit.Got(err, obj), toolkit.Want[*feed.Feed](nil, feed.ErrInvalidSize))
+
+	obj, err = feed.New(feed.Config{Size: 0})
+
+	require.NoError(t, err)
+	assert.NotNil(t, obj)
+}
+
+func TestUnitMustNew(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() { _ = feed.MustNew(feed.Config{Size: -1}) })
+	require.NotPanics(t, func() { _ = feed.MustNew(feed.Config{Size: 1}) })
+}
+
+func TestUnitFeedWatch(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := feed.MustNew(feed.Config{Size: 3})
+
+	record(obj, cache.ReasonSet, "user:1", "post:1")
+
+	// zero means from now on, the recorded changes are not seen
+	fresh, err := obj.Watch("user:", 0)
+	require.NoError(t, err)
+
+	resumed, err := obj.Watch("", 1)
+	require.NoError(t, err)
+
+	record(obj, cache.ReasonDelete, "user:1")
+
+	got, err := fresh.Next(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Change{change(3, "delete", "user:1")}, got)
+
+	got, err = resumed.Next(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Change{change(2, "set", "post:1"), change(3, "delete", "user:1")}, got)
+
+	// the changes with other prefixes move the cursor but don't wake up the caller
+	go record(obj, cache.ReasonSet, "post:2", "user:2")
+
+	got, err = fresh.Next(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Change{change(5, "set", "user:2")}, got)
+
+	require.NoError(t, fresh.Close())
+	require.NoError(t, resumed.Close())
+}
+
+func TestUnitFeedWatchGone(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := feed.MustNew(feed.Config{Size: 2})
+
+	record(obj, cache.ReasonSet, "key1", "key2", "key3", "key4")
+
+	// the second change is out of the ring already
+	changes, err := obj.Watch("", 1)
+
+	toolkit.Assert(t, toolkit.Got(err, changes), toolkit.Want[domain.Changes](nil, domain.ErrOffsetGone))
+
+	// the offset ahead of the feed is unknown to it
+	changes, err = obj.Watch("", 5)
+
+	toolkit.Assert(t, toolkit.Got(err, changes), toolkit.Want[domain.Changes](nil, domain.ErrOffsetGone))
+
+	changes, err = obj.Watch("", 2)
+	require.NoError(t, err)
+
+	// the slow watcher falls behind the ring
+	record(obj, cache.ReasonSet, "key5")
+
+	got, err := changes.Next(ctx)
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]domain.Change](nil, domain.ErrOffsetGone))
+}
+
+func TestUnitFeedWatchLost(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := feed.MustNew(feed.Config{Size: 10})
+
+	record(obj, cache.ReasonSet, "key1")
+
+	live, err := obj.Watch("", 0)
+	require.NoError(t, err)
+
+	// the lost events take the second ID, the internal key doesn't take any
+	obj.Record(cache.Event{Key: "ns\x1f0\x1fkey", Reason: cache.ReasonSet, Deadline: time.Time{}, Time: now, Lost: 2})
+	record(obj, cache.ReasonSet, "key2")
+
+	got, err := live.Next(ctx)
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]domain.Change](nil, domain.ErrOffsetGone))
+
+	// the watcher behind the lost events has to start over, the one after them resumes
+	changes, err := obj.Watch("", 1)
+
+	toolkit.Assert(t, toolkit.Got(err, changes), toolkit.Want[domain.Changes](nil, domain.ErrOffsetGone))
+
+	changes, err = obj.Watch("", 2)
+	require.NoError(t, err)
+
+	got, err = changes.Next(ctx)
+
+	require.NoError(t, err)
+	assert.Equal(t, []domain.Change{change(3, "set", "key2")}, got)
+}
+
+func TestUnitFeedClose(t *testing.T) {
+	t.Parallel()
+
+	obj := feed.MustNew(feed.Config{Size: 1})
+
+	changes, err := obj.Watch("", 0)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancel()
+
+	got, err := changes.Next(ctx)
+
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Empty(t, got)
+
+	require.NoError(t, obj.Close())
+	require.ErrorIs(t, obj.Close(), domain.ErrClosed)
+
+	got, err = changes.Next(context.Background())
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]domain.Change](nil, domain.ErrClosed))
+
+	changes, err = obj.Watch("", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, changes), toolkit.Want[domain.Changes](nil, domain.ErrClosed))
+}
+
+func TestUnitFeedFollow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := feed.MustNew(feed.Config{Size: 0})
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, machine.New())
+
+	obj.Follow(store)
+
+	changes, err := obj.Watch("", 0)
+	require.NoError(t, err)
+
+	// the keys of the namespaces never reach the flat watchers
+	require.NoError(t, store.Set(ctx, "team"+domain.Separator+"0"+domain.Separator+"key", []byte("val"), time.Time{}))
+	require.NoError(t, store.Set(ctx, "key", []byte("val"), time.Time{}))
+	require.NoError(t, store.Del(ctx, "key"))
+
+	kinds := make([]string, 0)
+
+	for len(kinds) < 2 {
+		got, err := changes.Next(ctx)
+		require.NoError(t, err)
+
+		for _, change := range got {
+			assert.Equal(t, "key", change.Key)
+			assert.Equal(t, uint64(len(kinds)+1), change.ID)
+
+			kinds = append(kinds, change.Kind)
+		}
+	}
+
+	assert.Equal(t, []string{"set", "delete"}, kinds)
+	require.NoError(t, store.Close())
+}
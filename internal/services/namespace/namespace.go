@@ -0,0 +1,409 @@
+package namespace
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/pkg/clock"
+	"github.com/therenotomorrow/apicache/pkg/eviction"
+)
+
+var (
+	ErrInvalidMaxConn     = errors.New("invalid MaxConn")
+	ErrInvalidConnTimeout = errors.New("invalid ConnTimeout")
+	ErrInvalidName        = errors.New("invalid Name")
+	ErrInvalidDefaultTTL  = errors.New("invalid DefaultTTL")
+	ErrInvalidMaxTTL      = errors.New("invalid MaxTTL")
+	ErrInvalidMaxKeys     = errors.New("invalid MaxKeys")
+	ErrInvalidShare       = errors.New("invalid Share")
+	ErrInvalidStateless   = errors.New("invalid Stateless")
+)
+
+type (
+	Store interface {
+		domain.CacheVersioner
+		domain.CacheSetter
+		domain.CacheDeleter
+	}
+	Config struct {
+		// MaxConn is the pool of the cache the shares of the namespaces are taken from.
+		MaxConn     int
+		ConnTimeout time.Duration
+		// Clock is a source of time for the default TTL and timeouts, nil means the real one.
+		Clock clock.Clock
+		// Failed is told about the store keys of the flushed generation and the evicted ones that
+		// were not deleted after all the attempts, nil means nobody is told.
+		Failed func(key string, err error)
+		// Stateless tells that the replicas share the store. The generations and the keys of the namespaces
+		// are kept in the process, so the replicas would disagree on them and the restart would bring
+		// the flushed keys back, that's why the namespaces are refused.
+		Stateless bool
+	}
+	// Namespace is a named group of keys with its own settings, zero means no bound for any of them.
+	Namespace struct {
+		Name string
+		// DefaultTTL is given to the keys without expiration, MaxTTL caps the expiration of all the keys.
+		DefaultTTL time.Duration
+		MaxTTL     time.Duration
+		// MaxKeys bounds the namespace, the least recently used key is deleted to make a room.
+		MaxKeys int
+		// Share is a part of MaxConn the namespace could hold at once.
+		Share float64
+	}
+	// Namespaces keeps the keys of each namespace apart in the single store. The namespace is taken
+	// from the context (see domain.WithNamespace), the store key is "name", "generation" and "key"
+	// joined by domain.Separator, the keys of the clients can't have it, so the flat API never addresses
+	// the namespaced keys. Flush moves the namespace to the next generation, so all its keys disappear
+	// at once and are deleted in background afterwards.
+	Namespaces struct {
+		store   Store
+		cfg     Config
+		spaces  map[string]*space
+		sweeper *sweeper
+	}
+	// tracked is the deadline of the key, the one of the sliding key moves on reads, so any event of it is the last one.
+	tracked struct {
+		deadline time.Time
+		sliding  bool
+	}
+	space struct {
+		Namespace
+
+		mutex sync.Mutex
+		gen   uint64
+		// keys are the deadlines of the keys of the current generation, order is their recency.
+		keys  map[string]tracked
+		order *eviction.LRU
+		// slots bound the concurrent calls, nil means no bound.
+		slots chan struct{}
+	}
+)
+
+func New(cfg Config, store Store, namespaces ...Namespace) (*Namespaces, error) {
+	if cfg.MaxConn < 0 {
+		return nil, ErrInvalidMaxConn
+	}
+
+	if cfg.ConnTimeout < 0 {
+		return nil, ErrInvalidConnTimeout
+	}
+
+	if cfg.Stateless && len(namespaces) > 0 {
+		return nil, ErrInvalidStateless
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New()
+	}
+
+	spaces := make(map[string]*space, len(namespaces))
+
+	for _, namespace := range namespaces {
+		err := validate(namespace)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", namespace.Name, err)
+		}
+
+		if _, ok := spaces[namespace.Name]; ok {
+			return nil, fmt.Errorf("%s: %w", namespace.Name, ErrInvalidName)
+		}
+
+		spaces[namespace.Name] = newSpace(namespace, cfg.MaxConn)
+	}
+
+	return &Namespaces{
+		store:   store,
+		cfg:     cfg,
+		spaces:  spaces,
+		sweeper: newSweeper(store, cfg.Clock, cfg.Failed),
+	}, nil
+}
+
+func MustNew(cfg Config, store Store, namespaces ...Namespace) *Namespaces {
+	obj, err := New(cfg, store, namespaces...)
+	if err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+func validate(namespace Namespace) error {
+	switch {
+	case namespace.Name == "" || strings.Contains(namespace.Name, domain.Separator):
+		return ErrInvalidName
+	case namespace.DefaultTTL < 0:
+		return ErrInvalidDefaultTTL
+	case namespace.MaxTTL < 0:
+		return ErrInvalidMaxTTL
+	case namespace.MaxTTL > 0 && namespace.DefaultTTL > namespace.MaxTTL:
+		return ErrInvalidDefaultTTL
+	case namespace.MaxKeys < 0:
+		return ErrInvalidMaxKeys
+	case namespace.Share < 0 || namespace.Share > 1:
+		return ErrInvalidShare
+	}
+
+	return nil
+}
+
+func newSpace(namespace Namespace, maxConn int) *space {
+	var slots chan struct{}
+
+	if namespace.Share > 0 && maxConn > 0 {
+		slots = make(chan struct{}, max(1, int(namespace.Share*float64(maxConn))))
+	}
+
+	return &space{
+		Namespace: namespace,
+		mutex:     sync.Mutex{},
+		gen:       0,
+		keys:      make(map[string]tracked),
+		order:     eviction.NewLRU(),
+		slots:     slots,
+	}
+}
+
+// Follow stops counting the keys that the cache expired or evicted by itself.
+func (n *Namespaces) Follow(cache *cache.Cache) {
+	cache.OnExpire(n.forget)
+	cache.OnEvict(n.forget)
+}
+
+func (n *Namespaces) Exists(namespace string) bool {
+	_, ok := n.spaces[namespace]
+
+	return ok
+}
+
+func (n *Namespaces) Get(ctx context.Context, key string) ([]byte, error) {
+	val, _, err := n.GetVersion(ctx, key)
+
+	return val, err
+}
+
+func (n *Namespaces) GetVersion(ctx context.Context, key string) ([]byte, uint64, error) {
+	space, err := n.space(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	release, err := n.admit(ctx, space)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer release()
+
+	val, version, err := n.store.GetVersion(ctx, space.stored(key))
+	if err != nil {
+		return nil, 0, fmt.Errorf("%w", err)
+	}
+
+	space.mutex.Lock()
+	space.order.Touch(key)
+	space.mutex.Unlock()
+
+	return val, version, nil
+}
+
+func (n *Namespaces) Set(ctx context.Context, key string, val []byte, at time.Time) error {
+	space, err := n.space(ctx)
+	if err != nil {
+		return err
+	}
+
+	release, err := n.admit(ctx, space)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	at = space.deadline(at, n.cfg.Clock.Now())
+	stored := space.stored(key)
+
+	err = n.store.Set(ctx, stored, val, at)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	// the victims are deleted at once, so they are not read anymore, the failed ones are retried in background
+	for _, victim := range space.track(stored, key, tracked{deadline: at, sliding: domain.SlidingFrom(ctx)}) {
+		if n.store.Del(ctx, victim) != nil {
+			n.sweeper.push(victim)
+		}
+	}
+
+	return nil
+}
+
+func (n *Namespaces) Del(ctx context.Context, key string) error {
+	space, err := n.space(ctx)
+	if err != nil {
+		return err
+	}
+
+	release, err := n.admit(ctx, space)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	stored := space.stored(key)
+
+	err = n.store.Del(ctx, stored)
+	if err != nil {
+		return fmt.Errorf("%w", err)
+	}
+
+	space.mutex.Lock()
+	if space.key(key) == stored {
+		space.untrack(key)
+	}
+	space.mutex.Unlock()
+
+	return nil
+}
+
+// Flush makes all the keys of the namespace disappear at once, then deletes them in background.
+func (n *Namespaces) Flush(ctx context.Context) error {
+	space, err := n.space(ctx)
+	if err != nil {
+		return err
+	}
+
+	n.sweeper.push(space.flush()...)
+
+	return nil
+}
+
+// Close stops the deletions in background, the keys left in the store are reported to Config.Failed.
+func (n *Namespaces) Close() error {
+	return n.sweeper.close()
+}
+
+func (n *Namespaces) space(ctx context.Context) (*space, error) {
+	space, ok := n.spaces[domain.NamespaceFrom(ctx)]
+	if !ok {
+		return nil, domain.ErrNoNamespace
+	}
+
+	return space, nil
+}
+
+// admit takes the slot of the namespace, so the single namespace can't hold the whole pool of the cache.
+func (n *Namespaces) admit(ctx context.Context, space *space) (func(), error) {
+	if space.slots == nil {
+		return func() {}, nil
+	}
+
+	timer := n.cfg.Clock.Timer(n.cfg.Clock.Now().Add(n.cfg.ConnTimeout))
+	defer timer.Stop()
+
+	select {
+	case space.slots <- struct{}{}:
+		return func() { <-space.slots }, nil
+	case <-timer.C():
+		return nil, domain.ErrConnTimeout
+	case <-ctx.Done():
+		return nil, domain.ErrContextTimeout
+	}
+}
+
+// forget stops counting the key of the current generation, unless it was set again after the event.
+func (n *Namespaces) forget(event cache.Event) {
+	parts := strings.SplitN(event.Key, domain.Separator, 3) //nolint:mnd // name, generation and key
+
+	space, ok := n.spaces[parts[0]]
+	if !ok || len(parts) != 3 { //nolint:mnd // name, generation and key
+		return
+	}
+
+	space.mutex.Lock()
+	defer space.mutex.Unlock()
+
+	mark, ok := space.keys[parts[2]]
+	if ok && space.key(parts[2]) == event.Key && (mark.sliding || mark.deadline.Equal(event.Deadline)) {
+		space.untrack(parts[2])
+	}
+}
+
+// stored is the store key in the current generation.
+func (s *space) stored(key string) string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.key(key)
+}
+
+// key is the store key in the current generation. Must be called under the mutex.
+func (s *space) key(key string) string {
+	return s.Name + domain.Separator + strconv.FormatUint(s.gen, 10) + domain.Separator + key
+}
+
+// deadline gives the default TTL to the infinite key and caps it by the MaxTTL.
+func (s *space) deadline(deadline time.Time, now time.Time) time.Time {
+	if deadline.IsZero() && s.DefaultTTL > 0 {
+		deadline = now.Add(s.DefaultTTL)
+	}
+
+	if s.MaxTTL > 0 && (deadline.IsZero() || deadline.After(now.Add(s.MaxTTL))) {
+		deadline = now.Add(s.MaxTTL)
+	}
+
+	return deadline
+}
+
+// track counts the key and returns the store keys of the victims that make room for it.
+// The key of the past generation is not counted, the flush happened while it was stored.
+func (s *space) track(stored string, key string, mark tracked) []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.key(key) != stored {
+		return []string{stored}
+	}
+
+	s.keys[key] = mark
+	s.order.Push(key)
+
+	victims := make([]string, 0)
+
+	for s.MaxKeys > 0 && s.order.Len() > s.MaxKeys {
+		victim, _ := s.order.Evict()
+		delete(s.keys, victim)
+
+		victims = append(victims, s.key(victim))
+	}
+
+	return victims
+}
+
+// untrack stops counting the key. Must be called under the mutex.
+func (s *space) untrack(key string) {
+	delete(s.keys, key)
+	s.order.Remove(key)
+}
+
+// flush moves the namespace to the next generation and returns the store keys of the past one.
+func (s *space) flush() []string {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	stored := make([]string, 0, len(s.keys))
+	for key := range s.keys {
+		stored = append(stored, s.key(key))
+	}
+
+	s.gen++
+	s.keys = make(map[string]tracked)
+	s.order = eviction.NewLRU()
+
+	return stored
+}
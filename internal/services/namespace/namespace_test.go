@@ -0,0 +1,459 @@
+package namespace_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/internal/services/namespace"
+	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
+	"github.com/therenotomorrow/apicache/test/mocks"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+var (
+	errDummy = errors.New("dummy error")
+	now      = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+)
+
+// store remembers the deadlines of the keys and could hold the calls until the gate is closed.
+type store struct {
+	mutex     sync.Mutex
+	vals      map[string][]byte
+	deadlines map[string]time.Time
+	versions  map[string]uint64
+	written   uint64
+	gate      chan struct{}
+	// failures is the number of the next deletions that fail.
+	failures int
+}
+
+func newStore() *store {
+	return &store{
+		mutex:     sync.Mutex{},
+		vals:      make(map[string][]byte),
+		deadlines: make(map[string]time.Time),
+		versions:  make(map[string]uint64),
+		written:   0,
+		gate:      nil,
+		failures:  0,
+	}
+}
+
+func (s *store) GetVersion(_ context.Context, key string) ([]byte, uint64, error) {
+	if s.gate != nil {
+		<-s.gate
+	}
+
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	val, ok := s.vals[key]
+	if !ok {
+		return nil, 0, domain.ErrKeyNotExist
+	}
+
+	return val, s.versions[key], nil
+}
+
+func (s *store) Set(_ context.Context, key string, val []byte, deadline time.Time) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.written++
+	s.vals[key] = val
+	s.deadlines[key] = deadline
+	s.versions[key] = s.written
+
+	return nil
+}
+
+func (s *store) Del(_ context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.failures > 0 {
+		s.failures--
+
+		return errDummy
+	}
+
+	delete(s.vals, key)
+	delete(s.deadlines, key)
+
+	return nil
+}
+
+func (s *store) fail(times int) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures = times
+}
+
+func (s *store) len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return len(s.vals)
+}
+
+func (s *store) deadline() time.Time {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for _, deadline := range s.deadlines {
+		return deadline
+	}
+
+	return time.Time{}
+}
+
+func in(name string) context.Context {
+	return domain.WithNamespace(context.Background(), name)
+}
+
+func space(name string) namespace.Namespace {
+	return namespace.Namespace{Name: name, DefaultTTL: 0, MaxTTL: 0, MaxKeys: 0, Share: 0}
+}
+
+func config() namespace.Config {
+	return namespace.Config{
+		MaxConn:     10,
+		ConnTimeout: time.Second,
+		Clock:       mocks.NewClockMock(now),
+		Failed:      nil,
+		Stateless:   false,
+	}
+}
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name  string
+		cfg   namespace.Config
+		space func(*namespace.Namespace)
+		want  error
+	}{
+		{name: "invalid MaxConn", cfg: namespace.Config{MaxConn: -1}, space: func(*namespace.Namespace) {}, want: namespace.ErrInvalidMaxConn},
+		{name: "invalid ConnTimeout", cfg: namespace.Config{ConnTimeout: -1}, space: func(*namespace.Namespace) {}, want: namespace.ErrInvalidConnTimeout},
+		{name: "invalid Stateless", cfg: namespace.Config{Stateless: true}, space: func(*namespace.Namespace) {}, want: namespace.ErrInvalidStateless},
+		{name: "empty Name", space: func(ns *namespace.Namespace) { ns.Name = "" }, want: namespace.ErrInvalidName},
+		{name: "separator in Name", space: func(ns *namespace.Namespace) { ns.Name = "a\x1fb" }, want: namespace.ErrInvalidName},
+		{name: "invalid DefaultTTL", space: func(ns *namespace.Namespace) { ns.DefaultTTL = -1 }, want: namespace.ErrInvalidDefaultTTL},
+		{name: "invalid MaxTTL", space: func(ns *namespace.Namespace) { ns.MaxTTL = -1 }, want: namespace.ErrInvalidMaxTTL},
+		{
+			name:  "DefaultTTL above MaxTTL",
+			space: func(ns *namespace.Namespace) { ns.DefaultTTL, ns.MaxTTL = time.Hour, time.Minute },
+			want:  namespace.ErrInvalidDefaultTTL,
+		},
+		{name: "invalid MaxKeys", space: func(ns *namespace.Namespace) { ns.MaxKeys = -1 }, want: namespace.ErrInvalidMaxKeys},
+		{name: "invalid Share", space: func(ns *namespace.Namespace) { ns.Share = 1.5 }, want: namespace.ErrInvalidShare},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ns := space("team")
+			test.space(&ns)
+
+			obj, err := namespace.New(test.cfg, newStore(), ns)
+
+			require.ErrorIs(t, err, test.want)
+			assert.Nil(t, obj)
+		})
+	}
+
+	obj, err := namespace.New(config(), newStore(), space("team"), space("team"))
+
+	require.ErrorIs(t, err, namespace.ErrInvalidName)
+	assert.Nil(t, obj)
+
+	// the shared store without namespaces is fine
+	obj, err = namespace.New(namespace.Config{Stateless: true}, newStore())
+
+	require.NoError(t, err)
+	assert.False(t, obj.Exists("team"))
+}
+
+func TestUnitMustNew(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() { _ = namespace.MustNew(namespace.Config{MaxConn: -1}, newStore()) })
+
+	obj := namespace.MustNew(config(), newStore(), space("team"))
+
+	assert.True(t, obj.Exists("team"))
+	assert.False(t, obj.Exists("other"))
+	assert.False(t, obj.Exists(""))
+}
+
+func TestUnitNamespacesIsolation(t *testing.T) {
+	t.Parallel()
+
+	store := newStore()
+	obj := namespace.MustNew(config(), store, space("a"), space("b"))
+
+	require.NoError(t, obj.Set(in("a"), "key", []byte("a"), time.Time{}))
+	require.NoError(t, obj.Set(in("b"), "key", []byte("b"), time.Time{}))
+	require.NoError(t, store.Set(context.Background(), "key", []byte("flat"), time.Time{}))
+
+	got, err := obj.Get(in("a"), "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte("a"), nil))
+
+	got, version, err := obj.GetVersion(in("b"), "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte("b"), nil))
+	toolkit.Assert(t, toolkit.Got(nil, version), toolkit.Want(uint64(2), nil))
+
+	require.NoError(t, obj.Del(in("a"), "key"))
+
+	got, err = obj.Get(in("a"), "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, domain.ErrKeyNotExist))
+
+	got, err = obj.Get(in("b"), "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte("b"), nil))
+
+	got, err = obj.Get(in("c"), "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, domain.ErrNoNamespace))
+
+	require.ErrorIs(t, obj.Set(context.Background(), "key", []byte("val"), time.Time{}), domain.ErrNoNamespace)
+	require.ErrorIs(t, obj.Del(in("c"), "key"), domain.ErrNoNamespace)
+	require.ErrorIs(t, obj.Flush(in("c")), domain.ErrNoNamespace)
+	assert.Equal(t, 2, store.len())
+}
+
+func TestUnitNamespacesTTL(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name       string
+		defaultTTL time.Duration
+		maxTTL     time.Duration
+		deadline   time.Time
+		want       time.Time
+	}{
+		{name: "no bounds", defaultTTL: 0, maxTTL: 0, deadline: time.Time{}, want: time.Time{}},
+		{name: "default", defaultTTL: time.Minute, maxTTL: 0, deadline: time.Time{}, want: now.Add(time.Minute)},
+		{name: "explicit", defaultTTL: time.Minute, maxTTL: 0, deadline: now.Add(time.Hour), want: now.Add(time.Hour)},
+		{name: "capped", defaultTTL: 0, maxTTL: time.Minute, deadline: now.Add(time.Hour), want: now.Add(time.Minute)},
+		{name: "capped infinite", defaultTTL: 0, maxTTL: time.Minute, deadline: time.Time{}, want: now.Add(time.Minute)},
+		{name: "below cap", defaultTTL: 0, maxTTL: time.Hour, deadline: now.Add(time.Minute), want: now.Add(time.Minute)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ns := space("team")
+			ns.DefaultTTL, ns.MaxTTL = test.defaultTTL, test.maxTTL
+
+			store := newStore()
+			obj := namespace.MustNew(config(), store, ns)
+
+			require.NoError(t, obj.Set(in("team"), "key", []byte("val"), test.deadline))
+			assert.Equal(t, test.want, store.deadline())
+		})
+	}
+}
+
+func TestUnitNamespacesMaxKeys(t *testing.T) {
+	t.Parallel()
+
+	ns := space("team")
+	ns.MaxKeys = 2
+
+	store := newStore()
+	obj := namespace.MustNew(config(), store, ns, space("other"))
+
+	require.NoError(t, obj.Set(in("other"), "key", []byte("val"), time.Time{}))
+
+	for _, key := range []string{"key1", "key2"} {
+		require.NoError(t, obj.Set(in("team"), key, []byte(key), time.Time{}))
+	}
+
+	// the recently read key stays, the least recently used one leaves
+	_, err := obj.Get(in("team"), "key1")
+	require.NoError(t, err)
+
+	require.NoError(t, obj.Set(in("team"), "key3", []byte("key3"), time.Time{}))
+
+	got, err := obj.Get(in("team"), "key2")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, domain.ErrKeyNotExist))
+
+	for _, key := range []string{"key1", "key3"} {
+		got, err = obj.Get(in("team"), key)
+		toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte(key), nil))
+	}
+
+	// the other namespace is not bounded by the neighbour
+	assert.Equal(t, 3, store.len())
+}
+
+func TestUnitNamespacesShare(t *testing.T) {
+	t.Parallel()
+
+	ns := space("team")
+	ns.Share = 0.1
+
+	store := newStore()
+	store.gate = make(chan struct{})
+	cfg := namespace.Config{MaxConn: 10, ConnTimeout: time.Millisecond, Clock: nil, Failed: nil}
+	obj := namespace.MustNew(cfg, store, ns, space("other"))
+
+	done := make(chan error)
+
+	go func() {
+		_, err := obj.Get(in("team"), "key")
+		done <- err
+	}()
+
+	// the only slot of the namespace is held by the stuck call, the other namespace is not bounded
+	require.Eventually(t, func() bool {
+		return errors.Is(obj.Set(in("team"), "key", []byte("val"), time.Time{}), domain.ErrConnTimeout)
+	}, time.Second, time.Millisecond)
+	require.NoError(t, obj.Set(in("other"), "key", []byte("val"), time.Time{}))
+
+	close(store.gate)
+
+	require.ErrorIs(t, <-done, domain.ErrKeyNotExist)
+	require.NoError(t, obj.Set(in("team"), "key", []byte("val"), time.Time{}))
+}
+
+func TestUnitNamespacesFlush(t *testing.T) {
+	t.Parallel()
+
+	store := newStore()
+	obj := namespace.MustNew(config(), store, space("team"), space("other"))
+
+	for _, key := range []string{"key1", "key2"} {
+		require.NoError(t, obj.Set(in("team"), key, []byte(key), time.Time{}))
+	}
+
+	require.NoError(t, obj.Set(in("other"), "key1", []byte("other"), time.Time{}))
+	require.NoError(t, obj.Flush(in("team")))
+
+	got, err := obj.Get(in("team"), "key1")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want[[]byte](nil, domain.ErrKeyNotExist))
+
+	got, err = obj.Get(in("other"), "key1")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte("other"), nil))
+
+	require.Eventually(t, func() bool { return store.len() == 1 }, time.Second, time.Millisecond)
+
+	// the namespace is usable after the flush
+	require.NoError(t, obj.Set(in("team"), "key1", []byte("new"), time.Time{}))
+
+	got, err = obj.Get(in("team"), "key1")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte("new"), nil))
+}
+
+func TestUnitNamespacesSweep(t *testing.T) {
+	t.Parallel()
+
+	type failure struct {
+		key string
+		err error
+	}
+
+	clock := mocks.NewClockMock(now)
+	failures := make(chan failure, 10)
+	store := newStore()
+	obj := namespace.MustNew(namespace.Config{
+		MaxConn:     10,
+		ConnTimeout: time.Second,
+		Clock:       clock,
+		Failed:      func(key string, err error) { failures <- failure{key: key, err: err} },
+	}, store, space("team"), namespace.Namespace{Name: "small", DefaultTTL: 0, MaxTTL: 0, MaxKeys: 1, Share: 0})
+
+	// the failed deletions are retried
+	store.fail(2)
+	require.NoError(t, obj.Set(in("team"), "key", []byte("val"), time.Time{}))
+	require.NoError(t, obj.Flush(in("team")))
+	require.Eventually(t, func() bool {
+		clock.Advance(time.Second)
+
+		return store.len() == 0
+	}, time.Second, time.Millisecond)
+
+	// so is the victim of the eviction
+	store.fail(1)
+	require.NoError(t, obj.Set(in("small"), "key1", []byte("val"), time.Time{}))
+	require.NoError(t, obj.Set(in("small"), "key2", []byte("val"), time.Time{}))
+	require.Eventually(t, func() bool {
+		clock.Advance(time.Second)
+
+		return store.len() == 1
+	}, time.Second, time.Millisecond)
+
+	// the key that outlives all the attempts is reported
+	store.fail(10)
+	require.NoError(t, obj.Set(in("team"), "key", []byte("val"), time.Time{}))
+	require.NoError(t, obj.Flush(in("team")))
+	require.Eventually(t, func() bool {
+		clock.Advance(time.Second)
+
+		return len(failures) == 1
+	}, time.Second, time.Millisecond)
+
+	got := <-failures
+
+	assert.Equal(t, "team"+domain.Separator+"1"+domain.Separator+"key", got.key)
+	require.ErrorIs(t, got.err, errDummy)
+	assert.Equal(t, 2, store.len())
+
+	// nothing is deleted after the close, so the keys are reported at once
+	store.fail(0)
+	require.NoError(t, obj.Close())
+	require.ErrorIs(t, obj.Close(), domain.ErrClosed)
+	require.NoError(t, obj.Set(in("team"), "key", []byte("val"), time.Time{}))
+	require.NoError(t, obj.Flush(in("team")))
+
+	got = <-failures
+
+	assert.Equal(t, "team"+domain.Separator+"2"+domain.Separator+"key", got.key)
+	require.ErrorIs(t, got.err, domain.ErrClosed)
+}
+
+func TestUnitNamespacesFollow(t *testing.T) {
+	t.Parallel()
+
+	ns := space("team")
+	ns.MaxKeys = 2
+
+	ctx := in("team")
+	clock := mocks.NewClockMock(now)
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second, Clock: clock}, machine.New())
+	obj := namespace.MustNew(config(), store, ns)
+	expired := make(chan cache.Event, 1)
+
+	// the listeners are called in order, so the namespace forgets the key before the test hears of it
+	obj.Follow(store)
+	store.OnExpire(func(event cache.Event) { expired <- event })
+
+	require.NoError(t, obj.Set(ctx, "key1", []byte("val"), time.Time{}))
+	require.NoError(t, obj.Set(domain.WithSliding(ctx, true), "key2", []byte("val"), now.Add(time.Second)))
+
+	// the read moves the deadline of the sliding key, it's forgotten anyway
+	clock.Advance(time.Second / 2)
+
+	_, err := obj.Get(ctx, "key2")
+	require.NoError(t, err)
+
+	clock.Advance(time.Second)
+	<-expired
+
+	// the expired key doesn't count, so nothing is evicted
+	require.NoError(t, obj.Set(ctx, "key3", []byte("val"), time.Time{}))
+
+	got, err := obj.Get(ctx, "key1")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want([]byte("val"), nil))
+	require.NoError(t, store.Close())
+}
@@ -0,0 +1,183 @@
+package namespace
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/pkg/clock"
+)
+
+// sweepAttempts bound the deletions of the key, the pause between them starts with sweepBackoff
+// and doubles with every next one.
+const (
+	sweepAttempts = 3
+	sweepBackoff  = 100 * time.Millisecond
+)
+
+// sweeper deletes the keys that the namespaces don't count anymore: the ones of the flushed generation
+// and the evicted ones. It works in background, so the flush doesn't hold the caller, and retries the
+// failed deletions, so the infinite keys are not left in the store because of the single failure.
+type sweeper struct {
+	store  domain.CacheDeleter
+	clock  clock.Clock
+	failed func(key string, err error)
+
+	mutex   sync.Mutex
+	keys    []string
+	closed  bool
+	wake    chan struct{}
+	done    chan struct{}
+	stopped chan struct{}
+	start   sync.Once
+}
+
+func newSweeper(store domain.CacheDeleter, clock clock.Clock, failed func(key string, err error)) *sweeper {
+	if failed == nil {
+		failed = func(string, error) {}
+	}
+
+	return &sweeper{
+		store:   store,
+		clock:   clock,
+		failed:  failed,
+		mutex:   sync.Mutex{},
+		keys:    make([]string, 0),
+		closed:  false,
+		wake:    make(chan struct{}, 1),
+		done:    make(chan struct{}),
+		stopped: make(chan struct{}),
+		start:   sync.Once{},
+	}
+}
+
+// push queues the store keys for the deletion, the first ones start the sweeper.
+func (s *sweeper) push(keys ...string) {
+	if len(keys) == 0 {
+		return
+	}
+
+	s.mutex.Lock()
+
+	if s.closed {
+		s.mutex.Unlock()
+
+		for _, key := range keys {
+			s.failed(key, domain.ErrClosed)
+		}
+
+		return
+	}
+
+	s.keys = append(s.keys, keys...)
+	s.mutex.Unlock()
+
+	s.start.Do(func() { go s.run() })
+
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// close stops the sweeper, the keys it didn't delete are reported as failed.
+func (s *sweeper) close() error {
+	s.mutex.Lock()
+
+	if s.closed {
+		s.mutex.Unlock()
+
+		return domain.ErrClosed
+	}
+
+	s.closed = true
+	s.mutex.Unlock()
+
+	close(s.done)
+	// the sweeper that was never started has nothing to stop
+	s.start.Do(func() { close(s.stopped) })
+	<-s.stopped
+
+	for key, ok := s.next(); ok; key, ok = s.next() {
+		s.failed(key, domain.ErrClosed)
+	}
+
+	return nil
+}
+
+func (s *sweeper) run() {
+	defer close(s.stopped)
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case <-s.wake:
+		}
+
+		for !s.stopping() {
+			key, ok := s.next()
+			if !ok {
+				break
+			}
+
+			s.sweep(key)
+		}
+	}
+}
+
+func (s *sweeper) next() (string, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if len(s.keys) == 0 {
+		return "", false
+	}
+
+	key := s.keys[0]
+	s.keys = s.keys[1:]
+
+	return key, true
+}
+
+func (s *sweeper) stopping() bool {
+	select {
+	case <-s.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// sweep deletes the key, the last error is reported when all the attempts failed.
+func (s *sweeper) sweep(key string) {
+	// nobody waits for the sweeper, so it shouldn't outrun the callers
+	ctx := domain.WithPriority(context.Background(), domain.PriorityLow)
+
+	err := s.store.Del(ctx, key)
+	for attempt := 1; err != nil && attempt < sweepAttempts; attempt++ {
+		if !s.sleep(sweepBackoff << (attempt - 1)) {
+			break
+		}
+
+		err = s.store.Del(ctx, key)
+	}
+
+	if err != nil {
+		s.failed(key, err)
+	}
+}
+
+// sleep pauses the sweeper, false means it was stopped meanwhile.
+func (s *sweeper) sleep(delay time.Duration) bool {
+	timer := s.clock.Timer(s.clock.Now().Add(delay))
+	defer timer.Stop()
+
+	select {
+	case <-timer.C():
+		return true
+	case <-s.done:
+		return false
+	}
+}
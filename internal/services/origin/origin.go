@@ -0,0 +1,128 @@
+package origin
+
+import (
+	"cmp"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+const defaultTimeout = 5 * time.Second
+
+var (
+	ErrInvalidURL     = errors.New("invalid URL")
+	ErrInvalidTTL     = errors.New("invalid TTL")
+	ErrInvalidTimeout = errors.New("invalid Timeout")
+
+	errStatus = errors.New("unexpected status")
+	errBody   = errors.New("invalid body")
+)
+
+type (
+	// Config of the origin, the value of the key is read from URL/{key}. The positive max-age of Cache-Control
+	// is the TTL of the loaded key, TTL is used when the origin doesn't tell it, zero means infinite key.
+	Config struct {
+		Client  *http.Client
+		URL     string
+		TTL     time.Duration
+		Timeout time.Duration
+	}
+	// Origin is the cache.Loader that reads the missed keys from the HTTP service, the value is
+	// its JSON body. The origin answers 404 for the key it doesn't have.
+	Origin struct {
+		client *http.Client
+		base   string
+		ttl    time.Duration
+	}
+)
+
+func New(cfg Config) (*Origin, error) {
+	link, err := url.Parse(cfg.URL)
+
+	switch {
+	case err != nil || (link.Scheme != "http" && link.Scheme != "https") || link.Host == "":
+		return nil, ErrInvalidURL
+	case cfg.TTL < 0:
+		return nil, ErrInvalidTTL
+	case cfg.Timeout < 0:
+		return nil, ErrInvalidTimeout
+	}
+
+	if cfg.Client == nil {
+		//nolint:exhaustruct // defaults of the standard client
+		cfg.Client = &http.Client{Timeout: cmp.Or(cfg.Timeout, defaultTimeout)}
+	}
+
+	return &Origin{client: cfg.Client, base: strings.TrimSuffix(cfg.URL, "/"), ttl: cfg.TTL}, nil
+}
+
+func MustNew(cfg Config) *Origin {
+	obj, err := New(cfg)
+	if err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+// Load reads the key from the origin, the missed key is domain.ErrKeyNotExist.
+func (o *Origin) Load(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, o.base+"/"+url.PathEscape(key), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("request error: %w", err)
+	}
+
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := o.client.Do(req)
+	if err != nil {
+		return nil, 0, fmt.Errorf("origin error: %w", err)
+	}
+
+	defer func() { _ = resp.Body.Close() }()
+
+	switch resp.StatusCode {
+	case http.StatusOK:
+	case http.StatusNotFound:
+		return nil, 0, domain.ErrKeyNotExist
+	default:
+		return nil, 0, fmt.Errorf("%w %d", errStatus, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, 0, fmt.Errorf("origin error: %w", err)
+	}
+
+	if !json.Valid(body) {
+		return nil, 0, errBody
+	}
+
+	return body, o.maxAge(resp.Header), nil
+}
+
+// maxAge is the TTL the origin tells in Cache-Control, the default one otherwise.
+func (o *Origin) maxAge(header http.Header) time.Duration {
+	for _, directive := range strings.Split(header.Get("Cache-Control"), ",") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if !strings.EqualFold(name, "max-age") {
+			continue
+		}
+
+		seconds, err := strconv.Atoi(strings.Trim(value, `"`))
+		if err == nil && seconds > 0 {
+			return time.Duration(seconds) * time.Second
+		}
+	}
+
+	return o.ttl
+}
@@ -0,0 +1,126 @@
+package origin_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/internal/services/origin"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const defaultTTL = time.Minute
+
+// server is the origin that answers every test key its own way.
+func server(t *testing.T) *httptest.Server {
+	t.Helper()
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/fresh":
+			w.Header().Set("Cache-Control", "public, max-age=30")
+		case "/zero":
+			w.Header().Set("Cache-Control", "max-age=0")
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+
+			return
+		case "/failure":
+			w.WriteHeader(http.StatusBadGateway)
+
+			return
+		case "/text":
+			_, _ = w.Write([]byte("not a json"))
+
+			return
+		case "/a b":
+		default:
+			t.Errorf("unexpected path %q", r.URL.Path)
+		}
+
+		_, _ = w.Write([]byte(`{"hello":"world"}`))
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv
+}
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  origin.Config
+		want error
+	}{
+		{name: "empty URL", cfg: origin.Config{URL: ""}, want: origin.ErrInvalidURL},
+		{name: "relative URL", cfg: origin.Config{URL: "test.loc"}, want: origin.ErrInvalidURL},
+		{name: "invalid scheme", cfg: origin.Config{URL: "ftp://test.loc"}, want: origin.ErrInvalidURL},
+		{name: "invalid TTL", cfg: origin.Config{URL: "http://test.loc", TTL: -1}, want: origin.ErrInvalidTTL},
+		{name: "invalid Timeout", cfg: origin.Config{URL: "http://test.loc", Timeout: -1}, want: origin.ErrInvalidTimeout},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			obj, err := origin.New(test.cfg)
+
+			require.ErrorIs(t, err, test.want)
+			assert.Nil(t, obj)
+		})
+	}
+}
+
+func TestUnitMustNew(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() { _ = origin.MustNew(origin.Config{URL: ""}) })
+
+	var _ cache.Loader = origin.MustNew(origin.Config{URL: "https://test.loc/"})
+}
+
+func TestUnitOriginLoad(t *testing.T) {
+	t.Parallel()
+
+	type want struct {
+		val []byte
+		ttl time.Duration
+		err error
+	}
+
+	tests := []struct {
+		name string
+		key  string
+		want want
+	}{
+		{name: "max age", key: "fresh", want: want{val: []byte(`{"hello":"world"}`), ttl: 30 * time.Second, err: nil}},
+		{name: "zero max age", key: "zero", want: want{val: []byte(`{"hello":"world"}`), ttl: defaultTTL, err: nil}},
+		{name: "escaped key", key: "a b", want: want{val: []byte(`{"hello":"world"}`), ttl: defaultTTL, err: nil}},
+		{name: "key not exist", key: "missing", want: want{val: nil, ttl: 0, err: domain.ErrKeyNotExist}},
+		{name: "failure", key: "failure", want: want{val: nil, ttl: 0, err: errors.New("unexpected status 502")}},
+		{name: "invalid body", key: "text", want: want{val: nil, ttl: 0, err: errors.New("invalid body")}},
+	}
+
+	srv := server(t)
+	obj := origin.MustNew(origin.Config{Client: nil, URL: srv.URL + "/", TTL: defaultTTL, Timeout: time.Second})
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			val, ttl, err := obj.Load(context.Background(), test.key)
+
+			toolkit.Assert(t, toolkit.Got(err, val), toolkit.Want(test.want.val, test.want.err))
+			assert.Equal(t, test.want.ttl, ttl)
+		})
+	}
+}
@@ -0,0 +1,131 @@
+package policy
+
+import (
+	"errors"
+	"math/rand/v2"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+)
+
+// wildcards make the pattern of the rule the glob instead of the prefix.
+const wildcards = "*?[\\"
+
+var (
+	ErrInvalidPattern    = errors.New("invalid Pattern")
+	ErrInvalidDefaultTTL = errors.New("invalid DefaultTTL")
+	ErrInvalidMaxTTL     = errors.New("invalid MaxTTL")
+	ErrInvalidJitter     = errors.New("invalid Jitter")
+)
+
+type (
+	// Rule shapes the deadlines of the keys matched by the Pattern, zero means no bound for any of them.
+	Rule struct {
+		// Pattern is the glob (see path.Match) if it has any wildcard and the prefix of the key otherwise.
+		Pattern string
+		// DefaultTTL is given to the keys without expiration, MaxTTL caps the expiration of all the keys.
+		DefaultTTL time.Duration
+		MaxTTL     time.Duration
+		// Finite rejects the keys that stay without expiration after the DefaultTTL.
+		Finite bool
+		// Jitter is a part of the TTL (less than one) the deadline is randomly moved back by, so the keys
+		// written at once don't expire at once.
+		Jitter float64
+	}
+	// Policy applies the first rule that matches the key, the key without any is kept as it is.
+	Policy struct {
+		rules []Rule
+	}
+)
+
+func New(rules ...Rule) (*Policy, error) {
+	for _, rule := range rules {
+		err := validate(rule)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return &Policy{rules: rules}, nil
+}
+
+func MustNew(rules ...Rule) *Policy {
+	obj, err := New(rules...)
+	if err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+func validate(rule Rule) error {
+	_, err := path.Match(rule.Pattern, "")
+
+	switch {
+	case err != nil:
+		return ErrInvalidPattern
+	case rule.DefaultTTL < 0:
+		return ErrInvalidDefaultTTL
+	case rule.MaxTTL < 0:
+		return ErrInvalidMaxTTL
+	case rule.MaxTTL > 0 && rule.DefaultTTL > rule.MaxTTL:
+		return ErrInvalidDefaultTTL
+	case rule.Jitter < 0 || rule.Jitter >= 1:
+		return ErrInvalidJitter
+	}
+
+	return nil
+}
+
+// Deadline gives the default TTL to the infinite key, caps it by the MaxTTL and moves it back by the jitter.
+// The jitter never moves the deadline forward, so the key doesn't outlive the cap.
+func (p *Policy) Deadline(key string, deadline time.Time, now time.Time) (time.Time, error) {
+	rule, ok := p.match(key)
+	if !ok {
+		return deadline, nil
+	}
+
+	if deadline.IsZero() && rule.DefaultTTL > 0 {
+		deadline = now.Add(rule.DefaultTTL)
+	}
+
+	if rule.MaxTTL > 0 && (deadline.IsZero() || deadline.After(now.Add(rule.MaxTTL))) {
+		deadline = now.Add(rule.MaxTTL)
+	}
+
+	if deadline.IsZero() {
+		if rule.Finite {
+			return time.Time{}, domain.ErrInfiniteKey
+		}
+
+		return deadline, nil
+	}
+
+	spread := time.Duration(float64(deadline.Sub(now)) * rule.Jitter)
+	if spread > 0 {
+		deadline = deadline.Add(-rand.N(spread + 1)) //nolint:gosec // jitter doesn't need the secure random
+	}
+
+	return deadline, nil
+}
+
+func (p *Policy) match(key string) (Rule, bool) {
+	for _, rule := range p.rules {
+		if !strings.ContainsAny(rule.Pattern, wildcards) {
+			if strings.HasPrefix(key, rule.Pattern) {
+				return rule, true
+			}
+
+			continue
+		}
+
+		// the pattern is validated, so there is no error to check
+		if ok, _ := path.Match(rule.Pattern, key); ok {
+			return rule, true
+		}
+	}
+
+	return Rule{}, false
+}
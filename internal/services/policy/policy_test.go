@@ -0,0 +1,125 @@
+package policy_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/policy"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+var now = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		rule policy.Rule
+		want error
+	}{
+		{name: "invalid Pattern", rule: policy.Rule{Pattern: "user:["}, want: policy.ErrInvalidPattern},
+		{name: "invalid DefaultTTL", rule: policy.Rule{DefaultTTL: -1}, want: policy.ErrInvalidDefaultTTL},
+		{name: "invalid MaxTTL", rule: policy.Rule{MaxTTL: -1}, want: policy.ErrInvalidMaxTTL},
+		{name: "DefaultTTL above MaxTTL", rule: policy.Rule{DefaultTTL: time.Hour, MaxTTL: time.Minute}, want: policy.ErrInvalidDefaultTTL},
+		{name: "negative Jitter", rule: policy.Rule{Jitter: -0.1}, want: policy.ErrInvalidJitter},
+		{name: "whole Jitter", rule: policy.Rule{Jitter: 1}, want: policy.ErrInvalidJitter},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			obj, err := policy.New(test.rule)
+
+			toolkit.Assert(t, toolkit.Got(err, obj), toolkit.Want[*policy.Policy](nil, test.want))
+		})
+	}
+}
+
+func TestUnitMustNew(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() { _ = policy.MustNew(policy.Rule{Jitter: 2}) })
+	require.NotPanics(t, func() { _ = policy.MustNew() })
+}
+
+func TestUnitPolicyDeadline(t *testing.T) {
+	t.Parallel()
+
+	obj := policy.MustNew(
+		policy.Rule{Pattern: "session:", DefaultTTL: time.Hour, MaxTTL: 24 * time.Hour},
+		policy.Rule{Pattern: "lock:*:owner", Finite: true},
+		policy.Rule{Pattern: "lock:", MaxTTL: time.Minute},
+		policy.Rule{Pattern: "report:?", Finite: true},
+	)
+
+	type args struct {
+		key      string
+		deadline time.Time
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[time.Time]
+	}{
+		{name: "no rule", args: args{key: "user:1", deadline: time.Time{}}, want: toolkit.Want(time.Time{}, nil)},
+		{name: "default", args: args{key: "session:1", deadline: time.Time{}}, want: toolkit.Want(now.Add(time.Hour), nil)},
+		{
+			name: "in bounds",
+			args: args{key: "session:1", deadline: now.Add(2 * time.Hour)},
+			want: toolkit.Want(now.Add(2*time.Hour), nil),
+		},
+		{
+			name: "capped",
+			args: args{key: "session:1", deadline: now.Add(48 * time.Hour)},
+			want: toolkit.Want(now.Add(24*time.Hour), nil),
+		},
+		{name: "capped infinite", args: args{key: "lock:1", deadline: time.Time{}}, want: toolkit.Want(now.Add(time.Minute), nil)},
+		{
+			name: "glob first",
+			args: args{key: "lock:1:owner", deadline: time.Time{}},
+			want: toolkit.Want(time.Time{}, domain.ErrInfiniteKey),
+		},
+		{
+			name: "glob finite",
+			args: args{key: "report:1", deadline: now.Add(time.Hour)},
+			want: toolkit.Want(now.Add(time.Hour), nil),
+		},
+		{name: "glob mismatch", args: args{key: "report:10", deadline: time.Time{}}, want: toolkit.Want(time.Time{}, nil)},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			got, err := obj.Deadline(test.args.key, test.args.deadline, now)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
+func TestUnitPolicyDeadlineJitter(t *testing.T) {
+	t.Parallel()
+
+	obj := policy.MustNew(policy.Rule{Pattern: "", DefaultTTL: time.Hour, MaxTTL: 2 * time.Hour, Jitter: 0.1})
+	seen := make(map[time.Time]struct{})
+
+	// the jitter moves the deadline back only, so the key never outlives the cap
+	for range 100 {
+		got, err := obj.Deadline("key", now.Add(3*time.Hour), now)
+
+		require.NoError(t, err)
+		assert.False(t, got.After(now.Add(2*time.Hour)))
+		assert.False(t, got.Before(now.Add(108*time.Minute)))
+
+		seen[got] = struct{}{}
+	}
+
+	assert.Greater(t, len(seen), 1)
+}
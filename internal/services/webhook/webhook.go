@@ -0,0 +1,504 @@
+package webhook
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/pkg/clock"
+)
+
+const (
+	defaultWorkers     = 4
+	defaultQueue       = 1024
+	defaultBackoff     = time.Second
+	defaultCeiling     = time.Minute
+	defaultTimeout     = 5 * time.Second
+	defaultDeadLetters = 1024
+	idSize             = 16
+
+	// SignatureHeader is "sha256=" and hex of HMAC-SHA256 by the secret of the subscription over
+	// the TimestampHeader value, the dot and the body, so the receiver could reject the replays.
+	SignatureHeader = "X-Apicache-Signature"
+	TimestampHeader = "X-Apicache-Timestamp"
+	EventHeader     = "X-Apicache-Event"
+)
+
+var (
+	ErrInvalidWorkers     = errors.New("invalid Workers")
+	ErrInvalidQueue       = errors.New("invalid Queue")
+	ErrInvalidRetries     = errors.New("invalid Retries")
+	ErrInvalidBackoff     = errors.New("invalid Backoff")
+	ErrInvalidCeiling     = errors.New("invalid Ceiling")
+	ErrInvalidTimeout     = errors.New("invalid Timeout")
+	ErrInvalidDeadLetters = errors.New("invalid DeadLetters")
+
+	errQueueFull = errors.New("queue full")
+	errStatus    = errors.New("unexpected status")
+	errTransient = errors.New("transient error")
+)
+
+type (
+	// Config of the webhooks, Retries of the failed delivery are spread by exponential Backoff
+	// with full jitter up to Ceiling, the delivery that failed them all is kept in DeadLetters.
+	// Zero Retries means the single attempt, zero of the others means default.
+	Config struct {
+		Clock       clock.Clock
+		Client      *http.Client
+		Workers     int
+		Queue       int
+		Retries     int
+		Backoff     time.Duration
+		Ceiling     time.Duration
+		Timeout     time.Duration
+		DeadLetters int
+	}
+	// Payload is the body of the webhook.
+	Payload struct {
+		ID           string    `json:"id"`
+		Subscription string    `json:"subscription"`
+		Event        string    `json:"event"`
+		Key          string    `json:"key"`
+		Time         time.Time `json:"time"`
+	}
+	// Webhooks notifies the subscribers about the deletions and expirations of the keys.
+	// Deliveries are made in background by the workers, so the cache never waits for the subscribers.
+	Webhooks struct {
+		mutex   sync.RWMutex
+		subs    map[string]domain.Subscription
+		closed  bool
+		letters sync.Mutex
+		dead    []domain.DeadLetter
+		next    int
+		queue   chan delivery
+		clock   clock.Clock
+		client  *http.Client
+		retries int
+		backoff time.Duration
+		ceiling time.Duration
+		ctx     context.Context //nolint:containedctx // cancels the retries on close
+		cancel  context.CancelFunc
+		wg      sync.WaitGroup
+		waiting sync.WaitGroup
+		once    sync.Once
+	}
+	delivery struct {
+		sub      domain.Subscription
+		payload  Payload
+		attempts int
+	}
+)
+
+// New creates the webhooks with the subscriptions known in advance (e.g. from the config).
+func New(cfg Config, subs ...domain.Subscription) (*Webhooks, error) {
+	cfg, err := validate(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	hooks := &Webhooks{
+		mutex:   sync.RWMutex{},
+		subs:    make(map[string]domain.Subscription),
+		closed:  false,
+		letters: sync.Mutex{},
+		dead:    make([]domain.DeadLetter, 0, cfg.DeadLetters),
+		next:    0,
+		queue:   make(chan delivery, cfg.Queue),
+		clock:   cfg.Clock,
+		client:  cfg.Client,
+		retries: cfg.Retries,
+		backoff: cfg.Backoff,
+		ceiling: cfg.Ceiling,
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      sync.WaitGroup{},
+		waiting: sync.WaitGroup{},
+		once:    sync.Once{},
+	}
+
+	for _, sub := range subs {
+		_, err = domain.NewSubscribeUseCase(hooks).Execute(sub)
+		if err != nil {
+			cancel()
+
+			return nil, fmt.Errorf("subscription %q error: %w", sub.URL, err)
+		}
+	}
+
+	hooks.wg.Add(cfg.Workers)
+
+	for range cfg.Workers {
+		go hooks.work()
+	}
+
+	return hooks, nil
+}
+
+func MustNew(cfg Config, subs ...domain.Subscription) *Webhooks {
+	obj, err := New(cfg, subs...)
+	if err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+func validate(cfg Config) (Config, error) {
+	switch {
+	case cfg.Workers < 0:
+		return cfg, ErrInvalidWorkers
+	case cfg.Queue < 0:
+		return cfg, ErrInvalidQueue
+	case cfg.Retries < 0:
+		return cfg, ErrInvalidRetries
+	case cfg.Backoff < 0:
+		return cfg, ErrInvalidBackoff
+	case cfg.Timeout < 0:
+		return cfg, ErrInvalidTimeout
+	case cfg.DeadLetters < 0:
+		return cfg, ErrInvalidDeadLetters
+	}
+
+	cfg.Workers = cmp.Or(cfg.Workers, defaultWorkers)
+	cfg.Queue = cmp.Or(cfg.Queue, defaultQueue)
+	cfg.Backoff = cmp.Or(cfg.Backoff, defaultBackoff)
+	cfg.Timeout = cmp.Or(cfg.Timeout, defaultTimeout)
+	cfg.DeadLetters = cmp.Or(cfg.DeadLetters, defaultDeadLetters)
+
+	if cfg.Ceiling != 0 && cfg.Ceiling < cfg.Backoff {
+		return cfg, ErrInvalidCeiling
+	}
+
+	cfg.Ceiling = cmp.Or(cfg.Ceiling, max(defaultCeiling, cfg.Backoff))
+
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New()
+	}
+
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: cfg.Timeout} //nolint:exhaustruct // defaults of the standard client
+	}
+
+	return cfg, nil
+}
+
+// Follow notifies the subscribers about the deletions and expirations of the cache.
+func (w *Webhooks) Follow(cache *cache.Cache) {
+	cache.OnDelete(w.Notify)
+	cache.OnExpire(w.Notify)
+}
+
+// Notify queues the deliveries to the subscribers of the event, the queue overflow is the dead letter.
+// The subscriptions are made for the flat keys, so the internal ones (see domain.Separator) are skipped.
+func (w *Webhooks) Notify(event cache.Event) {
+	if strings.Contains(event.Key, domain.Separator) {
+		return
+	}
+
+	kind := event.Reason.String()
+
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	for _, sub := range w.subs {
+		if !strings.HasPrefix(event.Key, sub.Prefix) || !slices.Contains(sub.Events, kind) {
+			continue
+		}
+
+		job := delivery{
+			sub:      sub,
+			payload:  Payload{ID: id(), Subscription: sub.ID, Event: kind, Key: event.Key, Time: event.Time},
+			attempts: 0,
+		}
+
+		if w.closed {
+			w.bury(job, 0, domain.ErrClosed)
+
+			continue
+		}
+
+		select {
+		case w.queue <- job:
+		default:
+			w.bury(job, 0, errQueueFull)
+		}
+	}
+}
+
+func (w *Webhooks) Subscribe(sub domain.Subscription) (domain.Subscription, error) {
+	if sub.ID == "" {
+		sub.ID = id()
+	}
+
+	if sub.Secret == "" {
+		sub.Secret = id()
+	}
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	w.subs[sub.ID] = sub
+
+	return sub, nil
+}
+
+func (w *Webhooks) Unsubscribe(id string) error {
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if _, ok := w.subs[id]; !ok {
+		return domain.ErrNotSubscribed
+	}
+
+	delete(w.subs, id)
+
+	return nil
+}
+
+// Subscriptions returns the subscriptions ordered by ID.
+func (w *Webhooks) Subscriptions() []domain.Subscription {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	subs := make([]domain.Subscription, 0, len(w.subs))
+	for _, sub := range w.subs {
+		subs = append(subs, sub)
+	}
+
+	slices.SortFunc(subs, func(a, b domain.Subscription) int { return strings.Compare(a.ID, b.ID) })
+
+	return subs
+}
+
+// DeadLetters returns the last failed deliveries from the oldest to the newest.
+func (w *Webhooks) DeadLetters() []domain.DeadLetter {
+	w.letters.Lock()
+	defer w.letters.Unlock()
+
+	dead := make([]domain.DeadLetter, 0, len(w.dead))
+	dead = append(dead, w.dead[w.next:]...)
+	dead = append(dead, w.dead[:w.next]...)
+
+	return dead
+}
+
+// Close stops the workers, the calls in flight are finished within the Timeout,
+// the deliveries that are not made yet (the ones waiting for the retry too) become the dead letters.
+func (w *Webhooks) Close() error {
+	err := domain.ErrClosed
+
+	w.once.Do(func() {
+		w.mutex.Lock()
+		w.closed = true
+		w.mutex.Unlock()
+
+		w.cancel()
+		w.wg.Wait()
+		w.waiting.Wait()
+
+		for {
+			select {
+			case job := <-w.queue:
+				w.bury(job, job.attempts, domain.ErrClosed)
+			default:
+				err = nil
+
+				return
+			}
+		}
+	})
+
+	return err
+}
+
+func (w *Webhooks) work() {
+	defer w.wg.Done()
+
+	for {
+		select {
+		case <-w.ctx.Done():
+			return
+		case job := <-w.queue:
+			// select picks at random, so the job could be taken after the close
+			if w.ctx.Err() != nil {
+				w.bury(job, job.attempts, domain.ErrClosed)
+
+				continue
+			}
+
+			w.deliver(job)
+		}
+	}
+}
+
+// deliver makes the single attempt, the failed one is retried later (see retry), so the worker is never
+// held by the dead subscriber while the others wait in the queue.
+func (w *Webhooks) deliver(job delivery) {
+	body, err := json.Marshal(&job.payload)
+	if err != nil {
+		w.bury(job, job.attempts, fmt.Errorf("json error: %w", err))
+
+		return
+	}
+
+	job.attempts++
+
+	err = w.post(job, body)
+	if err == nil {
+		return
+	}
+
+	if !errors.Is(err, errTransient) || job.attempts > w.retries {
+		w.bury(job, job.attempts, err)
+
+		return
+	}
+
+	w.retry(job, err)
+}
+
+// retry puts the job back to the queue after the backoff, the job that waits for it on close is the dead letter.
+func (w *Webhooks) retry(job delivery, err error) {
+	w.waiting.Add(1)
+
+	go func() {
+		defer w.waiting.Done()
+
+		if !w.sleep(w.delay(job.attempts)) {
+			w.bury(job, job.attempts, err)
+
+			return
+		}
+
+		select {
+		case w.queue <- job:
+		case <-w.ctx.Done():
+			w.bury(job, job.attempts, err)
+		}
+	}()
+}
+
+// post calls the webhook, the network errors, 5xx and 429 are worth the retry.
+func (w *Webhooks) post(job delivery, body []byte) error {
+	// the call in flight is not cancelled on close, the client timeout bounds it
+	req, err := http.NewRequestWithContext(context.WithoutCancel(w.ctx), http.MethodPost, job.sub.URL, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("request error: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(w.clock.Now().Unix(), 10)
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(EventHeader, job.payload.Event)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, Sign(job.sub.Secret, timestamp, body))
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %w", errTransient, err)
+	}
+
+	// the body is drained, so the connection is reused
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= http.StatusOK && resp.StatusCode < http.StatusMultipleChoices:
+		return nil
+	case resp.StatusCode >= http.StatusInternalServerError, resp.StatusCode == http.StatusTooManyRequests:
+		return fmt.Errorf("%w: %w %d", errTransient, errStatus, resp.StatusCode)
+	}
+
+	return fmt.Errorf("%w %d", errStatus, resp.StatusCode)
+}
+
+// delay is the full jitter of the exponential backoff, capped by the ceiling.
+func (w *Webhooks) delay(attempt int) time.Duration {
+	backoff := w.backoff
+
+	for range attempt - 1 {
+		backoff *= 2
+
+		if backoff >= w.ceiling {
+			backoff = w.ceiling
+
+			break
+		}
+	}
+
+	return mrand.N(backoff + 1) //nolint:gosec // jitter doesn't need the secure random
+}
+
+// sleep waits for the delay, false means the webhooks are closed meanwhile.
+func (w *Webhooks) sleep(delay time.Duration) bool {
+	timer := w.clock.Timer(w.clock.Now().Add(delay))
+	defer timer.Stop()
+
+	select {
+	case <-w.ctx.Done():
+		return false
+	case <-timer.C():
+		return true
+	}
+}
+
+func (w *Webhooks) bury(job delivery, attempts int, err error) {
+	letter := domain.DeadLetter{
+		ID:           job.payload.ID,
+		Subscription: job.sub.ID,
+		URL:          job.sub.URL,
+		Event:        job.payload.Event,
+		Key:          job.payload.Key,
+		Time:         job.payload.Time,
+		Attempts:     attempts,
+		Error:        err.Error(),
+	}
+
+	w.letters.Lock()
+	defer w.letters.Unlock()
+
+	if len(w.dead) < cap(w.dead) {
+		w.dead = append(w.dead, letter)
+
+		return
+	}
+
+	w.dead[w.next] = letter
+	w.next = (w.next + 1) % len(w.dead)
+}
+
+// Sign is the signature of the webhook, the receiver compares it with SignatureHeader.
+func Sign(secret string, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+
+	_, _ = mac.Write([]byte(timestamp + "."))
+	_, _ = mac.Write(body)
+
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func id() string {
+	raw := make([]byte, idSize)
+
+	_, _ = rand.Read(raw)
+
+	return hex.EncodeToString(raw)
+}
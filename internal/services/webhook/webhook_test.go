@@ -0,0 +1,344 @@
+package webhook_test
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/internal/domain"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/internal/services/webhook"
+	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const (
+	secret  = "secret"
+	waitFor = time.Second
+	tick    = time.Millisecond
+)
+
+var now = time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+type request struct {
+	header http.Header
+	body   []byte
+}
+
+// receiver answers with the statuses in turn, the last one is repeated.
+func receiver(t *testing.T, statuses ...int) (*httptest.Server, chan request) {
+	t.Helper()
+
+	requests := make(chan request, 10)
+	calls := atomic.Int64{}
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		requests <- request{header: r.Header, body: body}
+
+		w.WriteHeader(statuses[min(int(calls.Add(1))-1, len(statuses)-1)])
+	}))
+
+	t.Cleanup(srv.Close)
+
+	return srv, requests
+}
+
+func subscription(link string, prefix string, events ...string) domain.Subscription {
+	return domain.Subscription{ID: "", URL: link, Prefix: prefix, Events: events, Secret: secret}
+}
+
+func event(key string, reason cache.Reason) cache.Event {
+	return cache.Event{Key: key, Reason: reason, Deadline: time.Time{}, Time: now, Lost: 0}
+}
+
+func config() webhook.Config {
+	return webhook.Config{Backoff: time.Millisecond, Retries: 2}
+}
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		cfg  webhook.Config
+		subs []domain.Subscription
+		want error
+	}{
+		{name: "invalid Workers", cfg: webhook.Config{Workers: -1}, subs: nil, want: webhook.ErrInvalidWorkers},
+		{name: "invalid Queue", cfg: webhook.Config{Queue: -1}, subs: nil, want: webhook.ErrInvalidQueue},
+		{name: "invalid Retries", cfg: webhook.Config{Retries: -1}, subs: nil, want: webhook.ErrInvalidRetries},
+		{name: "invalid Backoff", cfg: webhook.Config{Backoff: -1}, subs: nil, want: webhook.ErrInvalidBackoff},
+		{name: "invalid Ceiling", cfg: webhook.Config{Ceiling: time.Millisecond}, subs: nil, want: webhook.ErrInvalidCeiling},
+		{name: "invalid Timeout", cfg: webhook.Config{Timeout: -1}, subs: nil, want: webhook.ErrInvalidTimeout},
+		{name: "invalid DeadLetters", cfg: webhook.Config{DeadLetters: -1}, subs: nil, want: webhook.ErrInvalidDeadLetters},
+		{
+			name: "invalid subscription",
+			cfg:  webhook.Config{},
+			subs: []domain.Subscription{subscription("test.loc", "", domain.EventExpire)},
+			want: domain.ErrInvalidURL,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			obj, err := webhook.New(test.cfg, test.subs...)
+
+			require.ErrorIs(t, err, test.want)
+			assert.Nil(t, obj)
+		})
+	}
+}
+
+func TestUnitMustNew(t *testing.T) {
+	t.Parallel()
+
+	require.Panics(t, func() { _ = webhook.MustNew(webhook.Config{Workers: -1}) })
+
+	obj := webhook.MustNew(webhook.Config{}, subscription("http://test.loc", "", domain.EventExpire))
+
+	assert.Len(t, obj.Subscriptions(), 1)
+	require.NoError(t, obj.Close())
+	require.ErrorIs(t, obj.Close(), domain.ErrClosed)
+}
+
+func TestUnitWebhooksSubscriptions(t *testing.T) {
+	t.Parallel()
+
+	obj := webhook.MustNew(config())
+	defer func() { _ = obj.Close() }()
+
+	sub, err := obj.Subscribe(domain.Subscription{ID: "", URL: "http://test.loc", Prefix: "", Events: nil, Secret: ""})
+
+	require.NoError(t, err)
+	assert.NotEmpty(t, sub.ID)
+	assert.NotEmpty(t, sub.Secret)
+
+	_, err = obj.Subscribe(domain.Subscription{ID: "0", URL: "http://test.loc", Prefix: "", Events: nil, Secret: secret})
+	require.NoError(t, err)
+
+	subs := obj.Subscriptions()
+
+	require.Len(t, subs, 2)
+	assert.Equal(t, "0", subs[0].ID)
+	assert.Equal(t, sub, subs[1])
+
+	require.NoError(t, obj.Unsubscribe("0"))
+	require.ErrorIs(t, obj.Unsubscribe("0"), domain.ErrNotSubscribed)
+	assert.Equal(t, []domain.Subscription{sub}, obj.Subscriptions())
+}
+
+func TestUnitWebhooksDeliver(t *testing.T) {
+	t.Parallel()
+
+	srv, requests := receiver(t, http.StatusOK)
+	obj := webhook.MustNew(config(), subscription(srv.URL, "session:", domain.EventExpire))
+
+	defer func() { _ = obj.Close() }()
+
+	// neither the other prefix nor the other event is delivered
+	obj.Notify(event("lock:1", cache.ReasonExpire))
+	obj.Notify(event("session:1", cache.ReasonDelete))
+	obj.Notify(event("session:2", cache.ReasonExpire))
+
+	req := <-requests
+
+	var payload webhook.Payload
+
+	require.NoError(t, json.Unmarshal(req.body, &payload))
+	assert.NotEmpty(t, payload.ID)
+	assert.Equal(t, obj.Subscriptions()[0].ID, payload.Subscription)
+	assert.Equal(t, domain.EventExpire, payload.Event)
+	assert.Equal(t, "session:2", payload.Key)
+	assert.Equal(t, now, payload.Time)
+
+	timestamp := req.header.Get(webhook.TimestampHeader)
+
+	assert.Equal(t, "application/json", req.header.Get("Content-Type"))
+	assert.Equal(t, domain.EventExpire, req.header.Get(webhook.EventHeader))
+	assert.Equal(t, webhook.Sign(secret, timestamp, req.body), req.header.Get(webhook.SignatureHeader))
+
+	require.NoError(t, obj.Close())
+	assert.Empty(t, requests)
+	assert.Empty(t, obj.DeadLetters())
+}
+
+func TestUnitWebhooksRetry(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name     string
+		retries  int
+		statuses []int
+		attempts int
+		dead     string
+	}{
+		{
+			name:     "recovered",
+			retries:  2,
+			statuses: []int{http.StatusBadGateway, http.StatusTooManyRequests, http.StatusNoContent},
+			attempts: 3,
+			dead:     "",
+		},
+		{
+			name:     "exhausted",
+			retries:  2,
+			statuses: []int{http.StatusServiceUnavailable},
+			attempts: 3,
+			dead:     "unexpected status 503",
+		},
+		{name: "permanent", retries: 2, statuses: []int{http.StatusGone}, attempts: 1, dead: "unexpected status 410"},
+		{
+			// zero is not replaced by the default
+			name:     "no retries",
+			retries:  0,
+			statuses: []int{http.StatusServiceUnavailable},
+			attempts: 1,
+			dead:     "unexpected status 503",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			cfg := config()
+			cfg.Retries = test.retries
+
+			srv, requests := receiver(t, test.statuses...)
+			obj := webhook.MustNew(cfg, subscription(srv.URL, "", domain.EventDelete))
+
+			defer func() { _ = obj.Close() }()
+
+			obj.Notify(event("key", cache.ReasonDelete))
+
+			for range test.attempts {
+				<-requests
+			}
+
+			if test.dead == "" {
+				require.NoError(t, obj.Close())
+				assert.Empty(t, obj.DeadLetters())
+
+				return
+			}
+
+			require.Eventually(t, func() bool { return len(obj.DeadLetters()) == 1 }, waitFor, tick)
+
+			letter := obj.DeadLetters()[0]
+
+			assert.Equal(t, srv.URL, letter.URL)
+			assert.Equal(t, domain.EventDelete, letter.Event)
+			assert.Equal(t, "key", letter.Key)
+			assert.Equal(t, test.attempts, letter.Attempts)
+			assert.Contains(t, letter.Error, test.dead)
+			assert.Empty(t, requests)
+		})
+	}
+}
+
+func TestUnitWebhooksRetryLater(t *testing.T) {
+	t.Parallel()
+
+	cfg := config()
+	cfg.Workers = 1
+	cfg.Retries = 1
+	cfg.Backoff = time.Hour
+
+	dead, failed := receiver(t, http.StatusServiceUnavailable)
+	live, delivered := receiver(t, http.StatusNoContent)
+	subs := []domain.Subscription{
+		subscription(dead.URL, "dead", domain.EventDelete),
+		subscription(live.URL, "live", domain.EventDelete),
+	}
+	obj := webhook.MustNew(cfg, subs...)
+
+	obj.Notify(event("dead", cache.ReasonDelete))
+	<-failed
+
+	// the only worker doesn't wait for the retry of the dead subscriber
+	obj.Notify(event("live", cache.ReasonDelete))
+
+	select {
+	case <-delivered:
+	case <-time.After(waitFor):
+		require.Fail(t, "the delivery is held by the retry")
+	}
+
+	// the retry is never made, so the close buries it
+	require.NoError(t, obj.Close())
+
+	letters := obj.DeadLetters()
+
+	require.Len(t, letters, 1)
+	assert.Equal(t, "dead", letters[0].Key)
+	assert.Contains(t, letters[0].Error, "unexpected status 503")
+}
+
+func TestUnitWebhooksDeadLetters(t *testing.T) {
+	t.Parallel()
+
+	obj := webhook.MustNew(webhook.Config{DeadLetters: 2}, subscription("http://test.loc", "", domain.EventExpire))
+
+	require.NoError(t, obj.Close())
+
+	// nothing is delivered after the close, the newest dead letters are kept
+	for _, key := range []string{"key1", "key2", "key3"} {
+		obj.Notify(event(key, cache.ReasonExpire))
+	}
+
+	dead := obj.DeadLetters()
+
+	require.Len(t, dead, 2)
+	assert.Equal(t, "key2", dead[0].Key)
+	assert.Equal(t, "key3", dead[1].Key)
+	assert.Equal(t, 0, dead[1].Attempts)
+	assert.Equal(t, domain.ErrClosed.Error(), dead[1].Error)
+}
+
+func TestUnitWebhooksFollow(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	srv, requests := receiver(t, http.StatusOK)
+	obj := webhook.MustNew(config(), subscription(srv.URL, "", domain.EventDelete, domain.EventExpire))
+	store := cache.MustNew(cache.Config{MaxConn: 1, ConnTimeout: time.Second}, machine.New())
+
+	defer func() { _ = obj.Close() }()
+
+	obj.Follow(store)
+
+	// the keys of the namespaces never reach the subscribers of the flat ones
+	internal := "team" + domain.Separator + "0" + domain.Separator + "key"
+
+	require.NoError(t, store.Set(ctx, internal, []byte("val"), time.Time{}))
+	require.NoError(t, store.Del(ctx, internal))
+	require.NoError(t, store.Set(ctx, "key", []byte("val"), time.Time{}))
+	require.NoError(t, store.Del(ctx, "key"))
+
+	req := <-requests
+
+	var payload webhook.Payload
+
+	require.NoError(t, json.Unmarshal(req.body, &payload))
+	toolkit.Assert(t, toolkit.Got(nil, req.header.Get(webhook.EventHeader)), toolkit.Want(domain.EventDelete, nil))
+	toolkit.Assert(t, toolkit.Got(nil, payload.Key), toolkit.Want("key", nil))
+	require.NoError(t, store.Close())
+}
+
+func TestUnitSign(t *testing.T) {
+	t.Parallel()
+
+	got := webhook.Sign(secret, "1704067200", []byte(`{"key":"key"}`))
+
+	assert.Equal(t, "sha256=752e863655ae718effb2208449eb055406696595830af918ea1b08e9b15c8f37", got)
+}
@@ -48,3 +48,14 @@ func (b *Blender[T]) Path(etx echo.Context) (*T, error) {
 
 	return b.validateStruct(params)
 }
+
+func (b *Blender[T]) Query(etx echo.Context) (*T, error) {
+	params := new(T)
+
+	err := b.binder.BindQueryParams(etx, params)
+	if err != nil {
+		return nil, fmt.Errorf("query error: %w", err)
+	}
+
+	return b.validateStruct(params)
+}
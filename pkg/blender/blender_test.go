@@ -24,6 +24,11 @@ type (
 		ID   int    `param:"id"`
 		JSON string `json:"json"`
 	}
+	query struct {
+		Name string `query:"name" validate:"required"`
+		ID   int    `query:"id"`
+		Path string `param:"path"`
+	}
 )
 
 var (
@@ -37,6 +42,11 @@ var (
 			`code=400, message=strconv.ParseInt: parsing "six": invalid syntax, ` +
 			`internal=strconv.ParseInt: parsing "six": invalid syntax`,
 	)
+	errQuery = errors.New(
+		"query error: " +
+			`code=400, message=strconv.ParseInt: parsing "six": invalid syntax, ` +
+			`internal=strconv.ParseInt: parsing "six": invalid syntax`,
+	)
 	errValidateJSON = errors.New(
 		"validate error: " +
 			"Key: 'json.Name' Error:Field validation for 'Name' failed on the 'required' tag",
@@ -45,6 +55,10 @@ var (
 		"validate error: " +
 			"Key: 'path.Name' Error:Field validation for 'Name' failed on the 'required' tag",
 	)
+	errValidateQuery = errors.New(
+		"validate error: " +
+			"Key: 'query.Name' Error:Field validation for 'Name' failed on the 'required' tag",
+	)
 )
 
 func TestUnitNew(t *testing.T) {
@@ -144,3 +158,45 @@ func TestUnitBlenderPath(t *testing.T) {
 		})
 	}
 }
+
+func TestUnitBlenderQuery(t *testing.T) {
+	t.Parallel()
+
+	type testCase struct {
+		name  string
+		query string
+		want  toolkit.W[*query]
+	}
+
+	tests := []testCase{
+		{
+			name:  "success",
+			query: "name=Kirill&id=666&path=ignore",
+			want:  toolkit.Want(&query{Name: "Kirill", ID: 666, Path: ""}, nil),
+		},
+		{
+			name:  "query error",
+			query: "name=Kirill&id=six",
+			want:  toolkit.Want[*query](nil, errQuery),
+		},
+		{
+			name:  "validate error",
+			query: "id=666",
+			want:  toolkit.Want[*query](nil, errValidateQuery),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			obj := blender.New[query]()
+			req := httptest.NewRequest(http.MethodGet, "/?"+test.query, nil)
+			rec := httptest.NewRecorder()
+
+			got, err := obj.Query(echo.New().NewContext(req, rec))
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
@@ -0,0 +1,46 @@
+package clock
+
+import "time"
+
+type (
+	Timer interface {
+		C() <-chan time.Time
+		Stop() bool
+		// Reset moves the timer to fire at the given moment.
+		Reset(at time.Time) bool
+	}
+	// Clock is a source of time that could be replaced in tests. Timers take the moment
+	// instead of duration, so it can't be missed between Now() and Timer() calls.
+	Clock interface {
+		Now() time.Time
+		Timer(at time.Time) Timer
+	}
+	Real  struct{}
+	timer struct {
+		timer *time.Timer
+	}
+)
+
+func New() *Real {
+	return &Real{}
+}
+
+func (r *Real) Now() time.Time {
+	return time.Now().UTC()
+}
+
+func (r *Real) Timer(at time.Time) Timer {
+	return &timer{timer: time.NewTimer(time.Until(at))}
+}
+
+func (t *timer) C() <-chan time.Time {
+	return t.timer.C
+}
+
+func (t *timer) Stop() bool {
+	return t.timer.Stop()
+}
+
+func (t *timer) Reset(at time.Time) bool {
+	return t.timer.Reset(time.Until(at))
+}
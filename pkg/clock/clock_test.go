@@ -0,0 +1,64 @@
+package clock_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/pkg/clock"
+)
+
+const wait = 10 * time.Millisecond
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	var _ clock.Clock = clock.New()
+}
+
+func TestUnitRealNow(t *testing.T) {
+	t.Parallel()
+
+	now := clock.New().Now()
+
+	assert.Equal(t, time.UTC, now.Location())
+	assert.WithinDuration(t, time.Now(), now, wait)
+}
+
+func TestUnitRealTimer(t *testing.T) {
+	t.Parallel()
+
+	obj := clock.New()
+	start := obj.Now()
+	timer := obj.Timer(start.Add(wait))
+
+	fired := <-timer.C()
+
+	assert.False(t, fired.Before(start.Add(wait)))
+	assert.False(t, timer.Stop())
+
+	// moment in the past fires immediately
+	timer.Reset(start)
+
+	select {
+	case <-timer.C():
+	case <-time.After(wait):
+		require.Fail(t, "timer was not fired")
+	}
+}
+
+func TestUnitRealTimerStop(t *testing.T) {
+	t.Parallel()
+
+	obj := clock.New()
+	timer := obj.Timer(obj.Now().Add(wait))
+
+	require.True(t, timer.Stop())
+
+	select {
+	case <-timer.C():
+		require.Fail(t, "stopped timer was fired")
+	case <-time.After(2 * wait):
+	}
+}
@@ -1,5 +1,55 @@
 package drivers
 
-import "errors"
+import (
+	"context"
+	"errors"
+	"io"
+	"net"
+	"syscall"
+)
 
-var ErrNotExist = errors.New("entity not exist")
+var (
+	ErrNotExist = errors.New("entity not exist")
+	// ErrTransient marks the failures that may go away by themselves, so the call is worth to repeat.
+	ErrTransient = errors.New("transient error")
+)
+
+// transient keeps the message of the error, so marking doesn't change what the caller sees.
+type transient struct {
+	err error
+}
+
+// Transient marks the error as ErrTransient.
+func Transient(err error) error {
+	return &transient{err: err}
+}
+
+func (e *transient) Error() string {
+	return e.err.Error()
+}
+
+func (e *transient) Unwrap() error {
+	return e.err
+}
+
+func (e *transient) Is(target error) bool {
+	return target == ErrTransient
+}
+
+// Network tells whether the error is a transient failure of the connection: timeouts,
+// resets and refusals, and the connection closed in the middle of the reply.
+// The cancelled or expired context is not transient, the caller has given up already.
+func Network(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.EPIPE) {
+		return true
+	}
+
+	var nerr net.Error
+
+	return errors.As(err, &nerr) && nerr.Timeout()
+}
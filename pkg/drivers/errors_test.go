@@ -1,14 +1,68 @@
 package drivers_test
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"syscall"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/therenotomorrow/apicache/pkg/drivers"
 	"github.com/therenotomorrow/apicache/test/toolkit"
 )
 
+var errDummy = errors.New("dummy error")
+
 func TestUnitErrNotExist(t *testing.T) {
 	t.Parallel()
 
 	toolkit.Assert(t, toolkit.Got(nil, drivers.ErrNotExist.Error()), toolkit.Want("entity not exist", nil))
 }
+
+func TestUnitErrTransient(t *testing.T) {
+	t.Parallel()
+
+	toolkit.Assert(t, toolkit.Got(nil, drivers.ErrTransient.Error()), toolkit.Want("transient error", nil))
+}
+
+func TestUnitTransient(t *testing.T) {
+	t.Parallel()
+
+	err := fmt.Errorf("wrap: %w", drivers.Transient(errDummy))
+
+	assert.EqualError(t, err, "wrap: dummy error")
+	assert.ErrorIs(t, err, drivers.ErrTransient)
+	assert.ErrorIs(t, err, errDummy)
+	assert.NotErrorIs(t, errDummy, drivers.ErrTransient)
+}
+
+func TestUnitNetwork(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{name: "eof", err: io.EOF, want: true},
+		{name: "unexpected eof", err: io.ErrUnexpectedEOF, want: true},
+		{name: "reset", err: fmt.Errorf("read: %w", syscall.ECONNRESET), want: true},
+		{name: "refused", err: fmt.Errorf("dial: %w", syscall.ECONNREFUSED), want: true},
+		{name: "broken pipe", err: syscall.EPIPE, want: true},
+		{name: "timeout", err: os.ErrDeadlineExceeded, want: true},
+		{name: "canceled", err: context.Canceled, want: false},
+		{name: "deadline", err: context.DeadlineExceeded, want: false},
+		{name: "other", err: errDummy, want: false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, test.want, drivers.Network(test.err))
+		})
+	}
+}
@@ -4,11 +4,21 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"strings"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/therenotomorrow/apicache/pkg/drivers"
 )
 
+// maxRelativeExpiration is the largest expiration memcached treats as seconds from now,
+// everything above is treated as an absolute unix timestamp.
+const maxRelativeExpiration = 30 * 24 * time.Hour
+
+// serverError starts the reply of memcached that failed the command (e.g. out of memory).
+const serverError = "SERVER_ERROR"
+
 type (
 	Config struct {
 		Addr string
@@ -31,24 +41,112 @@ func (d *Memcached) Get(_ context.Context, key string) (string, error) {
 	}
 
 	if err != nil {
-		return "", fmt.Errorf("Memcached.Get() error: %w", err)
+		return "", fmt.Errorf("Memcached.Get() error: %w", classify(err))
 	}
 
 	return string(item.Value), nil
 }
 
 func (d *Memcached) Set(_ context.Context, key string, val string) error {
-	item := &memcache.Item{
-		Key:        key,
-		Value:      []byte(val),
-		Flags:      0,
-		Expiration: 0,
-		CasID:      0,
+	err := d.client.Set(item(key, val, 0))
+	if err != nil {
+		return fmt.Errorf("Memcached.Set() error: %w", classify(err))
+	}
+
+	return nil
+}
+
+// SetEx stores the key with Expiration that is handled by memcached itself.
+func (d *Memcached) SetEx(_ context.Context, key string, val string, ttl time.Duration) error {
+	err := d.client.Set(item(key, val, ttl))
+	if err != nil {
+		return fmt.Errorf("Memcached.SetEx() error: %w", classify(err))
+	}
+
+	return nil
+}
+
+// SetNX stores the key only if it doesn't exist yet (add), zero TTL means no expiration.
+func (d *Memcached) SetNX(_ context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	err := d.client.Add(item(key, val, ttl))
+
+	if errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Memcached.SetNX() error: %w", classify(err))
+	}
+
+	return true, nil
+}
+
+// SetXX stores the key only if it exists already (replace), zero TTL means no expiration.
+func (d *Memcached) SetXX(_ context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	err := d.client.Replace(item(key, val, ttl))
+
+	if errors.Is(err, memcache.ErrNotStored) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Memcached.SetXX() error: %w", classify(err))
+	}
+
+	return true, nil
+}
+
+func (d *Memcached) TTL(_ context.Context, key string) (time.Duration, error) {
+	item, err := d.client.Get(key)
+
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return 0, drivers.ErrNotExist
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("Memcached.TTL() error: %w", classify(err))
+	}
+
+	if item.Flags == 0 {
+		return 0, nil
+	}
+
+	ttl := time.Until(time.Unix(int64(item.Flags), 0))
+	if ttl <= 0 {
+		return 0, drivers.ErrNotExist
+	}
+
+	return ttl, nil
+}
+
+// Expire moves the expiration of the key (touch), the non-positive TTL deletes the key.
+func (d *Memcached) Expire(_ context.Context, key string, ttl time.Duration) error {
+	if ttl <= 0 {
+		err := d.client.Delete(key)
+		if errors.Is(err, memcache.ErrCacheMiss) {
+			return drivers.ErrNotExist
+		}
+
+		if err != nil {
+			return fmt.Errorf("Memcached.Expire() error: %w", classify(err))
+		}
+
+		return nil
+	}
+
+	err := d.touch(key, ttl)
+	if err != nil {
+		return fmt.Errorf("Memcached.Expire() error: %w", err)
 	}
 
-	err := d.client.Set(item)
+	return nil
+}
+
+// Persist drops the expiration of the key (touch with zero).
+func (d *Memcached) Persist(_ context.Context, key string) error {
+	err := d.touch(key, 0)
 	if err != nil {
-		return fmt.Errorf("Memcached.Set() error: %w", err)
+		return fmt.Errorf("Memcached.Persist() error: %w", err)
 	}
 
 	return nil
@@ -62,12 +160,98 @@ func (d *Memcached) Del(_ context.Context, key string) error {
 	}
 
 	if err != nil {
-		return fmt.Errorf("Memcached.Del() error: %w", err)
+		return fmt.Errorf("Memcached.Del() error: %w", classify(err))
 	}
 
 	return nil
 }
 
+// DelXX deletes the key, the miss of delete tells that it didn't exist.
+func (d *Memcached) DelXX(_ context.Context, key string) (bool, error) {
+	err := d.client.Delete(key)
+
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Memcached.DelXX() error: %w", classify(err))
+	}
+
+	return true, nil
+}
+
+// touch moves the expiration of the key, then the deadline kept in the Flags follows it by cas. The cas fails
+// only because of the newer write that brought its own deadline, so the value is never overwritten.
+func (d *Memcached) touch(key string, ttl time.Duration) error {
+	next := item(key, "", ttl)
+
+	err := d.client.Touch(key, next.Expiration)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return drivers.ErrNotExist
+	}
+
+	if err != nil {
+		return classify(err)
+	}
+
+	current, err := d.client.Get(key)
+	if errors.Is(err, memcache.ErrCacheMiss) {
+		return nil
+	}
+
+	if err != nil {
+		return classify(err)
+	}
+
+	current.Flags = next.Flags
+	current.Expiration = next.Expiration
+
+	err = d.client.CompareAndSwap(current)
+	if err != nil && !errors.Is(err, memcache.ErrCASConflict) && !errors.Is(err, memcache.ErrCacheMiss) {
+		return classify(err)
+	}
+
+	return nil
+}
+
+// item is the key with Expiration that is handled by memcached itself, zero TTL means no expiration.
+// Memcached can't report the TTL back, so the deadline (unix seconds) is kept in the Flags of the item.
+func item(key string, val string, ttl time.Duration) *memcache.Item {
+	var deadline int64
+
+	// memcached has seconds precision, so round up to not lose the key before deadline
+	seconds := int32(math.Ceil(ttl.Seconds()))
+	if seconds > 0 {
+		deadline = time.Now().Add(time.Duration(seconds) * time.Second).Unix()
+	}
+
+	expiration := seconds
+	if ttl > maxRelativeExpiration {
+		expiration = int32(deadline)
+	}
+
+	return &memcache.Item{
+		Key:        key,
+		Value:      []byte(val),
+		Flags:      uint32(deadline),
+		Expiration: expiration,
+		CasID:      0,
+	}
+}
+
+// classify marks the server error (e.g. out of memory) and the broken connection as drivers.ErrTransient.
+// The client doesn't parse SERVER_ERROR reply, it comes back as the unexpected response line with it.
+func classify(err error) error {
+	var timeout *memcache.ConnectTimeoutError
+
+	if strings.Contains(err.Error(), serverError) || errors.As(err, &timeout) || drivers.Network(err) {
+		return drivers.Transient(err)
+	}
+
+	return err
+}
+
 func (d *Memcached) Close() error {
 	return nil
 }
@@ -1,9 +1,12 @@
 package memcached_test
 
 import (
+	"bufio"
 	"context"
 	"errors"
+	"net"
 	"testing"
+	"time"
 
 	"github.com/bradfitz/gomemcache/memcache"
 	"github.com/stretchr/testify/assert"
@@ -20,9 +23,11 @@ const (
 )
 
 var (
-	errDriverGet = errors.New("Memcached.Get() error: dial tcp :0: connect: connection refused")
-	errDriverSet = errors.New("Memcached.Set() error: dial tcp :0: connect: connection refused")
-	errDriverDel = errors.New("Memcached.Del() error: dial tcp :0: connect: connection refused")
+	errDriverGet   = errors.New("Memcached.Get() error: dial tcp :0: connect: connection refused")
+	errDriverSet   = errors.New("Memcached.Set() error: dial tcp :0: connect: connection refused")
+	errDriverDel   = errors.New("Memcached.Del() error: dial tcp :0: connect: connection refused")
+	errDriverSetEx = errors.New("Memcached.SetEx() error: dial tcp :0: connect: connection refused")
+	errDriverTTL   = errors.New("Memcached.TTL() error: dial tcp :0: connect: connection refused")
 )
 
 func config() memcached.Config {
@@ -44,7 +49,69 @@ func TestMain(m *testing.M) {
 func TestUnitNewWithConfig(t *testing.T) {
 	t.Parallel()
 
-	var _ cache.Driver = memcached.NewWithConfig(config())
+	var _ cache.ConditionalDriver = memcached.NewWithConfig(config())
+}
+
+func TestUnitMemcachedTransient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := memcached.NewWithConfig(memcached.Config{Addr: invalidAddr})
+
+	_, err := obj.Get(ctx, "key")
+	require.ErrorIs(t, err, drivers.ErrTransient)
+
+	// the invalid key is not going to become valid
+	_, err = obj.Get(ctx, "bad key")
+	require.NotErrorIs(t, err, drivers.ErrTransient)
+}
+
+// failing is the memcached that answers every command with the server error.
+func failing(t *testing.T) string {
+	t.Helper()
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+
+	t.Cleanup(func() { _ = listener.Close() })
+
+	go func() {
+		for {
+			conn, errA := listener.Accept()
+			if errA != nil {
+				return
+			}
+
+			go func() {
+				defer func() { _ = conn.Close() }()
+
+				reader := bufio.NewReader(conn)
+
+				for {
+					if _, errR := reader.ReadString('\n'); errR != nil {
+						return
+					}
+
+					_, _ = conn.Write([]byte("SERVER_ERROR out of memory storing object\r\n"))
+				}
+			}()
+		}
+	}()
+
+	return listener.Addr().String()
+}
+
+func TestUnitMemcachedServerError(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := memcached.NewWithConfig(memcached.Config{Addr: failing(t)})
+
+	_, err := obj.Get(ctx, "key")
+	require.ErrorIs(t, err, drivers.ErrTransient)
+
+	err = obj.Del(ctx, "key")
+	require.ErrorIs(t, err, drivers.ErrTransient)
 }
 
 func TestIntegrationMemcachedGet(t *testing.T) {
@@ -147,6 +214,106 @@ func TestIntegrationMemcachedSet(t *testing.T) {
 	}
 }
 
+func TestIntegrationMemcachedSetEx(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		cfg memcached.Config
+		key string
+		ttl time.Duration
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[any]
+	}{
+		{
+			name: "success",
+			args: args{cfg: config(), key: "newExKey", ttl: time.Minute},
+			want: toolkit.Err(nil),
+		},
+		{
+			name: "absolute expiration",
+			args: args{cfg: config(), key: "newLongKey", ttl: 60 * 24 * time.Hour},
+			want: toolkit.Err(nil),
+		},
+		{
+			name: "driver error",
+			args: args{cfg: memcached.Config{Addr: invalidAddr}, key: "key", ttl: time.Minute},
+			want: toolkit.Err(errDriverSetEx),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			obj := memcached.NewWithConfig(test.args.cfg)
+
+			err := obj.SetEx(ctx, test.args.key, "newVal", test.args.ttl)
+
+			toolkit.Assert(t, toolkit.Got[any](err), test.want)
+
+			if test.name == "driver error" {
+				return
+			}
+
+			// check inner client data
+			val, _ := obj.Get(ctx, test.args.key)
+			ttl, _ := obj.TTL(ctx, test.args.key)
+
+			assert.Equal(t, "newVal", val)
+			assert.InDelta(t, test.args.ttl, ttl, float64(2*time.Second))
+		})
+	}
+}
+
+func TestIntegrationMemcachedTTL(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		cfg memcached.Config
+		key string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[time.Duration]
+	}{
+		{
+			name: "no expiration",
+			args: args{cfg: config(), key: "insertKey"},
+			want: toolkit.Want(time.Duration(0), nil),
+		},
+		{
+			name: "key not exist",
+			args: args{cfg: config(), key: "invalidKey"},
+			want: toolkit.Want(time.Duration(0), drivers.ErrNotExist),
+		},
+		{
+			name: "driver error",
+			args: args{cfg: memcached.Config{Addr: invalidAddr}, key: "key"},
+			want: toolkit.Want(time.Duration(0), errDriverTTL),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			obj := memcached.NewWithConfig(test.args.cfg)
+
+			got, err := obj.TTL(ctx, test.args.key)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+}
+
 func TestIntegrationMemcachedDel(t *testing.T) {
 	t.Parallel()
 
@@ -201,6 +368,78 @@ func TestIntegrationMemcachedDel(t *testing.T) {
 	}
 }
 
+func TestIntegrationMemcachedConditional(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := memcached.NewWithConfig(config())
+	client := memcache.New(addr)
+
+	ok, err := obj.SetNX(ctx, "conditionalKey", "newVal", time.Minute)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+
+	ok, err = obj.SetNX(ctx, "conditionalKey", "otherVal", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+
+	ok, err = obj.SetXX(ctx, "conditionalKey", "otherVal", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+
+	item, err := client.Get("conditionalKey")
+
+	require.NoError(t, err)
+	assert.Equal(t, "otherVal", string(item.Value))
+	assert.Zero(t, item.Flags)
+
+	ok, err = obj.SetXX(ctx, "missingKey", "newVal", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+
+	ok, err = obj.DelXX(ctx, "conditionalKey")
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+
+	ok, err = obj.DelXX(ctx, "conditionalKey")
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+
+	_, err = memcached.NewWithConfig(memcached.Config{Addr: invalidAddr}).SetNX(ctx, "key", "val", 0)
+
+	require.ErrorIs(t, err, drivers.ErrTransient)
+}
+
+func TestIntegrationMemcachedExpire(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := memcached.NewWithConfig(config())
+
+	require.NoError(t, obj.Set(ctx, "expireKey", "expireVal"))
+	require.NoError(t, obj.Expire(ctx, "expireKey", time.Minute))
+
+	ttl, err := obj.TTL(ctx, "expireKey")
+
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(2*time.Second))
+
+	require.NoError(t, obj.Persist(ctx, "expireKey"))
+
+	ttl, err = obj.TTL(ctx, "expireKey")
+
+	toolkit.Assert(t, toolkit.Got(err, ttl), toolkit.Want(time.Duration(0), nil))
+
+	// the value is never written back
+	got, err := obj.Get(ctx, "expireKey")
+
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("expireVal", nil))
+
+	require.NoError(t, obj.Expire(ctx, "expireKey", 0))
+	require.ErrorIs(t, obj.Expire(ctx, "expireKey", time.Minute), drivers.ErrNotExist)
+	require.ErrorIs(t, obj.Persist(ctx, "expireKey"), drivers.ErrNotExist)
+}
+
 func TestUnitMemcachedClose(t *testing.T) {
 	t.Parallel()
 
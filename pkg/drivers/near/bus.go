@@ -0,0 +1,40 @@
+package near
+
+import (
+	"context"
+	"sync"
+)
+
+// Local is a Bus within the process, so the replicas in the same process (e.g. tests) could share it.
+type Local struct {
+	mutex    sync.RWMutex
+	handlers []func(key string)
+}
+
+func NewLocal() *Local {
+	return &Local{mutex: sync.RWMutex{}, handlers: nil}
+}
+
+func (b *Local) Publish(_ context.Context, key string) error {
+	b.mutex.RLock()
+	defer b.mutex.RUnlock()
+
+	for _, handler := range b.handlers {
+		handler(key)
+	}
+
+	return nil
+}
+
+func (b *Local) Subscribe(handler func(key string)) error {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.handlers = append(b.handlers, handler)
+
+	return nil
+}
+
+func (b *Local) Close() error {
+	return nil
+}
@@ -0,0 +1,364 @@
+package near
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/pkg/clock"
+	"github.com/therenotomorrow/apicache/pkg/drivers"
+	"github.com/therenotomorrow/apicache/pkg/eviction"
+)
+
+const (
+	defaultMaxKeys = 1024
+	defaultTTL     = time.Second
+)
+
+var (
+	ErrInvalidMaxKeys = errors.New("invalid MaxKeys")
+	ErrInvalidTTL     = errors.New("invalid TTL")
+)
+
+type (
+	Driver interface {
+		Get(ctx context.Context, key string) (string, error)
+		Set(ctx context.Context, key string, val string) error
+		Del(ctx context.Context, key string) error
+		io.Closer
+	}
+	ExpiringDriver interface {
+		Driver
+		SetEx(ctx context.Context, key string, val string, ttl time.Duration) error
+		TTL(ctx context.Context, key string) (time.Duration, error)
+		Expire(ctx context.Context, key string, ttl time.Duration) error
+		Persist(ctx context.Context, key string) error
+	}
+	ConditionalDriver interface {
+		ExpiringDriver
+		SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error)
+		SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error)
+		DelXX(ctx context.Context, key string) (bool, error)
+	}
+	// Bus delivers the invalidated keys to all the replicas, including the publisher.
+	Bus interface {
+		Publish(ctx context.Context, key string) error
+		// Subscribe calls the handler for every key published after the call.
+		Subscribe(handler func(key string)) error
+		io.Closer
+	}
+	Config struct {
+		// MaxKeys bounds the local tier, zero means default.
+		MaxKeys int
+		// TTL is how long the local copy is trusted without the remote, zero means default.
+		// It bounds the staleness when the invalidation is lost or the key expires remotely.
+		TTL time.Duration
+		// Bus invalidates the local tiers of the other replicas, nil means single replica.
+		Bus Bus
+		// Clock is a source of time for the local TTL, nil means the real one.
+		Clock clock.Clock
+	}
+	item struct {
+		val      string
+		deadline time.Time
+	}
+	// Near is a two-tier driver: the bounded local copies of the hot keys in front of the remote
+	// driver. Writes go to the remote and invalidate the local copies of all the replicas.
+	Near struct {
+		cfg    Config
+		remote Driver
+		mutex  sync.Mutex
+		items  map[string]item
+		policy eviction.Policy
+		// epoch grows with every invalidation, so the value read from the remote
+		// before the invalidation is never put into the local tier after it.
+		epoch uint64
+		once  sync.Once
+	}
+	// expiringNear is the Near over the ExpiringDriver, so it expires the keys by the remote.
+	expiringNear struct {
+		*Near
+
+		native ExpiringDriver
+	}
+	// conditionalNear is the Near over the ConditionalDriver, so it checks the conditions by the remote.
+	conditionalNear struct {
+		*expiringNear
+
+		conditional ConditionalDriver
+	}
+)
+
+// NewWithConfig puts the Near in front of the remote. The Near is the ExpiringDriver or the ConditionalDriver
+// only when the remote is, so the capabilities of the remote are known right after the construction.
+func NewWithConfig(cfg Config, remote Driver) (Driver, error) { //nolint:ireturn // capabilities follow the remote
+	if cfg.MaxKeys < 0 {
+		return nil, ErrInvalidMaxKeys
+	}
+
+	if cfg.TTL < 0 {
+		return nil, ErrInvalidTTL
+	}
+
+	if cfg.MaxKeys == 0 {
+		cfg.MaxKeys = defaultMaxKeys
+	}
+
+	if cfg.TTL == 0 {
+		cfg.TTL = defaultTTL
+	}
+
+	if cfg.Clock == nil {
+		cfg.Clock = clock.New()
+	}
+
+	near := &Near{
+		cfg:    cfg,
+		remote: remote,
+		mutex:  sync.Mutex{},
+		items:  make(map[string]item),
+		policy: eviction.NewLRU(),
+		epoch:  0,
+		once:   sync.Once{},
+	}
+
+	if cfg.Bus != nil {
+		err := cfg.Bus.Subscribe(near.invalidate)
+		if err != nil {
+			return nil, fmt.Errorf("Near.Subscribe() error: %w", err)
+		}
+	}
+
+	switch remote := remote.(type) {
+	case ConditionalDriver:
+		return &conditionalNear{expiringNear: &expiringNear{Near: near, native: remote}, conditional: remote}, nil
+	case ExpiringDriver:
+		return &expiringNear{Near: near, native: remote}, nil
+	}
+
+	return near, nil
+}
+
+func MustNewWithConfig(cfg Config, remote Driver) Driver { //nolint:ireturn // capabilities follow the remote
+	obj, err := NewWithConfig(cfg, remote)
+	if err != nil {
+		panic(err)
+	}
+
+	return obj
+}
+
+func (d *Near) Get(ctx context.Context, key string) (string, error) {
+	d.mutex.Lock()
+
+	epoch := d.epoch
+
+	it, ok := d.items[key]
+	if ok && d.cfg.Clock.Now().Before(it.deadline) {
+		d.policy.Touch(key)
+		d.mutex.Unlock()
+
+		return it.val, nil
+	}
+
+	d.mutex.Unlock()
+
+	val, err := d.remote.Get(ctx, key)
+	if errors.Is(err, drivers.ErrNotExist) {
+		return "", drivers.ErrNotExist
+	}
+
+	if err != nil {
+		return "", fmt.Errorf("Near.Get() error: %w", err)
+	}
+
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	if epoch == d.epoch {
+		d.store(key, val)
+	}
+
+	return val, nil
+}
+
+func (d *Near) Set(ctx context.Context, key string, val string) error {
+	err := d.remote.Set(ctx, key, val)
+	if err != nil {
+		return fmt.Errorf("Near.Set() error: %w", err)
+	}
+
+	d.publish(ctx, key)
+
+	return nil
+}
+
+func (d *expiringNear) SetEx(ctx context.Context, key string, val string, ttl time.Duration) error {
+	err := d.native.SetEx(ctx, key, val, ttl)
+	if err != nil {
+		return fmt.Errorf("Near.SetEx() error: %w", err)
+	}
+
+	d.publish(ctx, key)
+
+	return nil
+}
+
+// TTL always asks the remote, the local tier doesn't know the expiration of the keys it has read.
+func (d *expiringNear) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := d.native.TTL(ctx, key)
+	if errors.Is(err, drivers.ErrNotExist) {
+		return 0, drivers.ErrNotExist
+	}
+
+	if err != nil {
+		return 0, fmt.Errorf("Near.TTL() error: %w", err)
+	}
+
+	return ttl, nil
+}
+
+// Expire invalidates the key, so the local copy doesn't outlive the new expiration.
+func (d *expiringNear) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	err := d.native.Expire(ctx, key, ttl)
+	if errors.Is(err, drivers.ErrNotExist) {
+		return drivers.ErrNotExist
+	}
+
+	if err != nil {
+		return fmt.Errorf("Near.Expire() error: %w", err)
+	}
+
+	d.publish(ctx, key)
+
+	return nil
+}
+
+// Persist keeps the local copies, the key only lives longer in the remote.
+func (d *expiringNear) Persist(ctx context.Context, key string) error {
+	err := d.native.Persist(ctx, key)
+	if errors.Is(err, drivers.ErrNotExist) {
+		return drivers.ErrNotExist
+	}
+
+	if err != nil {
+		return fmt.Errorf("Near.Persist() error: %w", err)
+	}
+
+	return nil
+}
+
+// SetNX invalidates the key unless the remote refused it, the failed call could have written it as well.
+func (d *conditionalNear) SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	ok, err := d.conditional.SetNX(ctx, key, val, ttl)
+	if ok || err != nil {
+		d.publish(ctx, key)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Near.SetNX() error: %w", err)
+	}
+
+	return ok, nil
+}
+
+// SetXX invalidates the key the same way as SetNX.
+func (d *conditionalNear) SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	ok, err := d.conditional.SetXX(ctx, key, val, ttl)
+	if ok || err != nil {
+		d.publish(ctx, key)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Near.SetXX() error: %w", err)
+	}
+
+	return ok, nil
+}
+
+// DelXX invalidates the key the same way as SetNX.
+func (d *conditionalNear) DelXX(ctx context.Context, key string) (bool, error) {
+	ok, err := d.conditional.DelXX(ctx, key)
+	if ok || err != nil {
+		d.publish(ctx, key)
+	}
+
+	if err != nil {
+		return false, fmt.Errorf("Near.DelXX() error: %w", err)
+	}
+
+	return ok, nil
+}
+
+func (d *Near) Del(ctx context.Context, key string) error {
+	err := d.remote.Del(ctx, key)
+	if err != nil {
+		return fmt.Errorf("Near.Del() error: %w", err)
+	}
+
+	d.publish(ctx, key)
+
+	return nil
+}
+
+func (d *Near) Close() error {
+	var err error
+
+	d.once.Do(func() {
+		if d.cfg.Bus != nil {
+			err = d.cfg.Bus.Close()
+		}
+
+		err = errors.Join(err, d.remote.Close())
+	})
+
+	if err != nil {
+		return fmt.Errorf("Near.Close() error: %w", err)
+	}
+
+	return nil
+}
+
+// publish drops the local copy and tells the other replicas to do the same. The write is
+// already done by then, so the lost message only makes the replicas stale until the local TTL.
+func (d *Near) publish(ctx context.Context, key string) {
+	d.invalidate(key)
+
+	if d.cfg.Bus != nil {
+		_ = d.cfg.Bus.Publish(ctx, key)
+	}
+}
+
+func (d *Near) invalidate(key string) {
+	d.mutex.Lock()
+	defer d.mutex.Unlock()
+
+	d.epoch++
+
+	if _, ok := d.items[key]; ok {
+		delete(d.items, key)
+		d.policy.Remove(key)
+	}
+}
+
+func (d *Near) store(key string, val string) {
+	if _, ok := d.items[key]; ok {
+		d.policy.Touch(key)
+	} else {
+		d.policy.Push(key)
+	}
+
+	d.items[key] = item{val: val, deadline: d.cfg.Clock.Now().Add(d.cfg.TTL)}
+
+	for len(d.items) > d.cfg.MaxKeys {
+		victim, ok := d.policy.Evict()
+		if !ok {
+			break
+		}
+
+		delete(d.items, victim)
+	}
+}
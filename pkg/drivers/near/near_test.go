@@ -0,0 +1,268 @@
+package near_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/internal/services/cache"
+	"github.com/therenotomorrow/apicache/pkg/drivers"
+	"github.com/therenotomorrow/apicache/pkg/drivers/machine"
+	"github.com/therenotomorrow/apicache/pkg/drivers/near"
+	"github.com/therenotomorrow/apicache/test/mocks"
+	"github.com/therenotomorrow/apicache/test/toolkit"
+)
+
+const localTTL = time.Second
+
+var errDummy = errors.New("dummy error")
+
+// counted is a remote that counts the reads that reached it.
+func counted(remote near.Driver) (*mocks.DriverMock, *atomic.Int32) {
+	driver := mocks.NewDriverMock()
+	reads := new(atomic.Int32)
+
+	driver.GetMock = func(ctx context.Context, key string) (string, error) {
+		reads.Add(1)
+
+		return remote.Get(ctx, key)
+	}
+	driver.SetMock = remote.Set
+	driver.DelMock = remote.Del
+
+	return driver, reads
+}
+
+func TestUnitNewWithConfig(t *testing.T) {
+	t.Parallel()
+
+	obj, err := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: 0, Bus: nil, Clock: nil}, machine.New())
+	require.NoError(t, err)
+
+	var _ cache.Driver = obj
+
+	_, err = near.NewWithConfig(near.Config{MaxKeys: -1, TTL: 0, Bus: nil, Clock: nil}, machine.New())
+	require.ErrorIs(t, err, near.ErrInvalidMaxKeys)
+
+	_, err = near.NewWithConfig(near.Config{MaxKeys: 0, TTL: -1, Bus: nil, Clock: nil}, machine.New())
+	require.ErrorIs(t, err, near.ErrInvalidTTL)
+}
+
+func TestUnitMustNewWithConfig(t *testing.T) {
+	t.Parallel()
+
+	require.NotPanics(t, func() {
+		_ = near.MustNewWithConfig(near.Config{MaxKeys: 0, TTL: 0, Bus: nil, Clock: nil}, machine.New())
+	})
+
+	require.Panics(t, func() {
+		_ = near.MustNewWithConfig(near.Config{MaxKeys: -1, TTL: 0, Bus: nil, Clock: nil}, machine.New())
+	})
+}
+
+func TestUnitNearGet(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	clock := mocks.NewClockMock(time.Now())
+	remote, reads := counted(machine.New())
+	obj, _ := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: localTTL, Bus: nil, Clock: clock}, remote)
+
+	require.NoError(t, remote.Set(ctx, "key", "val"))
+
+	// the hot key is read from the remote once
+	for range 3 {
+		got, err := obj.Get(ctx, "key")
+		toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("val", nil))
+	}
+
+	assert.Equal(t, int32(1), reads.Load())
+
+	// the local copy is trusted only within the local TTL
+	clock.Advance(localTTL)
+
+	got, err := obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("val", nil))
+	assert.Equal(t, int32(2), reads.Load())
+
+	got, err = obj.Get(ctx, "missing")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("", drivers.ErrNotExist))
+
+	remote.GetMock = func(_ context.Context, _ string) (string, error) {
+		return "", errDummy
+	}
+
+	got, err = obj.Get(ctx, "other")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("", errors.New("Near.Get() error: dummy error")))
+}
+
+func TestUnitNearMaxKeys(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	remote, reads := counted(machine.New())
+	obj, _ := near.NewWithConfig(near.Config{MaxKeys: 2, TTL: localTTL, Bus: nil, Clock: nil}, remote)
+
+	for _, key := range []string{"key1", "key2", "key3", "key3", "key2", "key1"} {
+		_ = remote.Set(ctx, key, key)
+		_, _ = obj.Get(ctx, key)
+	}
+
+	// key1 was the least recently used one, when key3 came
+	assert.Equal(t, int32(4), reads.Load())
+}
+
+func TestUnitNearInvalidation(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := near.NewLocal()
+	remote := machine.New()
+	replica1, _ := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: time.Hour, Bus: bus, Clock: nil}, remote)
+	replica2, _ := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: time.Hour, Bus: bus, Clock: nil}, remote)
+
+	require.NoError(t, replica1.Set(ctx, "key", "old"))
+
+	got, err := replica2.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("old", nil))
+
+	// the write of one replica drops the local copies of the others
+	require.NoError(t, replica1.Set(ctx, "key", "new"))
+
+	got, err = replica2.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("new", nil))
+
+	require.NoError(t, replica1.Del(ctx, "key"))
+
+	got, err = replica2.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("", drivers.ErrNotExist))
+}
+
+func TestUnitNearExpiring(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	remote := mocks.NewDriverMock()
+	remote.TTLMock = func(_ context.Context, _ string) (time.Duration, error) {
+		return localTTL, nil
+	}
+
+	obj, _ := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: 0, Bus: nil, Clock: nil}, remote)
+
+	expiring, ok := obj.(cache.ExpiringDriver)
+	require.True(t, ok)
+	require.NoError(t, expiring.SetEx(ctx, "key", "val", localTTL))
+
+	ttl, err := expiring.TTL(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, ttl), toolkit.Want(localTTL, nil))
+
+	gone := atomic.Bool{}
+	remote.GetMock = func(_ context.Context, _ string) (string, error) {
+		if gone.Load() {
+			return "", drivers.ErrNotExist
+		}
+
+		return "val", nil
+	}
+	remote.ExpireMock = func(_ context.Context, _ string, ttl time.Duration) error {
+		gone.Store(ttl <= 0)
+
+		return nil
+	}
+
+	got, err := obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("val", nil))
+
+	// the local copy doesn't outlive the new expiration
+	require.NoError(t, expiring.Expire(ctx, "key", 0))
+
+	got, err = obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("", drivers.ErrNotExist))
+
+	_, ok = obj.(cache.ConditionalDriver)
+	assert.False(t, ok)
+
+	// machine can't expire the keys by itself, so the Stateless cache refuses it from the start
+	obj, _ = near.NewWithConfig(near.Config{MaxKeys: 0, TTL: 0, Bus: nil, Clock: nil}, machine.New())
+
+	_, ok = obj.(cache.ExpiringDriver)
+	assert.False(t, ok)
+
+	_, err = cache.New(cache.Config{MaxConn: 1, ConnTimeout: time.Second, Stateless: true}, obj)
+	require.ErrorIs(t, err, cache.ErrInvalidStateless)
+}
+
+func TestUnitNearConditional(t *testing.T) {
+	t.Parallel()
+
+	val := "old"
+	ctx := context.Background()
+	remote := mocks.NewConditionalDriverMock()
+	remote.GetMock = func(_ context.Context, _ string) (string, error) {
+		return val, nil
+	}
+	remote.SetNXMock = func(_ context.Context, _ string, _ string, _ time.Duration) (bool, error) {
+		return false, nil
+	}
+	remote.SetXXMock = func(_ context.Context, _ string, newVal string, _ time.Duration) (bool, error) {
+		val = newVal
+
+		return true, nil
+	}
+	remote.DelXXMock = func(_ context.Context, _ string) (bool, error) {
+		return false, errDummy
+	}
+
+	obj, _ := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: time.Hour, Bus: nil, Clock: nil}, remote)
+
+	conditional, ok := obj.(cache.ConditionalDriver)
+	require.True(t, ok)
+
+	got, err := obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("old", nil))
+
+	// the written key is invalidated
+	ok, err = conditional.SetXX(ctx, "key", "new", 0)
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+
+	got, err = obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("new", nil))
+
+	// the refused write changes nothing, so the local copy is still good
+	val = "other"
+
+	ok, err = conditional.SetNX(ctx, "key", "newest", 0)
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+
+	got, err = obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("new", nil))
+
+	// the failed call could have changed the key
+	ok, err = conditional.DelXX(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, errors.New("Near.DelXX() error: dummy error")))
+
+	got, err = obj.Get(ctx, "key")
+	toolkit.Assert(t, toolkit.Got(err, got), toolkit.Want("other", nil))
+}
+
+func TestUnitNearClose(t *testing.T) {
+	t.Parallel()
+
+	remote := mocks.NewDriverMock()
+	calls := atomic.Int32{}
+	remote.CloseMock = func() error {
+		calls.Add(1)
+
+		return errDummy
+	}
+
+	obj, _ := near.NewWithConfig(near.Config{MaxKeys: 0, TTL: 0, Bus: near.NewLocal(), Clock: nil}, remote)
+
+	require.EqualError(t, obj.Close(), "Near.Close() error: dummy error")
+	require.NoError(t, obj.Close())
+	assert.Equal(t, int32(1), calls.Load())
+}
@@ -0,0 +1,87 @@
+package redis
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type (
+	BusConfig struct {
+		Addr    string
+		Channel string
+	}
+	// Bus delivers the keys through Redis pub/sub. The messages published while the subscriber
+	// is reconnecting are lost, so the receivers must not trust the delivery forever.
+	Bus struct {
+		cfg    BusConfig
+		once   sync.Once
+		client *redis.Client
+		mutex  sync.Mutex
+		subs   []*redis.PubSub
+	}
+)
+
+func NewBusWithConfig(cfg BusConfig) *Bus {
+	options := new(redis.Options)
+	options.Addr = cfg.Addr
+
+	return &Bus{cfg: cfg, once: sync.Once{}, client: redis.NewClient(options), mutex: sync.Mutex{}, subs: nil}
+}
+
+func (b *Bus) Publish(ctx context.Context, key string) error {
+	_, err := b.client.Publish(ctx, b.cfg.Channel, key).Result()
+	if err != nil {
+		return fmt.Errorf("Bus.Publish() error: %w", classify(err))
+	}
+
+	return nil
+}
+
+func (b *Bus) Subscribe(handler func(key string)) error {
+	ctx := context.Background()
+	sub := b.client.Subscribe(ctx, b.cfg.Channel)
+
+	// wait for the confirmation, so nothing published after the call is missed
+	_, err := sub.Receive(ctx)
+	if err != nil {
+		_ = sub.Close()
+
+		return fmt.Errorf("Bus.Subscribe() error: %w", classify(err))
+	}
+
+	b.mutex.Lock()
+	b.subs = append(b.subs, sub)
+	b.mutex.Unlock()
+
+	go func() {
+		for msg := range sub.Channel() {
+			handler(msg.Payload)
+		}
+	}()
+
+	return nil
+}
+
+func (b *Bus) Close() error {
+	var err error
+
+	b.once.Do(func() {
+		b.mutex.Lock()
+		defer b.mutex.Unlock()
+
+		for _, sub := range b.subs {
+			_ = sub.Close()
+		}
+
+		err = b.client.Close()
+	})
+
+	if err != nil {
+		return fmt.Errorf("Bus.Close() error: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,47 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"github.com/therenotomorrow/apicache/pkg/drivers/near"
+	"github.com/therenotomorrow/apicache/pkg/drivers/redis"
+)
+
+func TestUnitNewBusWithConfig(t *testing.T) {
+	t.Parallel()
+
+	var _ near.Bus = redis.NewBusWithConfig(redis.BusConfig{Addr: addr, Channel: "channel"})
+}
+
+func TestIntegrationBus(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	bus := redis.NewBusWithConfig(redis.BusConfig{Addr: addr, Channel: "invalidate"})
+	keys := make(chan string, 1)
+
+	t.Cleanup(func() { _ = bus.Close() })
+
+	require.NoError(t, bus.Subscribe(func(key string) { keys <- key }))
+	require.NoError(t, bus.Publish(ctx, "key"))
+
+	select {
+	case key := <-keys:
+		require.Equal(t, "key", key)
+	case <-time.After(time.Second):
+		require.Fail(t, "no message")
+	}
+}
+
+func TestUnitBusError(t *testing.T) {
+	t.Parallel()
+
+	bus := redis.NewBusWithConfig(redis.BusConfig{Addr: invalidAddr, Channel: "invalidate"})
+
+	require.Error(t, bus.Subscribe(func(_ string) {}))
+	require.Error(t, bus.Publish(context.Background(), "key"))
+	require.NoError(t, bus.Close())
+}
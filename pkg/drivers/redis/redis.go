@@ -5,11 +5,20 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"time"
 
 	"github.com/redis/go-redis/v9"
 	"github.com/therenotomorrow/apicache/pkg/drivers"
 )
 
+const (
+	noKey        = -2 * time.Nanosecond
+	noExpiration = -1 * time.Nanosecond
+)
+
+// busy are the replies of the server that is alive, but can't serve right now.
+var busy = []string{"LOADING", "BUSY", "TRYAGAIN", "MASTERDOWN", "CLUSTERDOWN", "ERR max number of clients reached"}
+
 type (
 	Config struct {
 		Addr string
@@ -36,7 +45,7 @@ func (d *Redis) Get(ctx context.Context, key string) (string, error) {
 	}
 
 	if err != nil {
-		return "", fmt.Errorf("Redis.Get() error: %w", err)
+		return "", fmt.Errorf("Redis.Get() error: %w", classify(err))
 	}
 
 	return val, nil
@@ -45,7 +54,91 @@ func (d *Redis) Get(ctx context.Context, key string) (string, error) {
 func (d *Redis) Set(ctx context.Context, key string, val string) error {
 	_, err := d.client.Set(ctx, key, val, 0).Result()
 	if err != nil {
-		return fmt.Errorf("Redis.Set() error: %w", err)
+		return fmt.Errorf("Redis.Set() error: %w", classify(err))
+	}
+
+	return nil
+}
+
+// SetEx stores the key with expiration in milliseconds (PX) that is handled by Redis itself.
+func (d *Redis) SetEx(ctx context.Context, key string, val string, ttl time.Duration) error {
+	_, err := d.client.Set(ctx, key, val, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("Redis.SetEx() error: %w", classify(err))
+	}
+
+	return nil
+}
+
+// SetNX stores the key only if it doesn't exist yet (SET NX PX), zero TTL means no expiration.
+func (d *Redis) SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	ok, err := d.client.SetNX(ctx, key, val, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("Redis.SetNX() error: %w", classify(err))
+	}
+
+	return ok, nil
+}
+
+// SetXX stores the key only if it exists already (SET XX PX), zero TTL means no expiration.
+func (d *Redis) SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	ok, err := d.client.SetXX(ctx, key, val, ttl).Result()
+	if err != nil {
+		return false, fmt.Errorf("Redis.SetXX() error: %w", classify(err))
+	}
+
+	return ok, nil
+}
+
+func (d *Redis) TTL(ctx context.Context, key string) (time.Duration, error) {
+	ttl, err := d.client.PTTL(ctx, key).Result()
+	if err != nil {
+		return 0, fmt.Errorf("Redis.TTL() error: %w", classify(err))
+	}
+
+	// PTTL replies -2 if the key does not exist and -1 if the key has no expiration
+	switch ttl {
+	case noKey:
+		return 0, drivers.ErrNotExist
+	case noExpiration:
+		return 0, nil
+	}
+
+	return ttl, nil
+}
+
+// Expire moves the expiration of the key in milliseconds (PEXPIRE), the non-positive TTL deletes the key.
+func (d *Redis) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	ok, err := d.client.PExpire(ctx, key, ttl).Result()
+	if err != nil {
+		return fmt.Errorf("Redis.Expire() error: %w", classify(err))
+	}
+
+	if !ok {
+		return drivers.ErrNotExist
+	}
+
+	return nil
+}
+
+// Persist drops the expiration of the key (PERSIST), it replies zero for the infinite key as well.
+func (d *Redis) Persist(ctx context.Context, key string) error {
+	ok, err := d.client.Persist(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("Redis.Persist() error: %w", classify(err))
+	}
+
+	if ok {
+		return nil
+	}
+
+	count, err := d.client.Exists(ctx, key).Result()
+	if err != nil {
+		return fmt.Errorf("Redis.Persist() error: %w", classify(err))
+	}
+
+	if count == 0 {
+		return drivers.ErrNotExist
 	}
 
 	return nil
@@ -54,12 +147,37 @@ func (d *Redis) Set(ctx context.Context, key string, val string) error {
 func (d *Redis) Del(ctx context.Context, key string) error {
 	_, err := d.client.Del(ctx, key).Result()
 	if err != nil {
-		return fmt.Errorf("Redis.Del() error: %w", err)
+		return fmt.Errorf("Redis.Del() error: %w", classify(err))
 	}
 
 	return nil
 }
 
+// DelXX deletes the key, DEL replies the number of the deleted keys.
+func (d *Redis) DelXX(ctx context.Context, key string) (bool, error) {
+	count, err := d.client.Del(ctx, key).Result()
+	if err != nil {
+		return false, fmt.Errorf("Redis.DelXX() error: %w", classify(err))
+	}
+
+	return count > 0, nil
+}
+
+// classify marks the busy server and the broken connection as drivers.ErrTransient.
+func classify(err error) error {
+	for _, prefix := range busy {
+		if redis.HasErrorPrefix(err, prefix) {
+			return drivers.Transient(err)
+		}
+	}
+
+	if drivers.Network(err) {
+		return drivers.Transient(err)
+	}
+
+	return err
+}
+
 func (d *Redis) Close() error {
 	var err error
 
@@ -25,6 +25,8 @@ var (
 	errDriverGet    = errors.New("Redis.Get() error: dial tcp :0: connect: connection refused")
 	errDriverSet    = errors.New("Redis.Set() error: dial tcp :0: connect: connection refused")
 	errDriverDel    = errors.New("Redis.Del() error: dial tcp :0: connect: connection refused")
+	errDriverSetEx  = errors.New("Redis.SetEx() error: dial tcp :0: connect: connection refused")
+	errDriverTTL    = errors.New("Redis.TTL() error: dial tcp :0: connect: connection refused")
 	errDriverClosed = fmt.Errorf("Redis.Close() error: %w", redislib.ErrClosed)
 )
 
@@ -45,6 +47,7 @@ func TestMain(m *testing.M) {
 	_ = client.Set(ctx, "insertKey", "insertVal", 0)
 	_ = client.Set(ctx, "updateKey", "updateVal", 0)
 	_ = client.Set(ctx, "deleteKey", "deleteVal", 0)
+	_ = client.Set(ctx, "expireKey", "expireVal", time.Hour)
 
 	m.Run()
 }
@@ -52,7 +55,24 @@ func TestMain(m *testing.M) {
 func TestUnitNewWithConfig(t *testing.T) {
 	t.Parallel()
 
-	var _ cache.Driver = redis.NewWithConfig(config())
+	var _ cache.ConditionalDriver = redis.NewWithConfig(config())
+}
+
+func TestUnitRedisTransient(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := redis.NewWithConfig(redis.Config{Addr: invalidAddr})
+
+	_, err := obj.Get(ctx, "key")
+	require.ErrorIs(t, err, drivers.ErrTransient)
+
+	require.NoError(t, obj.Close())
+
+	// the closed client is not going to recover
+	_, err = obj.Get(ctx, "key")
+	require.ErrorIs(t, err, redislib.ErrClosed)
+	require.NotErrorIs(t, err, drivers.ErrTransient)
 }
 
 func TestIntegrationRedisGet(t *testing.T) {
@@ -156,6 +176,116 @@ func TestIntegrationRedisSet(t *testing.T) {
 	}
 }
 
+func TestIntegrationRedisSetEx(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		cfg redis.Config
+		key string
+		ttl time.Duration
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[any]
+	}{
+		{
+			name: "success",
+			args: args{cfg: config(), key: "newExKey", ttl: time.Minute},
+			want: toolkit.Err(nil),
+		},
+		{
+			name: "milliseconds",
+			args: args{cfg: config(), key: "newMsKey", ttl: 1500 * time.Millisecond},
+			want: toolkit.Err(nil),
+		},
+		{
+			name: "driver error",
+			args: args{cfg: redis.Config{Addr: invalidAddr}, key: "key", ttl: time.Minute},
+			want: toolkit.Err(errDriverSetEx),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			obj := redis.NewWithConfig(test.args.cfg)
+
+			err := obj.SetEx(ctx, test.args.key, "newVal", test.args.ttl)
+
+			toolkit.Assert(t, toolkit.Got[any](err), test.want)
+
+			if test.name == "driver error" {
+				return
+			}
+
+			// check inner client data
+			client := redislib.NewClient(new(redislib.Options))
+			val := client.Get(ctx, test.args.key).Val()
+			ttl := client.PTTL(ctx, test.args.key).Val()
+
+			assert.Equal(t, "newVal", val)
+			assert.InDelta(t, test.args.ttl, ttl, float64(time.Second))
+		})
+	}
+}
+
+func TestIntegrationRedisTTL(t *testing.T) {
+	t.Parallel()
+
+	type args struct {
+		cfg redis.Config
+		key string
+	}
+
+	tests := []struct {
+		name string
+		args args
+		want toolkit.W[time.Duration]
+	}{
+		{
+			name: "no expiration",
+			args: args{cfg: config(), key: "insertKey"},
+			want: toolkit.Want(time.Duration(0), nil),
+		},
+		{
+			name: "key not exist",
+			args: args{cfg: config(), key: "invalidKey"},
+			want: toolkit.Want(time.Duration(0), drivers.ErrNotExist),
+		},
+		{
+			name: "driver error",
+			args: args{cfg: redis.Config{Addr: invalidAddr}, key: "key"},
+			want: toolkit.Want(time.Duration(0), errDriverTTL),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			ctx := context.Background()
+			obj := redis.NewWithConfig(test.args.cfg)
+
+			got, err := obj.TTL(ctx, test.args.key)
+
+			toolkit.Assert(t, toolkit.Got(err, got), test.want)
+		})
+	}
+
+	t.Run("with expiration", func(t *testing.T) {
+		t.Parallel()
+
+		got, err := redis.NewWithConfig(config()).TTL(context.Background(), "expireKey")
+
+		require.NoError(t, err)
+		assert.InDelta(t, time.Hour, got, float64(time.Second))
+	})
+}
+
 func TestIntegrationRedisDel(t *testing.T) {
 	t.Parallel()
 
@@ -210,6 +340,74 @@ func TestIntegrationRedisDel(t *testing.T) {
 	}
 }
 
+func TestIntegrationRedisConditional(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := redis.NewWithConfig(config())
+	client := redislib.NewClient(new(redislib.Options))
+
+	ok, err := obj.SetNX(ctx, "conditionalKey", "newVal", time.Minute)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+	assert.InDelta(t, time.Minute, client.PTTL(ctx, "conditionalKey").Val(), float64(time.Second))
+
+	ok, err = obj.SetNX(ctx, "conditionalKey", "otherVal", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+
+	ok, err = obj.SetXX(ctx, "conditionalKey", "otherVal", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+	assert.Equal(t, "otherVal", client.Get(ctx, "conditionalKey").Val())
+	assert.Equal(t, -time.Nanosecond, client.PTTL(ctx, "conditionalKey").Val())
+
+	ok, err = obj.SetXX(ctx, "missingKey", "newVal", 0)
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+	assert.Empty(t, client.Get(ctx, "missingKey").Val())
+
+	ok, err = obj.DelXX(ctx, "conditionalKey")
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(true, nil))
+
+	ok, err = obj.DelXX(ctx, "conditionalKey")
+
+	toolkit.Assert(t, toolkit.Got(err, ok), toolkit.Want(false, nil))
+
+	// the condition could hold on the failed attempt, so it's left to the caller
+	_, err = redis.NewWithConfig(redis.Config{Addr: invalidAddr}).SetNX(ctx, "key", "val", 0)
+
+	require.ErrorIs(t, err, drivers.ErrTransient)
+}
+
+func TestIntegrationRedisExpire(t *testing.T) {
+	t.Parallel()
+
+	ctx := context.Background()
+	obj := redis.NewWithConfig(config())
+
+	require.NoError(t, obj.Set(ctx, "expireKey", "expireVal"))
+	require.NoError(t, obj.Expire(ctx, "expireKey", time.Minute))
+
+	ttl, err := obj.TTL(ctx, "expireKey")
+
+	require.NoError(t, err)
+	assert.InDelta(t, time.Minute, ttl, float64(time.Second))
+
+	require.NoError(t, obj.Persist(ctx, "expireKey"))
+	// the infinite key is persisted as well
+	require.NoError(t, obj.Persist(ctx, "expireKey"))
+
+	ttl, err = obj.TTL(ctx, "expireKey")
+
+	toolkit.Assert(t, toolkit.Got(err, ttl), toolkit.Want(time.Duration(0), nil))
+
+	require.NoError(t, obj.Expire(ctx, "expireKey", 0))
+	require.ErrorIs(t, obj.Expire(ctx, "expireKey", time.Minute), drivers.ErrNotExist)
+	require.ErrorIs(t, obj.Persist(ctx, "expireKey"), drivers.ErrNotExist)
+}
+
 func TestUnitRedisClose(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,14 @@
+package eviction
+
+// Policy chooses the key that leaves the full cache. Implementations are not safe
+// for concurrent use, the caller is responsible for locking.
+type Policy interface {
+	// Push starts to track the new key.
+	Push(key string)
+	// Touch records an access to the key.
+	Touch(key string)
+	// Remove stops to track the key that left the cache by itself.
+	Remove(key string)
+	// Evict chooses the victim and stops to track it.
+	Evict() (string, bool)
+}
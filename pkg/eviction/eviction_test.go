@@ -0,0 +1,132 @@
+package eviction_test
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/therenotomorrow/apicache/pkg/eviction"
+)
+
+const capacity = 100
+
+func drain(policy eviction.Policy) []string {
+	keys := make([]string, 0)
+
+	for {
+		key, ok := policy.Evict()
+		if !ok {
+			return keys
+		}
+
+		keys = append(keys, key)
+	}
+}
+
+func TestUnitNew(t *testing.T) {
+	t.Parallel()
+
+	var (
+		_ eviction.Policy = eviction.NewLRU()
+		_ eviction.Policy = eviction.NewLFU()
+		_ eviction.Policy = eviction.NewTinyLFU(capacity)
+	)
+}
+
+func TestUnitPolicyCommon(t *testing.T) {
+	t.Parallel()
+
+	tests := []struct {
+		name   string
+		policy func() eviction.Policy
+	}{
+		{name: "lru", policy: func() eviction.Policy { return eviction.NewLRU() }},
+		{name: "lfu", policy: func() eviction.Policy { return eviction.NewLFU() }},
+		{name: "tinylfu", policy: func() eviction.Policy { return eviction.NewTinyLFU(capacity) }},
+		{name: "tinylfu tiny", policy: func() eviction.Policy { return eviction.NewTinyLFU(0) }},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Parallel()
+
+			policy := test.policy()
+
+			_, ok := policy.Evict()
+			assert.False(t, ok)
+
+			policy.Push("a")
+			policy.Push("b")
+			policy.Push("c")
+			policy.Push("a")
+			policy.Touch("b")
+			policy.Touch("unknown")
+			policy.Remove("c")
+			policy.Remove("unknown")
+
+			assert.ElementsMatch(t, []string{"a", "b"}, drain(policy))
+		})
+	}
+}
+
+func TestUnitLRU(t *testing.T) {
+	t.Parallel()
+
+	policy := eviction.NewLRU()
+
+	policy.Push("a")
+	policy.Push("b")
+	policy.Push("c")
+	policy.Touch("a")
+
+	assert.Equal(t, 3, policy.Len())
+	assert.Equal(t, []string{"b", "c", "a"}, drain(policy))
+}
+
+func TestUnitLFU(t *testing.T) {
+	t.Parallel()
+
+	policy := eviction.NewLFU()
+
+	policy.Push("a")
+	policy.Push("b")
+	policy.Push("c")
+	policy.Touch("a")
+	policy.Touch("a")
+	policy.Touch("c")
+
+	assert.Equal(t, 3, policy.Len())
+	assert.Equal(t, []string{"b", "c", "a"}, drain(policy))
+}
+
+func TestUnitTinyLFU(t *testing.T) {
+	t.Parallel()
+
+	policy := eviction.NewTinyLFU(capacity)
+
+	// hot keys are popular before the scan starts
+	for range 5 {
+		for i := range capacity {
+			policy.Push("hot" + strconv.Itoa(i))
+		}
+	}
+
+	for i := range capacity {
+		// the one-hit wonders of the scan must not flush the hot keys
+		policy.Push("scan" + strconv.Itoa(i))
+
+		_, ok := policy.Evict()
+		assert.True(t, ok)
+	}
+
+	hot := 0
+
+	for _, key := range drain(policy) {
+		if strings.HasPrefix(key, "hot") {
+			hot++
+		}
+	}
+
+	assert.Greater(t, hot, capacity*9/10)
+}
@@ -0,0 +1,106 @@
+package eviction
+
+import "container/heap"
+
+type (
+	counter struct {
+		key   string
+		hits  uint64
+		tick  uint64
+		index int
+	}
+	counters []*counter
+	// LFU evicts the least frequently used key, the least recently used one among equals.
+	LFU struct {
+		heap counters
+		keys map[string]*counter
+		tick uint64
+	}
+)
+
+func (c counters) Len() int { return len(c) }
+
+func (c counters) Less(i, j int) bool {
+	if c[i].hits == c[j].hits {
+		return c[i].tick < c[j].tick
+	}
+
+	return c[i].hits < c[j].hits
+}
+
+func (c counters) Swap(i, j int) {
+	c[i], c[j] = c[j], c[i]
+	c[i].index = i
+	c[j].index = j
+}
+
+func (c *counters) Push(x any) {
+	item, _ := x.(*counter)
+	item.index = len(*c)
+	*c = append(*c, item)
+}
+
+func (c *counters) Pop() any {
+	old := *c
+	last := len(old) - 1
+	item := old[last]
+	old[last] = nil
+	*c = old[:last]
+
+	return item
+}
+
+func NewLFU() *LFU {
+	return &LFU{heap: make(counters, 0), keys: make(map[string]*counter), tick: 0}
+}
+
+func (p *LFU) Push(key string) {
+	if _, ok := p.keys[key]; ok {
+		p.Touch(key)
+
+		return
+	}
+
+	p.tick++
+
+	item := &counter{key: key, hits: 1, tick: p.tick, index: 0}
+	p.keys[key] = item
+
+	heap.Push(&p.heap, item)
+}
+
+func (p *LFU) Touch(key string) {
+	item, ok := p.keys[key]
+	if !ok {
+		return
+	}
+
+	p.tick++
+
+	item.hits++
+	item.tick = p.tick
+
+	heap.Fix(&p.heap, item.index)
+}
+
+func (p *LFU) Remove(key string) {
+	if item, ok := p.keys[key]; ok {
+		heap.Remove(&p.heap, item.index)
+		delete(p.keys, key)
+	}
+}
+
+func (p *LFU) Evict() (string, bool) {
+	if p.heap.Len() == 0 {
+		return "", false
+	}
+
+	item, _ := heap.Pop(&p.heap).(*counter)
+	delete(p.keys, item.key)
+
+	return item.key, true
+}
+
+func (p *LFU) Len() int {
+	return p.heap.Len()
+}
@@ -0,0 +1,52 @@
+package eviction
+
+import "container/list"
+
+// LRU evicts the least recently used key.
+type LRU struct {
+	order *list.List
+	keys  map[string]*list.Element
+}
+
+func NewLRU() *LRU {
+	return &LRU{order: list.New(), keys: make(map[string]*list.Element)}
+}
+
+func (p *LRU) Push(key string) {
+	if _, ok := p.keys[key]; ok {
+		p.Touch(key)
+
+		return
+	}
+
+	p.keys[key] = p.order.PushFront(key)
+}
+
+func (p *LRU) Touch(key string) {
+	if elem, ok := p.keys[key]; ok {
+		p.order.MoveToFront(elem)
+	}
+}
+
+func (p *LRU) Remove(key string) {
+	if elem, ok := p.keys[key]; ok {
+		p.order.Remove(elem)
+		delete(p.keys, key)
+	}
+}
+
+func (p *LRU) Evict() (string, bool) {
+	elem := p.order.Back()
+	if elem == nil {
+		return "", false
+	}
+
+	key, _ := p.order.Remove(elem).(string)
+	delete(p.keys, key)
+
+	return key, true
+}
+
+func (p *LRU) Len() int {
+	return p.order.Len()
+}
@@ -0,0 +1,281 @@
+package eviction
+
+import (
+	"container/list"
+	"math/bits"
+)
+
+const (
+	sketchDepth   = 4
+	sketchMax     = 15
+	sketchResets  = 10
+	windowPercent = 1
+	protectPart   = 80
+	minCapacity   = 1
+	defCapacity   = 1024
+)
+
+type (
+	region int
+	entry  struct {
+		key    string
+		region region
+	}
+	// sketch is a count-min sketch with 4-bit saturating counters that are halved
+	// periodically, so the old popularity fades away.
+	sketch struct {
+		rows  [sketchDepth][]uint8
+		mask  uint64
+		added int
+		limit int
+	}
+	// TinyLFU is a W-TinyLFU policy: new keys go through a small LRU window and are
+	// admitted into the main segmented LRU only if they are more frequent than its victim.
+	TinyLFU struct {
+		sketch    *sketch
+		window    *list.List
+		probation *list.List
+		protected *list.List
+		keys      map[string]*list.Element
+		windowCap int
+		mainCap   int
+		protCap   int
+	}
+)
+
+const (
+	inWindow region = iota
+	inProbation
+	inProtected
+)
+
+func newSketch(capacity int) *sketch {
+	width := uint64(1) << bits.Len64(uint64(capacity))
+
+	var rows [sketchDepth][]uint8
+
+	for i := range rows {
+		rows[i] = make([]uint8, width)
+	}
+
+	return &sketch{rows: rows, mask: width - 1, added: 0, limit: sketchResets * capacity}
+}
+
+func (s *sketch) indexes(key string) [sketchDepth]uint64 {
+	// inline FNV-1a to avoid allocation of the hasher
+	const (
+		offset = 14695981039346656037
+		prime  = 1099511628211
+	)
+
+	hash := uint64(offset)
+
+	for i := range len(key) {
+		hash ^= uint64(key[i])
+		hash *= prime
+	}
+
+	// double hashing gives independent enough rows from the single hash
+	low, high := hash, (hash>>32)|1
+
+	var idx [sketchDepth]uint64
+
+	for i := range idx {
+		idx[i] = (low + uint64(i)*high) & s.mask
+	}
+
+	return idx
+}
+
+func (s *sketch) add(key string) {
+	for row, i := range s.indexes(key) {
+		if s.rows[row][i] < sketchMax {
+			s.rows[row][i]++
+		}
+	}
+
+	s.added++
+
+	if s.added >= s.limit {
+		s.reset()
+	}
+}
+
+func (s *sketch) estimate(key string) uint8 {
+	est := uint8(sketchMax)
+
+	for row, i := range s.indexes(key) {
+		est = min(est, s.rows[row][i])
+	}
+
+	return est
+}
+
+func (s *sketch) reset() {
+	for row := range s.rows {
+		for i := range s.rows[row] {
+			s.rows[row][i] >>= 1
+		}
+	}
+
+	s.added /= 2
+}
+
+// NewTinyLFU creates the policy tuned for the expected number of keys, zero means default.
+func NewTinyLFU(capacity int) *TinyLFU {
+	if capacity <= 0 {
+		capacity = defCapacity
+	}
+
+	windowCap := max(capacity*windowPercent/100, minCapacity)
+	mainCap := max(capacity-windowCap, minCapacity)
+
+	return &TinyLFU{
+		sketch:    newSketch(capacity),
+		window:    list.New(),
+		probation: list.New(),
+		protected: list.New(),
+		keys:      make(map[string]*list.Element),
+		windowCap: windowCap,
+		mainCap:   mainCap,
+		protCap:   max(mainCap*protectPart/100, minCapacity),
+	}
+}
+
+func (p *TinyLFU) Push(key string) {
+	if _, ok := p.keys[key]; ok {
+		p.Touch(key)
+
+		return
+	}
+
+	p.sketch.add(key)
+	p.keys[key] = p.window.PushFront(&entry{key: key, region: inWindow})
+
+	// the main segment takes the window overflow for free until it is full
+	for p.window.Len() > p.windowCap && p.probation.Len()+p.protected.Len() < p.mainCap {
+		p.admit(p.window.Back())
+	}
+}
+
+func (p *TinyLFU) Touch(key string) {
+	elem, ok := p.keys[key]
+	if !ok {
+		return
+	}
+
+	p.sketch.add(key)
+
+	item, _ := elem.Value.(*entry)
+
+	switch item.region {
+	case inWindow:
+		p.window.MoveToFront(elem)
+	case inProtected:
+		p.protected.MoveToFront(elem)
+	case inProbation:
+		// the second hit promotes key to the protected segment
+		p.probation.Remove(elem)
+
+		item.region = inProtected
+		p.keys[key] = p.protected.PushFront(item)
+
+		if p.protected.Len() > p.protCap {
+			p.demote()
+		}
+	}
+}
+
+func (p *TinyLFU) Remove(key string) {
+	elem, ok := p.keys[key]
+	if !ok {
+		return
+	}
+
+	p.list(elem).Remove(elem)
+	delete(p.keys, key)
+}
+
+func (p *TinyLFU) Evict() (string, bool) {
+	for {
+		var candidate, victim *list.Element
+
+		if p.window.Len() > p.windowCap {
+			candidate = p.window.Back()
+		}
+
+		if victim = p.probation.Back(); victim == nil {
+			victim = p.protected.Back()
+		}
+
+		switch {
+		case candidate != nil && victim == nil:
+			// the main segment is empty, so nothing to compete with
+			p.admit(candidate)
+		case candidate != nil:
+			// the less frequent one leaves the cache
+			if p.sketch.estimate(p.key(candidate)) > p.sketch.estimate(p.key(victim)) {
+				p.admit(candidate)
+
+				return p.drop(victim), true
+			}
+
+			return p.drop(candidate), true
+		case victim != nil:
+			return p.drop(victim), true
+		case p.window.Len() > 0:
+			return p.drop(p.window.Back()), true
+		default:
+			return "", false
+		}
+	}
+}
+
+func (p *TinyLFU) Len() int {
+	return len(p.keys)
+}
+
+func (p *TinyLFU) list(elem *list.Element) *list.List {
+	item, _ := elem.Value.(*entry)
+
+	switch item.region {
+	case inProbation:
+		return p.probation
+	case inProtected:
+		return p.protected
+	default:
+		return p.window
+	}
+}
+
+func (p *TinyLFU) key(elem *list.Element) string {
+	item, _ := elem.Value.(*entry)
+
+	return item.key
+}
+
+func (p *TinyLFU) admit(elem *list.Element) {
+	p.window.Remove(elem)
+
+	item, _ := elem.Value.(*entry)
+	item.region = inProbation
+	p.keys[item.key] = p.probation.PushFront(item)
+}
+
+func (p *TinyLFU) demote() {
+	elem := p.protected.Back()
+	p.protected.Remove(elem)
+
+	item, _ := elem.Value.(*entry)
+	item.region = inProbation
+	p.keys[item.key] = p.probation.PushFront(item)
+}
+
+func (p *TinyLFU) drop(elem *list.Element) string {
+	key := p.key(elem)
+
+	p.list(elem).Remove(elem)
+	delete(p.keys, key)
+
+	return key
+}
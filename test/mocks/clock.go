@@ -0,0 +1,103 @@
+package mocks
+
+import (
+	"sync"
+	"time"
+
+	"github.com/therenotomorrow/apicache/pkg/clock"
+)
+
+type (
+	// ClockMock is a manual clock, time moves only by Advance.
+	ClockMock struct {
+		now    time.Time
+		timers map[*TimerMock]struct{}
+		mutex  sync.Mutex
+	}
+	TimerMock struct {
+		clock   *ClockMock
+		channel chan time.Time
+		at      time.Time
+	}
+)
+
+func NewClockMock(now time.Time) *ClockMock {
+	return &ClockMock{now: now.UTC(), timers: make(map[*TimerMock]struct{}), mutex: sync.Mutex{}}
+}
+
+func (c *ClockMock) Now() time.Time {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	return c.now
+}
+
+func (c *ClockMock) Timer(at time.Time) clock.Timer {
+	timer := &TimerMock{clock: c, channel: make(chan time.Time, 1), at: at}
+
+	timer.Reset(at)
+
+	return timer
+}
+
+// Advance moves the time forward and fires all the timers that are due.
+func (c *ClockMock) Advance(d time.Duration) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.now = c.now.Add(d)
+
+	for timer := range c.timers {
+		c.fire(timer)
+	}
+}
+
+func (c *ClockMock) fire(timer *TimerMock) {
+	if timer.at.After(c.now) {
+		return
+	}
+
+	delete(c.timers, timer)
+
+	select {
+	case timer.channel <- c.now:
+	default:
+	}
+}
+
+func (t *TimerMock) C() <-chan time.Time {
+	return t.channel
+}
+
+func (t *TimerMock) Stop() bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	_, active := t.clock.timers[t]
+	delete(t.clock.timers, t)
+	t.drain()
+
+	return active
+}
+
+func (t *TimerMock) Reset(at time.Time) bool {
+	t.clock.mutex.Lock()
+	defer t.clock.mutex.Unlock()
+
+	_, active := t.clock.timers[t]
+	t.drain()
+
+	t.at = at
+	t.clock.timers[t] = struct{}{}
+	t.clock.fire(t)
+
+	return active
+}
+
+// drain drops the stale value like time.Timer does since go1.23.
+func (t *TimerMock) drain() {
+	select {
+	case <-t.channel:
+	default:
+	}
+}
@@ -1,20 +1,31 @@
 package mocks
 
-import "context"
+import (
+	"context"
+	"time"
+)
 
 type DriverMock struct {
-	CloseMock func() error
-	GetMock   func(ctx context.Context, key string) (string, error)
-	SetMock   func(ctx context.Context, key string, val string) error
-	DelMock   func(ctx context.Context, key string) error
+	CloseMock   func() error
+	GetMock     func(ctx context.Context, key string) (string, error)
+	SetMock     func(ctx context.Context, key string, val string) error
+	DelMock     func(ctx context.Context, key string) error
+	SetExMock   func(ctx context.Context, key string, val string, ttl time.Duration) error
+	TTLMock     func(ctx context.Context, key string) (time.Duration, error)
+	ExpireMock  func(ctx context.Context, key string, ttl time.Duration) error
+	PersistMock func(ctx context.Context, key string) error
 }
 
 func NewDriverMock() *DriverMock {
 	return &DriverMock{
-		CloseMock: func() error { return nil },
-		GetMock:   nil,
-		SetMock:   func(_ context.Context, _ string, _ string) error { return nil },
-		DelMock:   func(_ context.Context, _ string) error { return nil },
+		CloseMock:   func() error { return nil },
+		GetMock:     nil,
+		SetMock:     func(_ context.Context, _ string, _ string) error { return nil },
+		DelMock:     func(_ context.Context, _ string) error { return nil },
+		SetExMock:   func(_ context.Context, _ string, _ string, _ time.Duration) error { return nil },
+		TTLMock:     nil,
+		ExpireMock:  func(_ context.Context, _ string, _ time.Duration) error { return nil },
+		PersistMock: func(_ context.Context, _ string) error { return nil },
 	}
 }
 
@@ -24,3 +35,49 @@ func (d *DriverMock) Set(ctx context.Context, key string, val string) error {
 }
 func (d *DriverMock) Del(ctx context.Context, key string) error { return d.DelMock(ctx, key) }
 func (d *DriverMock) Close() error                              { return d.CloseMock() }
+func (d *DriverMock) SetEx(ctx context.Context, key string, val string, ttl time.Duration) error {
+	return d.SetExMock(ctx, key, val, ttl)
+}
+func (d *DriverMock) TTL(ctx context.Context, key string) (time.Duration, error) {
+	return d.TTLMock(ctx, key)
+}
+func (d *DriverMock) Expire(ctx context.Context, key string, ttl time.Duration) error {
+	return d.ExpireMock(ctx, key, ttl)
+}
+func (d *DriverMock) Persist(ctx context.Context, key string) error { return d.PersistMock(ctx, key) }
+
+type ConditionalDriverMock struct {
+	*DriverMock
+
+	SetNXMock func(ctx context.Context, key string, val string, ttl time.Duration) (bool, error)
+	SetXXMock func(ctx context.Context, key string, val string, ttl time.Duration) (bool, error)
+	DelXXMock func(ctx context.Context, key string) (bool, error)
+}
+
+func NewConditionalDriverMock() *ConditionalDriverMock {
+	return &ConditionalDriverMock{DriverMock: NewDriverMock(), SetNXMock: nil, SetXXMock: nil, DelXXMock: nil}
+}
+
+func (d *ConditionalDriverMock) SetNX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	return d.SetNXMock(ctx, key, val, ttl)
+}
+
+func (d *ConditionalDriverMock) SetXX(ctx context.Context, key string, val string, ttl time.Duration) (bool, error) {
+	return d.SetXXMock(ctx, key, val, ttl)
+}
+
+func (d *ConditionalDriverMock) DelXX(ctx context.Context, key string) (bool, error) {
+	return d.DelXXMock(ctx, key)
+}
+
+type LoaderMock struct {
+	LoadMock func(ctx context.Context, key string) ([]byte, time.Duration, error)
+}
+
+func NewLoaderMock() *LoaderMock {
+	return &LoaderMock{LoadMock: nil}
+}
+
+func (l *LoaderMock) Load(ctx context.Context, key string) ([]byte, time.Duration, error) {
+	return l.LoadMock(ctx, key)
+}
@@ -23,15 +23,506 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
+        "/api/v1/admin/webhooks": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "\"List the webhooks\"",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apiv1webhooks.Subscription"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Unauthorized"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "\"Subscribe the webhook to the events of the keys\"",
+                "parameters": [
+                    {
+                        "description": "Payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apiv1webhooks.Payload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apiv1webhooks.Subscription"
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Unauthorized"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.UnprocessableEntity"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/webhooks/dead": {
+            "get": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "\"List the webhook deliveries that failed all the attempts\"",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/apiv1webhooks.DeadLetter"
+                            }
+                        }
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Unauthorized"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/admin/webhooks/{id}": {
+            "delete": {
+                "security": [
+                    {
+                        "Bearer": []
+                    }
+                ],
+                "tags": [
+                    "admin"
+                ],
+                "summary": "\"Unsubscribe the webhook\"",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "401": {
+                        "description": "Unauthorized",
+                        "schema": {
+                            "$ref": "#/definitions/api.Unauthorized"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.NotFound"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.UnprocessableEntity"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/ns/{namespace}/": {
+            "delete": {
+                "tags": [
+                    "namespaces"
+                ],
+                "summary": "\"Delete all the keys of the namespace at once\"",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Namespace",
+                        "name": "namespace",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.NotFound"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.TooManyRequests"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.InternalServer"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/watch": {
+            "get": {
+                "produces": [
+                    "text/event-stream"
+                ],
+                "tags": [
+                    "cache"
+                ],
+                "summary": "\"Stream the changes of the keys as server-sent events\"",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Prefix of the keys",
+                        "name": "prefix",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Resume after this event",
+                        "name": "Last-Event-ID",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apiv1watch.Event"
+                        }
+                    },
+                    "410": {
+                        "description": "Gone",
+                        "schema": {
+                            "$ref": "#/definitions/api.Gone"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.UnprocessableEntity"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.InternalServer"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
+                    }
+                }
+            }
+        },
         "/api/v1/{key}/": {
             "get": {
                 "produces": [
                     "application/json"
                 ],
                 "tags": [
-                    "cache"
+                    "cache"
+                ],
+                "summary": "\"Retrieve key/value pair\"",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity tags of the known versions, * matches any",
+                        "name": "If-None-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/apiv1get.Response"
+                        },
+                        "headers": {
+                            "ETag": {
+                                "type": "string",
+                                "description": "Version of the value, the stale and the just loaded values have none"
+                            },
+                            "X-Cache-Stale": {
+                                "type": "string",
+                                "description": "true when the value is stale"
+                            }
+                        }
+                    },
+                    "304": {
+                        "description": "Not Modified"
+                    },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.BadRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.NotFound"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.UnprocessableEntity"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.TooManyRequests"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.InternalServer"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "cache"
+                ],
+                "summary": "\"Insert key/value pair\"",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "nx",
+                            "xx"
+                        ],
+                        "type": "string",
+                        "description": "Write only if the key is absent (nx) or present (xx)",
+                        "name": "mode",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity tag of the version to replace, * requires the key to exist, excludes mode",
+                        "name": "If-Match",
+                        "in": "header"
+                    },
+                    {
+                        "description": "Payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apiv1post.Payload"
+                        }
+                    }
+                ],
+                "responses": {
+                    "201": {
+                        "description": "Created",
+                        "schema": {
+                            "$ref": "#/definitions/apiv1post.Response"
+                        }
+                    },
+                    "409": {
+                        "description": "Conflict",
+                        "schema": {
+                            "$ref": "#/definitions/api.Conflict"
+                        }
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.PreconditionFailed"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.UnprocessableEntity"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.TooManyRequests"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.InternalServer"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "tags": [
+                    "cache"
+                ],
+                "summary": "\"Delete key/value pair\"",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Key",
+                        "name": "key",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Entity tag of the version to delete, * requires the key to exist",
+                        "name": "If-Match",
+                        "in": "header"
+                    }
+                ],
+                "responses": {
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "412": {
+                        "description": "Precondition Failed",
+                        "schema": {
+                            "$ref": "#/definitions/api.PreconditionFailed"
+                        }
+                    },
+                    "422": {
+                        "description": "Unprocessable Entity",
+                        "schema": {
+                            "$ref": "#/definitions/api.UnprocessableEntity"
+                        }
+                    },
+                    "429": {
+                        "description": "Too Many Requests",
+                        "schema": {
+                            "$ref": "#/definitions/api.TooManyRequests"
+                        }
+                    },
+                    "500": {
+                        "description": "Internal Server Error",
+                        "schema": {
+                            "$ref": "#/definitions/api.InternalServer"
+                        }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
+                    }
+                }
+            }
+        },
+        "/api/v1/{key}/ttl": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "ttl"
                 ],
-                "summary": "\"Retrieve key/value pair\"",
+                "summary": "\"Retrieve the TTL of the key\"",
                 "parameters": [
                     {
                         "type": "string",
@@ -45,7 +536,7 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/apiv1get.Response"
+                            "$ref": "#/definitions/apiv1ttl.Response"
                         }
                     },
                     "400": {
@@ -77,20 +568,20 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.InternalServer"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
                     }
                 }
             },
-            "post": {
-                "consumes": [
-                    "application/json"
-                ],
-                "produces": [
-                    "application/json"
-                ],
+            "delete": {
                 "tags": [
-                    "cache"
+                    "ttl"
                 ],
-                "summary": "\"Insert key/value pair\"",
+                "summary": "\"Make the key infinite\"",
                 "parameters": [
                     {
                         "type": "string",
@@ -98,22 +589,22 @@ const docTemplate = `{
                         "name": "key",
                         "in": "path",
                         "required": true
-                    },
-                    {
-                        "description": "Payload",
-                        "name": "payload",
-                        "in": "body",
-                        "required": true,
-                        "schema": {
-                            "$ref": "#/definitions/apiv1post.Payload"
-                        }
                     }
                 ],
                 "responses": {
-                    "201": {
-                        "description": "Created",
+                    "204": {
+                        "description": "No Content"
+                    },
+                    "400": {
+                        "description": "Bad Request",
                         "schema": {
-                            "$ref": "#/definitions/apiv1post.Response"
+                            "$ref": "#/definitions/api.BadRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.NotFound"
                         }
                     },
                     "422": {
@@ -133,14 +624,23 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.InternalServer"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
                     }
                 }
             },
-            "delete": {
+            "patch": {
+                "consumes": [
+                    "application/json"
+                ],
                 "tags": [
-                    "cache"
+                    "ttl"
                 ],
-                "summary": "\"Delete key/value pair\"",
+                "summary": "\"Extend or shorten the TTL of the key, the value stays untouched\"",
                 "parameters": [
                     {
                         "type": "string",
@@ -148,12 +648,33 @@ const docTemplate = `{
                         "name": "key",
                         "in": "path",
                         "required": true
+                    },
+                    {
+                        "description": "Payload",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/apiv1ttl.Payload"
+                        }
                     }
                 ],
                 "responses": {
                     "204": {
                         "description": "No Content"
                     },
+                    "400": {
+                        "description": "Bad Request",
+                        "schema": {
+                            "$ref": "#/definitions/api.BadRequest"
+                        }
+                    },
+                    "404": {
+                        "description": "Not Found",
+                        "schema": {
+                            "$ref": "#/definitions/api.NotFound"
+                        }
+                    },
                     "422": {
                         "description": "Unprocessable Entity",
                         "schema": {
@@ -171,6 +692,12 @@ const docTemplate = `{
                         "schema": {
                             "$ref": "#/definitions/api.InternalServer"
                         }
+                    },
+                    "503": {
+                        "description": "Service Unavailable",
+                        "schema": {
+                            "$ref": "#/definitions/api.ServiceUnavailable"
+                        }
                     }
                 }
             }
@@ -188,6 +715,28 @@ const docTemplate = `{
                 }
             }
         },
+        "api.Conflict": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "enum": [
+                        "key already exist"
+                    ]
+                }
+            }
+        },
+        "api.Gone": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "enum": [
+                        "offset gone"
+                    ]
+                }
+            }
+        },
         "api.InternalServer": {
             "type": "object",
             "properties": {
@@ -202,7 +751,34 @@ const docTemplate = `{
                 "message": {
                     "type": "string",
                     "enum": [
-                        "key not exist"
+                        "key not exist",
+                        "subscription not exist",
+                        "namespace not exist"
+                    ]
+                }
+            }
+        },
+        "api.PreconditionFailed": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "enum": [
+                        "key not exist",
+                        "version mismatch"
+                    ]
+                }
+            }
+        },
+        "api.ServiceUnavailable": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "enum": [
+                        "request shed",
+                        "circuit open",
+                        "closed instance"
                     ]
                 }
             }
@@ -214,7 +790,22 @@ const docTemplate = `{
                     "type": "string",
                     "enum": [
                         "connection timeout",
-                        "context timeout"
+                        "context timeout",
+                        "read pool exhausted: connection timeout",
+                        "read pool exhausted: context timeout",
+                        "write pool exhausted: connection timeout",
+                        "write pool exhausted: context timeout"
+                    ]
+                }
+            }
+        },
+        "api.Unauthorized": {
+            "type": "object",
+            "properties": {
+                "message": {
+                    "type": "string",
+                    "enum": [
+                        "unauthorized"
                     ]
                 }
             }
@@ -244,9 +835,19 @@ const docTemplate = `{
                 "val"
             ],
             "properties": {
+                "expireAt": {
+                    "description": "ExpireAt is an RFC3339 deadline in the future, it excludes TTL.",
+                    "type": "string"
+                },
+                "sliding": {
+                    "description": "Sliding extends the deadline by the TTL on every successful read, Stateless driver refuses it.",
+                    "type": "boolean"
+                },
                 "ttl": {
-                    "type": "integer",
-                    "minimum": 0
+                    "description": "TTL is a number of seconds or a duration string, e.g. \"1500ms\" or \"2h30m\".",
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1500ms"
                 },
                 "val": {
                     "$ref": "#/definitions/domain.ValType"
@@ -264,14 +865,152 @@ const docTemplate = `{
                 }
             }
         },
+        "apiv1ttl.Payload": {
+            "type": "object",
+            "properties": {
+                "expireAt": {
+                    "description": "ExpireAt is an RFC3339 deadline in the future, it excludes TTL.",
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "TTL is a number of seconds or a duration string, e.g. \"1500ms\" or \"2h30m\".",
+                    "type": "string",
+                    "minLength": 0,
+                    "example": "1500ms"
+                }
+            }
+        },
+        "apiv1ttl.Response": {
+            "type": "object",
+            "properties": {
+                "deadline": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "ttl": {
+                    "description": "TTL is a number of seconds left until the Deadline.",
+                    "type": "number"
+                }
+            }
+        },
+        "apiv1watch.Event": {
+            "type": "object",
+            "properties": {
+                "key": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                }
+            }
+        },
+        "apiv1webhooks.DeadLetter": {
+            "type": "object",
+            "properties": {
+                "attempts": {
+                    "type": "integer"
+                },
+                "error": {
+                    "type": "string"
+                },
+                "event": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "string"
+                },
+                "key": {
+                    "type": "string"
+                },
+                "subscription": {
+                    "type": "string"
+                },
+                "time": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "apiv1webhooks.Payload": {
+            "type": "object",
+            "required": [
+                "events",
+                "url"
+            ],
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string",
+                        "enum": [
+                            "delete",
+                            "expire"
+                        ]
+                    }
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "secret": {
+                    "description": "Secret signs the payloads, it is generated when missed.",
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
+        "apiv1webhooks.Subscription": {
+            "type": "object",
+            "properties": {
+                "events": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "id": {
+                    "type": "string"
+                },
+                "prefix": {
+                    "type": "string"
+                },
+                "secret": {
+                    "type": "string"
+                },
+                "url": {
+                    "type": "string"
+                }
+            }
+        },
         "domain.ValType": {
             "type": "object",
             "additionalProperties": {}
         }
     },
+    "securityDefinitions": {
+        "Bearer": {
+            "type": "apiKey",
+            "name": "Authorization",
+            "in": "header"
+        }
+    },
     "tags": [
         {
             "name": "cache"
+        },
+        {
+            "name": "ttl"
+        },
+        {
+            "name": "namespaces"
+        },
+        {
+            "name": "admin"
         }
     ]
 }`